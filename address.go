@@ -0,0 +1,570 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Per-chain address validation. Catches malformed recipient/refund
+// addresses before they reach 1Click, which otherwise rejects them at quote
+// time with an opaque error. Chains without a checker here only get a basic
+// sanity check — 1Click remains the final authority.
+
+// addressError explains why an address was rejected.
+type addressError struct {
+	Chain  string // display name, e.g. "Ethereum"
+	Reason string
+}
+
+func (e *addressError) Error() string {
+	return "not a valid " + e.Chain + " address — " + e.Reason
+}
+
+// addressFormats maps the registry's AddressFormat names (see chains.go)
+// to format checkers. Each returns a reason string, or "" when the address
+// is valid.
+var addressFormats = map[string]func(string) string{
+	"evm":         checkEVMAddress,
+	"bitcoin":     checkBitcoinAddress,
+	"litecoin":    checkLitecoinAddress,
+	"bitcoincash": checkBitcoinCashAddress,
+	"dogecoin":    checkDogecoinAddress,
+	"solana":      checkSolanaAddress,
+	"tron":        checkTronAddress,
+	"ton":         checkTONAddress,
+	"near":        checkNEARAccount,
+	"xrp":         checkXRPAddress,
+}
+
+// validateAddress checks addr against the format of chain (a 1Click
+// blockchain code such as "eth"). Returns nil when the address is valid.
+func validateAddress(chain, addr string) error {
+	c := lookupChain(chain)
+	name := strings.ToUpper(chain)
+	var check func(string) string
+	if c != nil {
+		name, check = c.Name, addressFormats[c.AddressFormat]
+	}
+
+	if strings.ContainsAny(addr, " \t\r\n") {
+		return &addressError{name, "it contains spaces"}
+	}
+
+	if check == nil {
+		if len(addr) < 10 {
+			return &addressError{name, "it seems too short"}
+		}
+		return nil
+	}
+	if reason := check(addr); reason != "" {
+		if hint := guessAddressFormat(addr, c.AddressFormat); hint != "" {
+			reason += " (this looks like " + hint + " address)"
+		}
+		return &addressError{name, reason}
+	}
+	return nil
+}
+
+// guessAddressFormat names the address family addr belongs to, for the
+// common mistake of pasting an address for the wrong network.
+func guessAddressFormat(addr, selected string) string {
+	guesses := []struct{ format, label string }{
+		{"evm", "an EVM"}, {"bitcoin", "a Bitcoin"}, {"litecoin", "a Litecoin"},
+		{"tron", "a TRON"}, {"xrp", "an XRP"}, {"ton", "a TON"},
+		{"solana", "a Solana"}, {"dogecoin", "a Dogecoin"},
+	}
+	for _, g := range guesses {
+		if g.format != selected && addressFormats[g.format](addr) == "" {
+			return g.label
+		}
+	}
+	return ""
+}
+
+// --- EVM ---
+
+// checkEVMAddress validates 0x + 40 hex, enforcing the EIP-55 checksum when
+// the address is mixed-case. All-lowercase/all-uppercase carry no checksum.
+func checkEVMAddress(addr string) string {
+	if !strings.HasPrefix(addr, "0x") && !strings.HasPrefix(addr, "0X") {
+		return "it should start with 0x"
+	}
+	body := addr[2:]
+	if len(body) != 40 {
+		return "it should be 0x followed by 40 hex characters"
+	}
+	if _, err := hex.DecodeString(body); err != nil {
+		return "it contains non-hex characters"
+	}
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		return ""
+	}
+	if body != eip55Checksum(body)[2:] {
+		return "the EIP-55 checksum doesn't match (check for a typo, or paste it in all lowercase)"
+	}
+	return ""
+}
+
+// eip55Checksum returns the checksummed form of a 40-hex-char address body.
+func eip55Checksum(body string) string {
+	lower := strings.ToLower(body)
+	hash := hex.EncodeToString(keccak256([]byte(lower)))
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && c <= 'f' && hash[i] >= '8' {
+			out[i] = c - 32
+		}
+	}
+	return "0x" + string(out)
+}
+
+// --- Bitcoin family ---
+
+func checkBitcoinAddress(addr string) string {
+	return checkUTXOAddress(addr, "bc", []byte{0x00, 0x05}, "bc1…, 1… or 3…")
+}
+
+func checkLitecoinAddress(addr string) string {
+	return checkUTXOAddress(addr, "ltc", []byte{0x30, 0x32, 0x05}, "ltc1…, L… or M…")
+}
+
+func checkDogecoinAddress(addr string) string {
+	return checkUTXOAddress(addr, "", []byte{0x1e, 0x16}, "D… or A…")
+}
+
+// checkUTXOAddress accepts a segwit address with the given bech32 HRP
+// (if any) or a base58check address with one of the version bytes.
+func checkUTXOAddress(addr, hrp string, versions []byte, forms string) string {
+	if hrp != "" && strings.HasPrefix(strings.ToLower(addr), hrp+"1") {
+		return checkSegwitAddress(addr, hrp)
+	}
+	payload, reason := base58CheckDecode(addr, base58BTC)
+	if reason != "" {
+		return reason + "; expected " + forms
+	}
+	if len(payload) != 21 || !containsByte(versions, payload[0]) {
+		return "unrecognised address type; expected " + forms
+	}
+	return ""
+}
+
+func checkBitcoinCashAddress(addr string) string {
+	lower := strings.ToLower(addr)
+	if strings.HasPrefix(lower, "bitcoincash:") || strings.HasPrefix(lower, "q") || strings.HasPrefix(lower, "p") {
+		return checkCashAddr(addr)
+	}
+	payload, reason := base58CheckDecode(addr, base58BTC)
+	if reason != "" {
+		return reason + "; expected bitcoincash:q… or a legacy 1…/3… address"
+	}
+	if len(payload) != 21 || (payload[0] != 0x00 && payload[0] != 0x05) {
+		return "unrecognised address type; expected bitcoincash:q… or a legacy 1…/3… address"
+	}
+	return ""
+}
+
+// --- Bech32 / bech32m (BIP-173, BIP-350) ---
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Decode splits and verifies a bech32/bech32m string, returning the
+// HRP, the data part (without checksum) and the checksum constant matched.
+func bech32Decode(s string) (hrp string, data []byte, constant uint32, err error) {
+	if len(s) > 90 {
+		return "", nil, 0, errors.New("it is too long")
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, errors.New("it mixes upper and lower case")
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) {
+		return "", nil, 0, errors.New("it is malformed")
+	}
+	hrp = s[:pos]
+	for i := pos + 1; i < len(s); i++ {
+		d := strings.IndexByte(bech32Charset, s[i])
+		if d < 0 {
+			return "", nil, 0, errors.New("it contains an invalid character '" + string(s[i]) + "'")
+		}
+		data = append(data, byte(d))
+	}
+	constant = bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if constant != bech32Const && constant != bech32mConst {
+		return "", nil, 0, errors.New("the checksum doesn't match (check for a typo)")
+	}
+	return hrp, data[:len(data)-6], constant, nil
+}
+
+// segwitEncode builds a witness v0 (bech32) address for program.
+func segwitEncode(hrp string, program []byte) string {
+	conv, _ := convertBits(program, 8, 5, true)
+	data := append([]byte{0}, conv...)
+	mod := bech32Polymod(append(append(bech32HRPExpand(hrp), data...), 0, 0, 0, 0, 0, 0)) ^ bech32Const
+	for i := 0; i < 6; i++ {
+		data = append(data, byte(mod>>uint(5*(5-i))&31))
+	}
+	out := []byte(hrp + "1")
+	for _, d := range data {
+		out = append(out, bech32Charset[d])
+	}
+	return string(out)
+}
+
+// convertBits regroups a slice of fromBits-wide values into toBits-wide values.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, bool) {
+	var acc uint32
+	var nbits uint
+	maxv := uint32(1)<<toBits - 1
+	var out []byte
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, false
+		}
+		acc = acc<<fromBits | uint32(v)
+		nbits += fromBits
+		for nbits >= toBits {
+			nbits -= toBits
+			out = append(out, byte(acc>>nbits&maxv))
+		}
+	}
+	if pad {
+		if nbits > 0 {
+			out = append(out, byte(acc<<(toBits-nbits)&maxv))
+		}
+	} else if nbits >= fromBits || acc<<(toBits-nbits)&maxv != 0 {
+		return nil, false
+	}
+	return out, true
+}
+
+func checkSegwitAddress(addr, wantHRP string) string {
+	hrp, data, constant, err := bech32Decode(addr)
+	if err != nil {
+		return err.Error()
+	}
+	if hrp != wantHRP || len(data) < 1 {
+		return "wrong prefix; expected " + wantHRP + "1…"
+	}
+	version := data[0]
+	prog, ok := convertBits(data[1:], 5, 8, false)
+	if !ok || version > 16 || len(prog) < 2 || len(prog) > 40 {
+		return "the witness program is malformed"
+	}
+	if version == 0 {
+		if constant != bech32Const {
+			return "segwit v0 addresses must use bech32, not bech32m"
+		}
+		if len(prog) != 20 && len(prog) != 32 {
+			return "the witness program has the wrong length"
+		}
+	} else if constant != bech32mConst {
+		return "segwit v1+ addresses must use bech32m (BIP-350)"
+	}
+	return ""
+}
+
+// --- CashAddr (Bitcoin Cash) ---
+
+func cashAddrPolymod(values []byte) uint64 {
+	c := uint64(1)
+	for _, d := range values {
+		c0 := byte(c >> 35)
+		c = (c&0x07ffffffff)<<5 ^ uint64(d)
+		if c0&0x01 != 0 {
+			c ^= 0x98f2bc8e61
+		}
+		if c0&0x02 != 0 {
+			c ^= 0x79b76d99e2
+		}
+		if c0&0x04 != 0 {
+			c ^= 0xf33e5fb3c4
+		}
+		if c0&0x08 != 0 {
+			c ^= 0xae2eabe2a8
+		}
+		if c0&0x10 != 0 {
+			c ^= 0x1e4f43e470
+		}
+	}
+	return c ^ 1
+}
+
+func checkCashAddr(addr string) string {
+	if strings.ToLower(addr) != addr && strings.ToUpper(addr) != addr {
+		return "it mixes upper and lower case"
+	}
+	s := strings.ToLower(addr)
+	s = strings.TrimPrefix(s, "bitcoincash:")
+	if len(s) != 42 {
+		return "it should be 42 characters after the bitcoincash: prefix"
+	}
+	values := make([]byte, 0, len("bitcoincash")+1+len(s))
+	for _, c := range []byte("bitcoincash") {
+		values = append(values, c&31)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(bech32Charset, s[i])
+		if d < 0 {
+			return "it contains an invalid character '" + string(s[i]) + "'"
+		}
+		values = append(values, byte(d))
+	}
+	if cashAddrPolymod(values) != 0 {
+		return "the checksum doesn't match (check for a typo)"
+	}
+	return ""
+}
+
+// --- Base58 ---
+
+const (
+	base58BTC    = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base58Ripple = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+)
+
+// base58Decode decodes s with the given alphabet.
+func base58Decode(s, alphabet string) ([]byte, bool) {
+	if s == "" {
+		return nil, false
+	}
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(alphabet, s[i])
+		if d < 0 {
+			return nil, false
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	leading := 0
+	for leading < len(s) && s[leading] == alphabet[0] {
+		leading++
+	}
+	return append(make([]byte, leading), n.Bytes()...), true
+}
+
+// base58Encode encodes b with the given alphabet, keeping leading zero
+// bytes as leading zero digits.
+func base58Encode(b []byte, alphabet string) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, alphabet[mod.Int64()])
+	}
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		out = append(out, alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckEncode appends the double-SHA-256 checksum to payload
+// (version byte included) and base58-encodes the result.
+func base58CheckEncode(payload []byte, alphabet string) string {
+	h1 := sha256.Sum256(payload)
+	h2 := sha256.Sum256(h1[:])
+	return base58Encode(append(append([]byte{}, payload...), h2[:4]...), alphabet)
+}
+
+// base58CheckDecode decodes and verifies a base58check string, returning
+// the payload (version byte included, checksum stripped) or a reason.
+func base58CheckDecode(s, alphabet string) ([]byte, string) {
+	raw, ok := base58Decode(s, alphabet)
+	if !ok {
+		return nil, "it contains characters that aren't valid in this address format"
+	}
+	if len(raw) < 5 {
+		return nil, "it is too short"
+	}
+	payload, sum := raw[:len(raw)-4], raw[len(raw)-4:]
+	h1 := sha256.Sum256(payload)
+	h2 := sha256.Sum256(h1[:])
+	if string(h2[:4]) != string(sum) {
+		return nil, "the checksum doesn't match (check for a typo)"
+	}
+	return payload, ""
+}
+
+func containsByte(set []byte, b byte) bool {
+	for _, v := range set {
+		if v == b {
+			return true
+		}
+	}
+	return false
+}
+
+// --- Solana ---
+
+func checkSolanaAddress(addr string) string {
+	if len(addr) < 32 || len(addr) > 44 {
+		return "it should be 32–44 base58 characters"
+	}
+	raw, ok := base58Decode(addr, base58BTC)
+	if !ok {
+		return "it contains characters that aren't valid in base58 (0, O, I and l are never used)"
+	}
+	if len(raw) != 32 {
+		return "it doesn't decode to a 32-byte public key"
+	}
+	return ""
+}
+
+// --- TRON ---
+
+func checkTronAddress(addr string) string {
+	if !strings.HasPrefix(addr, "T") || len(addr) != 34 {
+		return "it should be 34 characters starting with T"
+	}
+	payload, reason := base58CheckDecode(addr, base58BTC)
+	if reason != "" {
+		return reason
+	}
+	if len(payload) != 21 || payload[0] != 0x41 {
+		return "unrecognised address type"
+	}
+	return ""
+}
+
+// --- TON ---
+
+var tonRawAddr = regexp.MustCompile(`^-?[0-9]+:[0-9a-fA-F]{64}$`)
+
+// checkTONAddress accepts raw (workchain:hex) and user-friendly (48-char
+// base64/base64url with CRC16) addresses.
+func checkTONAddress(addr string) string {
+	if strings.Contains(addr, ":") {
+		if !tonRawAddr.MatchString(addr) {
+			return "raw addresses should look like 0:<64 hex characters>"
+		}
+		return ""
+	}
+	if len(addr) != 48 {
+		return "it should be 48 characters (e.g. EQ… or UQ…)"
+	}
+	std := strings.NewReplacer("-", "+", "_", "/").Replace(addr)
+	raw, err := base64.StdEncoding.DecodeString(std)
+	if err != nil || len(raw) != 36 {
+		return "it isn't valid base64"
+	}
+	if tag := raw[0] &^ 0x80; tag != 0x11 && tag != 0x51 {
+		return "unrecognised address flags"
+	}
+	if crc16XModem(raw[:34]) != uint16(raw[34])<<8|uint16(raw[35]) {
+		return "the checksum doesn't match (check for a typo)"
+	}
+	return ""
+}
+
+func crc16XModem(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// --- NEAR ---
+
+var (
+	nearNamedAccount    = regexp.MustCompile(`^(([a-z\d]+[-_])*[a-z\d]+\.)*([a-z\d]+[-_])*[a-z\d]+$`)
+	nearImplicitAccount = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	nearEthImplicit     = regexp.MustCompile(`^0x[0-9a-f]{40}$`)
+)
+
+// checkNEARAccount accepts named accounts (alice.near), 64-hex implicit
+// accounts and 0x-prefixed ETH-implicit accounts.
+func checkNEARAccount(addr string) string {
+	if nearImplicitAccount.MatchString(addr) || nearEthImplicit.MatchString(addr) {
+		return ""
+	}
+	if len(addr) < 2 || len(addr) > 64 {
+		return "account IDs are 2–64 characters"
+	}
+	if strings.ToLower(addr) != addr {
+		return "account IDs are lowercase"
+	}
+	if !nearNamedAccount.MatchString(addr) {
+		return "account IDs use a–z, 0–9, and single - _ . separators (e.g. alice.near)"
+	}
+	return ""
+}
+
+// --- XRP ---
+
+// checkXRPAddress accepts classic r-addresses and X-addresses.
+func checkXRPAddress(addr string) string {
+	switch {
+	case strings.HasPrefix(addr, "r"):
+		payload, reason := base58CheckDecode(addr, base58Ripple)
+		if reason != "" {
+			return reason
+		}
+		if len(payload) != 21 || payload[0] != 0x00 {
+			return "unrecognised address type"
+		}
+	case strings.HasPrefix(addr, "X"):
+		payload, reason := base58CheckDecode(addr, base58Ripple)
+		if reason != "" {
+			return reason
+		}
+		if len(payload) != 31 || payload[0] != 0x05 || payload[1] != 0x44 {
+			return "unrecognised X-address"
+		}
+	default:
+		return "it should start with r (or X for an X-address)"
+	}
+	return ""
+}
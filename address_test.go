@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	for in, want := range map[string]string{
+		"":    "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"abc": "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+	} {
+		if got := hex.EncodeToString(keccak256([]byte(in))); got != want {
+			t.Errorf("keccak256(%q) = %s, want %s", in, got, want)
+		}
+	}
+	// Inputs at the 136-byte rate boundary need an extra padding block.
+	a := keccak256([]byte(strings.Repeat("a", 135)))
+	b := keccak256([]byte(strings.Repeat("a", 136)))
+	if hex.EncodeToString(a) == hex.EncodeToString(b) {
+		t.Error("keccak256 collides across the rate boundary")
+	}
+}
+
+func TestEIP55(t *testing.T) {
+	// Test vectors from EIP-55.
+	for _, addr := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := eip55Checksum(addr[2:]); got != addr {
+			t.Errorf("eip55Checksum(%s) = %s", addr, got)
+		}
+		if reason := checkEVMAddress(addr); reason != "" {
+			t.Errorf("checkEVMAddress(%s): %s", addr, reason)
+		}
+	}
+}
+
+func versioned(version byte, n int) []byte {
+	p := make([]byte, n+1)
+	p[0] = version
+	for i := 1; i <= n; i++ {
+		p[i] = byte(i * 7)
+	}
+	return p
+}
+
+func TestValidateAddress(t *testing.T) {
+	valid := []struct{ chain, addr string }{
+		{"eth", "0x000000000000000000000000000000000000dEaD"},
+		{"eth", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{"base", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+		{"btc", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		{"btc", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"},
+		{"btc", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{"btc", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4"},
+		{"btc", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"},
+		{"ltc", base58CheckEncode(versioned(0x30, 20), base58BTC)},
+		{"ltc", base58CheckEncode(versioned(0x32, 20), base58BTC)},
+		{"bch", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"},
+		{"bch", "qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"},
+		{"bch", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		{"doge", base58CheckEncode(versioned(0x1e, 20), base58BTC)},
+		{"sol", "So11111111111111111111111111111111111111112"},
+		{"sol", "11111111111111111111111111111111"},
+		{"tron", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"},
+		{"ton", "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs"},
+		{"ton", "0:" + strings.Repeat("ab", 32)},
+		{"near", "alice.near"},
+		{"near", "bob"},
+		{"near", "sub.account-name_1.near"},
+		{"near", strings.Repeat("a1", 32)},
+		{"xrp", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"},
+		{"xrp", "rrrrrrrrrrrrrrrrrrrrrhoLvTp"},
+		{"sui", "0x" + strings.Repeat("a", 64)}, // no checker: sanity only
+	}
+	for _, tt := range valid {
+		if err := validateAddress(tt.chain, tt.addr); err != nil {
+			t.Errorf("validateAddress(%s, %s) = %v, want valid", tt.chain, tt.addr, err)
+		}
+	}
+
+	invalid := []struct{ chain, addr, reason string }{
+		{"eth", "0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "checksum"},
+		{"eth", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe", "40 hex"},
+		{"eth", "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "0x"},
+		{"eth", "0xZZZeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "non-hex"},
+		{"eth", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "Bitcoin address"},
+		{"btc", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", "checksum"},
+		{"btc", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", "checksum"},
+		{"btc", "bc1qw508d6qejxtdg4y5r3zarvaRY0c5xw7kv8f3t4", "mixes"},
+		{"btc", "0x000000000000000000000000000000000000dEaD", "EVM address"},
+		{"btc", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", "TRON address"},
+		{"ltc", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "unrecognised"},
+		{"bch", "bitcoincash:qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6c", "checksum"},
+		{"sol", "So1111111111111111111111111111111111111111O", "base58"},
+		{"sol", "0x000000000000000000000000000000000000dEaD", "EVM address"},
+		{"sol", "So1111111111", "32–44"},
+		{"tron", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u", "checksum"},
+		{"tron", "0x000000000000000000000000000000000000dEaD", "EVM address"},
+		{"ton", "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDt", "checksum"},
+		{"ton", "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id", "48"},
+		{"near", "Alice.near", "lowercase"},
+		{"near", "alice..near", "separators"},
+		{"near", "a", "2–64"},
+		{"xrp", "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTi", "checksum"},
+		{"xrp", "0x000000000000000000000000000000000000dEaD", "start with r"},
+		{"eth", "0x0000 000000000000000000000000000000000dEaD", "spaces"},
+		{"sui", "0x1", "too short"},
+	}
+	for _, tt := range invalid {
+		err := validateAddress(tt.chain, tt.addr)
+		if err == nil {
+			t.Errorf("validateAddress(%s, %s) = nil, want error mentioning %q", tt.chain, tt.addr, tt.reason)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.reason) {
+			t.Errorf("validateAddress(%s, %s) = %q, want it to mention %q", tt.chain, tt.addr, err, tt.reason)
+		}
+	}
+}
+
+func TestSwapInputValidateAddresses(t *testing.T) {
+	in := &swapInput{
+		FromNet:    "btc",
+		ToNet:      "eth",
+		Recipient:  "0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // bad checksum
+		RefundAddr: "0x000000000000000000000000000000000000dEaD", // EVM address on BTC
+	}
+	errs := in.validate()
+	if len(errs) != 2 {
+		t.Fatalf("validate() = %v, want 2 errors", errs)
+	}
+	if !strings.HasPrefix(errs[0], "Recipient address is not a valid Ethereum address") {
+		t.Errorf("recipient error: %q", errs[0])
+	}
+	if !strings.HasPrefix(errs[1], "Refund address is not a valid Bitcoin address") {
+		t.Errorf("refund error: %q", errs[1])
+	}
+}
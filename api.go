@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JSON API (/api/v1/*) — a stateless mirror of the web swap flow for
+// scripted clients. CSRF does not apply: there are no cookies or sessions,
+// and every order is carried entirely in its encrypted token.
+
+// apiLimiter is separate from the web limiter so scripted traffic and
+// browser traffic don't exhaust each other's budgets.
+var apiLimiter = &rateLimiter{counters: make(map[string]*rateBucket)}
+
+// apiMaxBody caps JSON request bodies.
+const apiMaxBody = 64 << 10
+
+// apiErrorBody is the structured error returned by every API endpoint.
+type apiErrorBody struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// apiSwapRequest is the JSON body for /api/v1/quote and /api/v1/swap.
+// Leave both amount fields empty on /api/v1/swap for an ANY_INPUT order.
+type apiSwapRequest struct {
+	From       string `json:"from"`
+	FromNet    string `json:"fromNet"`
+	To         string `json:"to"`
+	ToNet      string `json:"toNet"`
+	Amount     string `json:"amount,omitempty"`
+	AmountOut  string `json:"amountOut,omitempty"`
+	Recipient  string `json:"recipient"`
+	RefundAddr string `json:"refundAddr"`
+	Slippage   string `json:"slippage,omitempty"` // percent, default "1"
+
+	// Optional status webhook, /api/v1/swap only.
+	CallbackURL    string `json:"callbackUrl,omitempty"`
+	CallbackSecret string `json:"callbackSecret,omitempty"`
+}
+
+// apiToken is the public view of a cached token.
+type apiToken struct {
+	AssetID         string  `json:"assetId"`
+	Ticker          string  `json:"ticker"`
+	Name            string  `json:"name,omitempty"`
+	Network         string  `json:"network"`
+	Decimals        int     `json:"decimals"`
+	Price           float64 `json:"price,omitempty"`
+	ContractAddress string  `json:"contractAddress,omitempty"`
+}
+
+// apiQuoteResponse is returned by /api/v1/quote.
+type apiQuoteResponse struct {
+	SwapType     string `json:"swapType"`
+	OriginAsset  string `json:"originAsset"`
+	DestAsset    string `json:"destinationAsset"`
+	AtomicAmount string `json:"atomicAmount"`
+	AmountIn     string `json:"amountIn"`
+	AmountOut    string `json:"amountOut"`
+	AmountInUSD  string `json:"amountInUsd,omitempty"`
+	AmountOutUSD string `json:"amountOutUsd,omitempty"`
+	Rate         string `json:"rate,omitempty"`
+	SpreadUSD    string `json:"spreadUsd,omitempty"`
+	SpreadPct    string `json:"spreadPct,omitempty"`
+	SlippageBPS  int    `json:"slippageBps"`
+}
+
+// apiOrder is the readable form of OrderData (whose JSON tags are
+// abbreviated to keep tokens short).
+type apiOrder struct {
+	DepositAddress string `json:"depositAddress"`
+	DepositMemo    string `json:"depositMemo,omitempty"`
+	From           string `json:"from"`
+	FromNet        string `json:"fromNet"`
+	To             string `json:"to"`
+	ToNet          string `json:"toNet"`
+	AmountIn       string `json:"amountIn"`
+	AmountOut      string `json:"amountOut"`
+	Deadline       string `json:"deadline,omitempty"`
+	CorrelationID  string `json:"correlationId,omitempty"`
+	RefundAddr     string `json:"refundAddr,omitempty"`
+	Recipient      string `json:"recipient,omitempty"`
+	SwapType       string `json:"swapType"`
+	CallbackURL    string `json:"callbackUrl,omitempty"`    // the secret is never echoed
+	PaymentURI     string `json:"paymentUri,omitempty"`     // BIP21 / EIP-681 / Solana Pay / ton://, when supported
+	QuoteSignature string `json:"quoteSignature,omitempty"` // verified, unchecked
+}
+
+// apiSwapResponse is returned by /api/v1/swap.
+type apiSwapResponse struct {
+	Token    string   `json:"token"`
+	OrderURL string   `json:"orderUrl"`
+	Order    apiOrder `json:"order"`
+}
+
+// apiOrderResponse is returned by /api/v1/order/{token}.
+type apiOrderResponse struct {
+	Token         string               `json:"token"`
+	Order         apiOrder             `json:"order"`
+	Status        string               `json:"status"`
+	StatusStep    int                  `json:"statusStep"`
+	IsTerminal    bool                 `json:"isTerminal"`
+	TimeRemaining string               `json:"timeRemaining,omitempty"`
+	SwapDetails   *SwapDetails         `json:"swapDetails,omitempty"`
+	Withdrawals   []AnyInputWithdrawal `json:"withdrawals,omitempty"`
+}
+
+func newAPIOrder(o *OrderData) apiOrder {
+	swapType := o.SwapType
+	if swapType == "" {
+		swapType = "FLEX_INPUT"
+	}
+	return apiOrder{
+		DepositAddress: o.DepositAddr,
+		DepositMemo:    o.Memo,
+		From:           o.FromTicker,
+		FromNet:        o.FromNet,
+		To:             o.ToTicker,
+		ToNet:          o.ToNet,
+		AmountIn:       o.AmountIn,
+		AmountOut:      o.AmountOut,
+		Deadline:       o.Deadline,
+		CorrelationID:  o.CorrID,
+		RefundAddr:     o.RefundAddr,
+		Recipient:      o.RecvAddr,
+		SwapType:       swapType,
+		CallbackURL:    o.CallbackURL,
+		PaymentURI:     paymentURI(o),
+		QuoteSignature: o.QuoteSig,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string, details ...string) {
+	writeJSON(w, status, apiErrorBody{Error: apiErrorDetail{Code: code, Message: message, Details: details}})
+}
+
+func writeAPISwapError(w http.ResponseWriter, serr *swapError) {
+	writeAPIError(w, serr.Status, serr.Code, serr.Message)
+}
+
+// apiGuard enforces the method and the per-endpoint rate limit.
+// Returns false (after writing the error) when the request must stop.
+func apiGuard(w http.ResponseWriter, r *http.Request, method, endpoint string, limit int) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeAPIError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Use "+method+".")
+		return false
+	}
+	if !apiLimiter.allow(endpoint+"|"+clientIP(r), limit, time.Minute) {
+		w.Header().Set("Retry-After", "60")
+		writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Too many requests. Please wait a minute.")
+		return false
+	}
+	return true
+}
+
+// decodeAPISwapRequest parses and normalizes a JSON swap body.
+func decodeAPISwapRequest(w http.ResponseWriter, r *http.Request) (*swapInput, bool) {
+	var req apiSwapRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_json", "Request body must be a JSON object: "+err.Error())
+		return nil, false
+	}
+	in := &swapInput{
+		FromTicker: req.From,
+		FromNet:    req.FromNet,
+		ToTicker:   req.To,
+		ToNet:      req.ToNet,
+		Amount:     req.Amount,
+		AmountOut:  req.AmountOut,
+		Recipient:  req.Recipient,
+		RefundAddr: req.RefundAddr,
+		Slippage:   req.Slippage,
+
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: req.CallbackSecret,
+	}
+	in.normalize()
+	if errs := in.validate(); len(errs) > 0 {
+		writeAPIError(w, http.StatusBadRequest, "validation_error", "Please check your input.", errs...)
+		return nil, false
+	}
+	return in, true
+}
+
+// handleAPITokens returns the supported token list, optionally filtered by ?search=.
+func handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	if !apiGuard(w, r, http.MethodGet, "tokens", 60) {
+		return
+	}
+
+	if _, err := getTokens(); err != nil {
+		writeAPIError(w, http.StatusBadGateway, "upstream_unavailable", "Could not load the token list. NEAR Intents API may be temporarily unavailable.")
+		return
+	}
+
+	tokens := searchTokens(strings.TrimSpace(r.URL.Query().Get("search")))
+	out := make([]apiToken, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, apiToken{
+			AssetID:         t.DefuseAssetID,
+			Ticker:          t.Ticker,
+			Name:            t.Name,
+			Network:         t.ChainName,
+			Decimals:        t.Decimals,
+			Price:           t.Price,
+			ContractAddress: t.ContractAddress,
+		})
+	}
+	resp := map[string]interface{}{"tokens": out}
+	if at := tokenCacheSnapshotTime(); !at.IsZero() {
+		resp["snapshotSavedAt"] = at.UTC().Format(time.RFC3339)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleAPIQuote returns a dry quote — the JSON equivalent of /quote.
+func handleAPIQuote(w http.ResponseWriter, r *http.Request) {
+	if !apiGuard(w, r, http.MethodPost, "quote", 30) {
+		return
+	}
+	in, ok := decodeAPISwapRequest(w, r)
+	if !ok {
+		return
+	}
+	if in.swapType() == "ANY_INPUT" {
+		writeAPIError(w, http.StatusBadRequest, "amount_required", "Set amount or amountOut to quote. For an open-amount swap, call /api/v1/swap directly.")
+		return
+	}
+
+	fromToken, toToken, serr := in.resolveTokens()
+	if serr != nil {
+		writeAPISwapError(w, serr)
+		return
+	}
+
+	pq, serr := prepareQuote(r.Context(), in, fromToken, toToken)
+	if serr != nil {
+		writeAPISwapError(w, serr)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiQuoteResponse{
+		SwapType:     pq.SwapType,
+		OriginAsset:  fromToken.DefuseAssetID,
+		DestAsset:    toToken.DefuseAssetID,
+		AtomicAmount: pq.AtomicAmount,
+		AmountIn:     pq.AmountIn,
+		AmountOut:    pq.AmountOut,
+		AmountInUSD:  pq.AmountInUSD,
+		AmountOutUSD: pq.AmountOutUSD,
+		Rate:         pq.Rate,
+		SpreadUSD:    pq.SpreadUSD,
+		SpreadPct:    pq.SpreadPct,
+		SlippageBPS:  pq.SlippageBPS,
+	})
+}
+
+// handleAPISwap places a real quote and returns the order token and deposit
+// address — the JSON equivalent of /swap.
+func handleAPISwap(w http.ResponseWriter, r *http.Request) {
+	if !apiGuard(w, r, http.MethodPost, "swap", 10) {
+		return
+	}
+	in, ok := decodeAPISwapRequest(w, r)
+	if !ok {
+		return
+	}
+
+	fromToken, toToken, serr := in.resolveTokens()
+	if serr != nil {
+		writeAPISwapError(w, serr)
+		return
+	}
+
+	swapType := in.swapType()
+	atomicAmount, serr := in.atomicAmount(swapType, fromToken, toToken)
+	if serr != nil {
+		writeAPISwapError(w, serr)
+		return
+	}
+
+	order, token, serr := placeOrder(r.Context(), in, fromToken, toToken, swapType, atomicAmount, in.slippageBPS(), in.Amount, in.AmountOut)
+	if serr != nil {
+		writeAPISwapError(w, serr)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiSwapResponse{
+		Token:    token,
+		OrderURL: "/order/" + token,
+		Order:    newAPIOrder(order),
+	})
+}
+
+// handleAPIOrder returns an order's details and live status.
+func handleAPIOrder(w http.ResponseWriter, r *http.Request) {
+	if !apiGuard(w, r, http.MethodGet, "order", 60) {
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, "/api/v1/order/")
+	if token == "" || strings.Contains(token, "/") {
+		writeAPIError(w, http.StatusBadRequest, "missing_token", "No order token provided.")
+		return
+	}
+
+	order, err := decryptOrderData(token)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_token", "This order token is invalid or was created on a different server.")
+		return
+	}
+
+	webhooks.resume(order)
+
+	status, withdrawals := loadOrderStatus(r.Context(), order)
+	step, terminal := orderStatusStep(status.Status)
+
+	resp := apiOrderResponse{
+		Token:         token,
+		Order:         newAPIOrder(order),
+		Status:        status.Status,
+		StatusStep:    step,
+		IsTerminal:    terminal,
+		TimeRemaining: orderTimeRemaining(order.Deadline),
+		SwapDetails:   status.SwapDetails,
+	}
+	if withdrawals != nil {
+		resp.Withdrawals = withdrawals.Withdrawals
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// The chain registry is the one place that knows about each 1Click
+// blockchain code: what to call it, where it sorts, how to link to it on a
+// block explorer, whether deposits need a memo, which address format it
+// uses, how long deposits take to confirm and which payment URI it speaks.
+// Token grouping, address validation, payment URIs, order pages and the
+// Telegram cards all read from it.
+//
+// CHAIN_REGISTRY_PATH names an optional JSON file of overrides: an array of
+// objects keyed by "code", each replacing only the fields it sets. Unknown
+// codes add new chains.
+
+// chainInfo describes one chain.
+type chainInfo struct {
+	Code     string   `json:"code"`              // 1Click blockchain code, e.g. "eth"
+	Aliases  []string `json:"aliases,omitempty"` // other codes seen for it (Explorer API prefixes)
+	Name     string   `json:"name"`
+	Priority int      `json:"priority,omitempty"` // network list order; 0 sorts alphabetically after the rest
+
+	// Block explorer URL templates; {address} and {tx} are substituted.
+	AddressURL string `json:"addressUrl,omitempty"`
+	TxURL      string `json:"txUrl,omitempty"`
+
+	MemoRequired   bool   `json:"memoRequired,omitempty"`   // deposits share an address and are told apart by memo
+	AddressFormat  string `json:"addressFormat,omitempty"`  // key into addressFormats; "" = basic checks only
+	ConfirmSeconds int    `json:"confirmSeconds,omitempty"` // typical time until 1Click sees a deposit
+
+	// Payment URIs (see paymenturi.go).
+	URIScheme    string `json:"uriScheme,omitempty"`    // BIP21-style scheme
+	EVMChainID   int    `json:"evmChainId,omitempty"`   // EIP-155 ID for EIP-681 URIs
+	NativeTicker string `json:"nativeTicker,omitempty"` // gas token, when natives and tokens need different URIs
+}
+
+var defaultChains = []chainInfo{
+	{Code: "eth", Name: "Ethereum", Priority: 1, AddressURL: "https://etherscan.io/address/{address}", TxURL: "https://etherscan.io/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 180, EVMChainID: 1, NativeTicker: "ETH"},
+	{Code: "btc", Name: "Bitcoin", Priority: 2, AddressURL: "https://mempool.space/address/{address}", TxURL: "https://mempool.space/tx/{tx}",
+		AddressFormat: "bitcoin", ConfirmSeconds: 1800, URIScheme: "bitcoin"},
+	{Code: "sol", Name: "Solana", Priority: 3, AddressURL: "https://solscan.io/account/{address}", TxURL: "https://solscan.io/tx/{tx}",
+		AddressFormat: "solana", ConfirmSeconds: 30, NativeTicker: "SOL"},
+	{Code: "base", Name: "Base", Priority: 4, AddressURL: "https://basescan.org/address/{address}", TxURL: "https://basescan.org/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 60, EVMChainID: 8453, NativeTicker: "ETH"},
+	{Code: "arb", Name: "Arbitrum", Priority: 5, AddressURL: "https://arbiscan.io/address/{address}", TxURL: "https://arbiscan.io/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 60, EVMChainID: 42161, NativeTicker: "ETH"},
+	{Code: "ton", Name: "TON", Priority: 6, AddressURL: "https://tonviewer.com/{address}", TxURL: "https://tonviewer.com/transaction/{tx}",
+		AddressFormat: "ton", ConfirmSeconds: 30, NativeTicker: "TON"},
+	{Code: "tron", Aliases: []string{"trx"}, Name: "TRON", Priority: 7, AddressURL: "https://tronscan.org/#/address/{address}", TxURL: "https://tronscan.org/#/transaction/{tx}",
+		AddressFormat: "tron", ConfirmSeconds: 180},
+	{Code: "bsc", Name: "BNB Chain", Priority: 8, AddressURL: "https://bscscan.com/address/{address}", TxURL: "https://bscscan.com/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 60, EVMChainID: 56, NativeTicker: "BNB"},
+	{Code: "pol", Name: "Polygon", Priority: 9, AddressURL: "https://polygonscan.com/address/{address}", TxURL: "https://polygonscan.com/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 120, EVMChainID: 137, NativeTicker: "POL"},
+	{Code: "op", Name: "Optimism", Priority: 10, AddressURL: "https://optimistic.etherscan.io/address/{address}", TxURL: "https://optimistic.etherscan.io/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 60, EVMChainID: 10, NativeTicker: "ETH"},
+	{Code: "avax", Name: "Avalanche", Priority: 11, AddressURL: "https://snowtrace.io/address/{address}", TxURL: "https://snowtrace.io/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 30, EVMChainID: 43114, NativeTicker: "AVAX"},
+	{Code: "near", Aliases: []string{"nep141"}, Name: "NEAR", Priority: 12, AddressURL: "https://nearblocks.io/address/{address}", TxURL: "https://nearblocks.io/txns/{tx}",
+		AddressFormat: "near", ConfirmSeconds: 10},
+
+	{Code: "sui", Name: "Sui", AddressURL: "https://suiscan.xyz/mainnet/account/{address}", TxURL: "https://suiscan.xyz/mainnet/tx/{tx}", ConfirmSeconds: 10},
+	{Code: "apt", Aliases: []string{"aptos"}, Name: "Aptos", AddressURL: "https://explorer.aptoslabs.com/account/{address}?network=mainnet",
+		TxURL: "https://explorer.aptoslabs.com/txn/{tx}?network=mainnet", ConfirmSeconds: 10},
+	{Code: "doge", Name: "Dogecoin", AddressURL: "https://blockchair.com/dogecoin/address/{address}", TxURL: "https://blockchair.com/dogecoin/transaction/{tx}",
+		AddressFormat: "dogecoin", ConfirmSeconds: 600, URIScheme: "dogecoin"},
+	{Code: "ltc", Name: "Litecoin", AddressURL: "https://blockchair.com/litecoin/address/{address}", TxURL: "https://blockchair.com/litecoin/transaction/{tx}",
+		AddressFormat: "litecoin", ConfirmSeconds: 600, URIScheme: "litecoin"},
+	{Code: "xrp", Name: "XRP", AddressURL: "https://xrpscan.com/account/{address}", TxURL: "https://xrpscan.com/tx/{tx}",
+		MemoRequired: true, AddressFormat: "xrp", ConfirmSeconds: 10},
+	{Code: "bch", Name: "Bitcoin Cash", AddressURL: "https://blockchair.com/bitcoin-cash/address/{address}", TxURL: "https://blockchair.com/bitcoin-cash/transaction/{tx}",
+		AddressFormat: "bitcoincash", ConfirmSeconds: 1800},
+	{Code: "xlm", Aliases: []string{"stellar"}, Name: "Stellar", AddressURL: "https://stellar.expert/explorer/public/account/{address}",
+		TxURL: "https://stellar.expert/explorer/public/tx/{tx}", MemoRequired: true, ConfirmSeconds: 10},
+	{Code: "zec", Name: "Zcash", AddressURL: "https://blockchair.com/zcash/address/{address}", TxURL: "https://blockchair.com/zcash/transaction/{tx}", ConfirmSeconds: 1200},
+	{Code: "cardano", Name: "Cardano", AddressURL: "https://cardanoscan.io/address/{address}", TxURL: "https://cardanoscan.io/transaction/{tx}", ConfirmSeconds: 600},
+	{Code: "starknet", Name: "StarkNet", AddressURL: "https://starkscan.co/contract/{address}", TxURL: "https://starkscan.co/tx/{tx}", ConfirmSeconds: 300},
+	{Code: "gnosis", Name: "Gnosis", AddressURL: "https://gnosisscan.io/address/{address}", TxURL: "https://gnosisscan.io/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 60, EVMChainID: 100, NativeTicker: "XDAI"},
+	{Code: "bera", Name: "Berachain", AddressURL: "https://berascan.com/address/{address}", TxURL: "https://berascan.com/tx/{tx}",
+		AddressFormat: "evm", ConfirmSeconds: 60, EVMChainID: 80094, NativeTicker: "BERA"},
+	{Code: "monad", Name: "Monad", AddressFormat: "evm"},
+	{Code: "plasma", Name: "Plasma", AddressFormat: "evm"},
+	{Code: "xlayer", Name: "X Layer", AddressFormat: "evm"},
+	{Code: "aleo", Name: "Aleo"},
+	{Code: "adi", Name: "ADI"},
+}
+
+// chains indexes the registry by lowercase code and alias.
+var chains = indexChains(defaultChains)
+
+func indexChains(list []chainInfo) map[string]*chainInfo {
+	m := make(map[string]*chainInfo, len(list))
+	for i := range list {
+		c := &list[i]
+		m[c.Code] = c
+		for _, a := range c.Aliases {
+			if a = strings.ToLower(a); m[a] == nil {
+				m[a] = c
+			}
+		}
+	}
+	return m
+}
+
+// lookupChain returns the registry entry for a chain code or alias, or nil.
+func lookupChain(code string) *chainInfo {
+	return chains[strings.ToLower(code)]
+}
+
+// chainName returns the display name for a chain code, or the code itself
+// for chains the registry doesn't know.
+func chainName(code string) string {
+	if c := lookupChain(code); c != nil {
+		return c.Name
+	}
+	return code
+}
+
+// AddressLink returns the explorer URL for addr, or "" without a template.
+// Safe on a nil *chainInfo.
+func (c *chainInfo) AddressLink(addr string) string {
+	if c == nil || c.AddressURL == "" || addr == "" {
+		return ""
+	}
+	return strings.ReplaceAll(c.AddressURL, "{address}", url.PathEscape(addr))
+}
+
+// TxLink returns the explorer URL for a transaction hash, or "".
+func (c *chainInfo) TxLink(hash string) string {
+	if c == nil || c.TxURL == "" || hash == "" {
+		return ""
+	}
+	return strings.ReplaceAll(c.TxURL, "{tx}", url.PathEscape(hash))
+}
+
+// txExplorerURL returns tx.ExplorerURL, or the registry link for chain when
+// 1Click didn't supply one.
+func txExplorerURL(tx TransactionDetail, chain string) string {
+	if tx.ExplorerURL != "" {
+		return tx.ExplorerURL
+	}
+	return lookupChain(chain).TxLink(tx.Hash)
+}
+
+// ConfirmLabel is ConfirmSeconds for people: "~30 sec", "~3 min".
+func (c *chainInfo) ConfirmLabel() string {
+	switch {
+	case c == nil || c.ConfirmSeconds <= 0:
+		return ""
+	case c.ConfirmSeconds < 60:
+		return fmt.Sprintf("~%d sec", c.ConfirmSeconds)
+	default:
+		return fmt.Sprintf("~%d min", (c.ConfirmSeconds+30)/60)
+	}
+}
+
+// initChains applies CHAIN_REGISTRY_PATH, if set.
+func initChains() {
+	path := os.Getenv("CHAIN_REGISTRY_PATH")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("chain registry: %v (using built-in chains)", err)
+		return
+	}
+	list, err := applyChainOverrides(defaultChains, data)
+	if err != nil {
+		log.Printf("chain registry: %s: %v (using built-in chains)", path, err)
+		return
+	}
+	chains = indexChains(list)
+	log.Printf("Chain registry: %d chains (overrides from %s)", len(list), path)
+}
+
+// applyChainOverrides merges a JSON array of partial entries into a copy
+// of base. Fields an entry omits keep their current value.
+func applyChainOverrides(base []chainInfo, data []byte) ([]chainInfo, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	list := append([]chainInfo(nil), base...)
+	for i, r := range raw {
+		var key struct {
+			Code string `json:"code"`
+		}
+		if err := json.Unmarshal(r, &key); err != nil || key.Code == "" {
+			return nil, fmt.Errorf("entry %d: missing code", i)
+		}
+		code := strings.ToLower(key.Code)
+		j := len(list)
+		for k := range list {
+			if list[k].Code == code {
+				j = k
+				break
+			}
+		}
+		if j == len(list) {
+			list = append(list, chainInfo{Code: code, Name: strings.ToUpper(code)})
+		}
+		entry := list[j]
+		entry.Aliases = append([]string(nil), entry.Aliases...)
+		if err := json.Unmarshal(r, &entry); err != nil {
+			return nil, fmt.Errorf("entry %d (%s): %v", i, code, err)
+		}
+		entry.Code = code
+		if entry.AddressFormat != "" && addressFormats[entry.AddressFormat] == nil {
+			return nil, fmt.Errorf("entry %d (%s): unknown addressFormat %q", i, code, entry.AddressFormat)
+		}
+		list[j] = entry
+	}
+	return list, nil
+}
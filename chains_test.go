@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChainRegistryConsistent(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range defaultChains {
+		if c.Code != strings.ToLower(c.Code) || c.Name == "" {
+			t.Errorf("%q: codes must be lowercase and named", c.Code)
+		}
+		if seen[c.Code] {
+			t.Errorf("%q listed twice", c.Code)
+		}
+		seen[c.Code] = true
+		if c.AddressFormat != "" && addressFormats[c.AddressFormat] == nil {
+			t.Errorf("%q: unknown address format %q", c.Code, c.AddressFormat)
+		}
+		if c.AddressURL != "" && !strings.Contains(c.AddressURL, "{address}") || c.TxURL != "" && !strings.Contains(c.TxURL, "{tx}") {
+			t.Errorf("%q: explorer templates need their placeholder", c.Code)
+		}
+		if c.EVMChainID != 0 && c.AddressFormat != "evm" {
+			t.Errorf("%q has an EIP-155 ID but no EVM address format", c.Code)
+		}
+	}
+	for code, want := range map[string]string{"ETH": "Ethereum", "trx": "TRON", "nep141": "NEAR", "aptos": "Aptos", "stellar": "Stellar"} {
+		if got := chainName(code); got != want {
+			t.Errorf("chainName(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestChainLinks(t *testing.T) {
+	eth := lookupChain("eth")
+	if got := eth.TxLink("0xabc"); got != "https://etherscan.io/tx/0xabc" {
+		t.Errorf("TxLink = %q", got)
+	}
+	if got := lookupChain("near").AddressLink("alice.near"); got != "https://nearblocks.io/address/alice.near" {
+		t.Errorf("AddressLink = %q", got)
+	}
+	if got := lookupChain("nope").TxLink("0xabc"); got != "" {
+		t.Errorf("unknown chain should have no link, got %q", got)
+	}
+	if got := txExplorerURL(TransactionDetail{Hash: "h", ExplorerURL: "https://upstream/h"}, "eth"); got != "https://upstream/h" {
+		t.Errorf("1Click's own link should win, got %q", got)
+	}
+	if got := txExplorerURL(TransactionDetail{Hash: "h"}, "sol"); got != "https://solscan.io/tx/h" {
+		t.Errorf("registry fallback = %q", got)
+	}
+	for secs, want := range map[int]string{10: "~10 sec", 60: "~1 min", 180: "~3 min", 1800: "~30 min"} {
+		if got := (&chainInfo{ConfirmSeconds: secs}).ConfirmLabel(); got != want {
+			t.Errorf("ConfirmLabel(%d) = %q, want %q", secs, got, want)
+		}
+	}
+}
+
+func TestChainOverrides(t *testing.T) {
+	list, err := applyChainOverrides(defaultChains, []byte(`[
+		{"code": "monad", "txUrl": "https://monad.example/tx/{tx}", "evmChainId": 143},
+		{"code": "XRP", "memoRequired": false},
+		{"code": "newchain", "name": "New Chain", "priority": 13, "aliases": ["NC"], "addressFormat": "evm"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := chains
+	chains = indexChains(list)
+	defer func() { chains = saved }()
+
+	monad := lookupChain("monad")
+	if monad.Name != "Monad" || monad.AddressFormat != "evm" || monad.EVMChainID != 143 || monad.TxLink("1") != "https://monad.example/tx/1" {
+		t.Errorf("monad = %+v; omitted fields should keep their defaults", monad)
+	}
+	if lookupChain("xrp").MemoRequired {
+		t.Error("an override should be able to clear a flag")
+	}
+	if c := lookupChain("nc"); c == nil || c.Name != "New Chain" {
+		t.Errorf("new chain via alias: %+v", c)
+	}
+	if err := validateAddress("newchain", "not-an-address"); err == nil {
+		t.Error("new chain should validate with its address format")
+	}
+	if defaultChains[len(defaultChains)-1].Code == "newchain" || saved["xrp"].MemoRequired == false {
+		t.Error("overrides must not modify the built-in list")
+	}
+
+	for _, bad := range []string{`{"code":"eth"}`, `[{"name":"x"}]`, `[{"code":"eth","addressFormat":"cobol"}]`} {
+		if _, err := applyChainOverrides(defaultChains, []byte(bad)); err == nil {
+			t.Errorf("%s should be rejected", bad)
+		}
+	}
+}
+
+func TestInitChainsFromFile(t *testing.T) {
+	saved := chains
+	defer func() { chains = saved }()
+	path := filepath.Join(t.TempDir(), "chains.json")
+	os.WriteFile(path, []byte(`[{"code":"eth","name":"Ethereum Mainnet"}]`), 0644)
+	t.Setenv("CHAIN_REGISTRY_PATH", path)
+	initChains()
+	if chainName("eth") != "Ethereum Mainnet" {
+		t.Errorf("override file not applied: %q", chainName("eth"))
+	}
+
+	// A broken file leaves the registry as it was.
+	os.WriteFile(path, []byte(`not json`), 0644)
+	chains = saved
+	initChains()
+	if chainName("eth") != "Ethereum" {
+		t.Error("a bad override file should be ignored")
+	}
+}
+
+func TestOrderPageUsesChainRegistry(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v0/status") {
+			fmt.Fprint(w, `{"status":"SUCCESS","swapDetails":{"destinationChainTxHashes":[{"hash":"5xSig","explorerUrl":""}]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"withdrawals":[]}`)
+	})
+	render := func(order *OrderData) string {
+		token, _ := encryptOrderData(order)
+		w := httptest.NewRecorder()
+		handleOrder(w, httptest.NewRequest("GET", "/order/"+token, nil))
+		return w.Body.String()
+	}
+
+	body := render(&OrderData{DepositAddr: "0xdeposit", FromTicker: "ETH", FromNet: "arb", ToTicker: "SOL", ToNet: "sol", AmountIn: "1"})
+	if !strings.Contains(body, `href="https://solscan.io/tx/5xSig"`) {
+		t.Error("missing 1Click explorer link should fall back to the registry")
+	}
+
+	statusCache.reset()
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v0/status") {
+			fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+			return
+		}
+		fmt.Fprint(w, `{"withdrawals":[]}`)
+	})
+	body = render(&OrderData{DepositAddr: "0xdeposit", FromTicker: "ETH", FromNet: "arb", ToTicker: "SOL", ToNet: "sol", AmountIn: "1"})
+	for _, want := range []string{"<strong>Arbitrum</strong>", "~1 min", `href="https://arbiscan.io/address/0xdeposit"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("pending order page is missing %q", want)
+		}
+	}
+	if body := render(&OrderData{DepositAddr: "addr", FromTicker: "X", FromNet: "mystery", ToTicker: "Y"}); !strings.Contains(body, "<strong>mystery</strong>") {
+		t.Error("unknown chains should still render with their code")
+	}
+}
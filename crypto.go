@@ -9,7 +9,9 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"strconv"
@@ -17,7 +19,38 @@ import (
 	"time"
 )
 
-var orderKey []byte
+// orderKeyring holds the order-token keys. keys[0] is the current key and
+// seals every new token and CSRF token; the rest (from ORDER_SECRET_PREVIOUS)
+// only open existing ones. Remove a key from ORDER_SECRET_PREVIOUS to retire
+// every token sealed with it.
+type orderKeyring struct {
+	keys []orderKey
+	// ephemeral is set when ORDER_SECRET is unset and the key is random:
+	// anything sealed with it is unreadable after a restart.
+	ephemeral bool
+}
+
+type orderKey struct {
+	id  byte // stored in the token header; derived from the key
+	key []byte
+}
+
+var keyring = &orderKeyring{}
+
+// tokenVersion is the first byte of versioned order tokens.
+// Layout: version (1) + key ID (1) + IV (12) + ciphertext + GCM tag (16).
+// Tokens without the header (pre-keyring) are still accepted.
+const tokenVersion = 0x01
+
+func newOrderKey(key []byte) orderKey {
+	sum := sha256.Sum256(append([]byte("zero-order-key-id:"), key...))
+	return orderKey{id: sum[0], key: key}
+}
+
+// current returns the key that seals new tokens.
+func (kr *orderKeyring) current() orderKey {
+	return kr.keys[0]
+}
 
 func initCrypto() {
 	secretHex := os.Getenv("ORDER_SECRET")
@@ -26,18 +59,58 @@ func initCrypto() {
 		if _, err := rand.Read(b); err != nil {
 			log.Fatal("failed to generate random key:", err)
 		}
-		orderKey = b
+		keyring.keys = []orderKey{newOrderKey(b)}
+		keyring.ephemeral = true
 		log.Println("WARNING: ORDER_SECRET not set — generated random key. Tokens will not survive restart.")
 		return
 	}
+	current, err := parseOrderSecret(secretHex)
+	if err != nil {
+		log.Fatal("ORDER_SECRET must be a 64-character hex string (32 bytes)")
+	}
+	keyring.keys = []orderKey{newOrderKey(current)}
+	keyring.ephemeral = false
+
+	// ORDER_SECRET_PREVIOUS: comma-separated retired-but-still-valid keys.
+	for _, h := range strings.Split(os.Getenv("ORDER_SECRET_PREVIOUS"), ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		prev, err := parseOrderSecret(h)
+		if err != nil {
+			log.Fatal("ORDER_SECRET_PREVIOUS entries must be 64-character hex strings (32 bytes)")
+		}
+		keyring.keys = append(keyring.keys, newOrderKey(prev))
+	}
+	if n := len(keyring.keys) - 1; n > 0 {
+		log.Printf("Order keyring: current key + %d previous key(s)", n)
+	}
+}
+
+func parseOrderSecret(secretHex string) ([]byte, error) {
 	decoded, err := hex.DecodeString(secretHex)
 	if err != nil || len(decoded) < 32 {
-		log.Fatal("ORDER_SECRET must be a 64-character hex string (32 bytes)")
+		return nil, fmt.Errorf("invalid order secret")
+	}
+	return decoded[:32], nil
+}
+
+func newOrderGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
 	}
-	orderKey = decoded[:32]
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	return gcm, nil
 }
 
 // OrderData holds the swap metadata encrypted into the order token.
+// New tokens use the binary layout in orderbin.go; the JSON tags decode
+// tokens issued before it.
 type OrderData struct {
 	DepositAddr string `json:"d"`
 	Memo        string `json:"m,omitempty"`
@@ -52,24 +125,31 @@ type OrderData struct {
 	RefundAddr  string `json:"ra,omitempty"`
 	RecvAddr    string `json:"rca,omitempty"`
 	SwapType    string `json:"st,omitempty"` // FLEX_INPUT, EXACT_OUTPUT, ANY_INPUT (empty = FLEX_INPUT)
+
+	// Optional status webhook (see webhook.go). Kept only in the token.
+	CallbackURL    string `json:"cb,omitempty"`
+	CallbackSecret string `json:"cs,omitempty"`
+
+	QuoteSig string `json:"qs,omitempty"` // quote signature check: verified, unchecked (empty = issued before checks)
+
+	// Hex SHA-256 of the quote transcript document (see transcript.go).
+	// Binary tokens only; JSON-era tokens never carried it.
+	TranscriptHash string `json:"-"`
+	// DEFLATE-compressed quote request/response. Only decoded from tokens
+	// issued before TranscriptHash replaced it; new tokens leave it empty.
+	Transcript string `json:"-"`
 }
 
-// encryptOrderData encrypts order data into a base64url token.
-// Format: IV (12 bytes) + GCM tag (16 bytes) + ciphertext → base64url
+// encryptOrderData encrypts order data into a base64url token with the
+// current key. The version/key-ID header is authenticated as GCM
+// additional data.
 func encryptOrderData(data *OrderData) (string, error) {
-	plaintext, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("marshal order data: %w", err)
-	}
-
-	block, err := aes.NewCipher(orderKey)
-	if err != nil {
-		return "", fmt.Errorf("create cipher: %w", err)
-	}
+	plaintext := marshalOrderData(data) // see orderbin.go
 
-	gcm, err := cipher.NewGCM(block)
+	k := keyring.current()
+	gcm, err := newOrderGCM(k.key)
 	if err != nil {
-		return "", fmt.Errorf("create gcm: %w", err)
+		return "", err
 	}
 
 	iv := make([]byte, gcm.NonceSize()) // 12 bytes
@@ -77,65 +157,186 @@ func encryptOrderData(data *OrderData) (string, error) {
 		return "", fmt.Errorf("generate iv: %w", err)
 	}
 
-	// Seal appends ciphertext+tag to dst
-	sealed := gcm.Seal(nil, iv, plaintext, nil)
+	header := []byte{tokenVersion, k.id}
 
-	// Pack: IV + sealed (ciphertext + tag)
-	packed := make([]byte, 0, len(iv)+len(sealed))
+	// Pack: header + IV + sealed (ciphertext + tag)
+	packed := make([]byte, 0, len(header)+len(iv)+len(plaintext)+gcm.Overhead())
+	packed = append(packed, header...)
 	packed = append(packed, iv...)
-	packed = append(packed, sealed...)
+	packed = gcm.Seal(packed, iv, plaintext, header)
 
 	return base64.RawURLEncoding.EncodeToString(packed), nil
 }
 
-// decryptOrderData decrypts a base64url token back to order data.
+// decryptOrderData decrypts a base64url token back to order data. It tries
+// the versioned layout with the key named in the header, then the legacy
+// header-less layout with every key in the ring.
 func decryptOrderData(token string) (*OrderData, error) {
 	packed, err := base64.RawURLEncoding.DecodeString(token)
 	if err != nil {
 		return nil, fmt.Errorf("decode base64: %w", err)
 	}
 
-	block, err := aes.NewCipher(orderKey)
+	plaintext, err := openOrderToken(packed)
 	if err != nil {
-		return nil, fmt.Errorf("create cipher: %w", err)
+		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("create gcm: %w", err)
-	}
+	return unmarshalOrderData(plaintext)
+}
 
-	nonceSize := gcm.NonceSize()
-	if len(packed) < nonceSize+gcm.Overhead() {
+func openOrderToken(packed []byte) ([]byte, error) {
+	const nonceSize, overhead = 12, 16
+	if len(packed) < nonceSize+overhead {
 		return nil, fmt.Errorf("token too short")
 	}
 
-	iv := packed[:nonceSize]
-	ciphertext := packed[nonceSize:]
+	// Versioned: header is authenticated, so a legacy token whose first IV
+	// byte happens to equal tokenVersion simply fails here and falls through.
+	if packed[0] == tokenVersion && len(packed) >= 2+nonceSize+overhead {
+		header, iv, sealed := packed[:2], packed[2:2+nonceSize], packed[2+nonceSize:]
+		for _, k := range keyring.keys {
+			if k.id != header[1] {
+				continue
+			}
+			gcm, err := newOrderGCM(k.key)
+			if err != nil {
+				return nil, err
+			}
+			if plaintext, err := gcm.Open(nil, iv, sealed, header); err == nil {
+				return plaintext, nil
+			}
+		}
+	}
+
+	// Legacy: IV + ciphertext + tag, no header, no additional data.
+	iv, sealed := packed[:nonceSize], packed[nonceSize:]
+	for _, k := range keyring.keys {
+		gcm, err := newOrderGCM(k.key)
+		if err != nil {
+			return nil, err
+		}
+		if plaintext, err := gcm.Open(nil, iv, sealed, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("decrypt: no key in the keyring opens this token")
+}
+
+// atRestKey derives the key for one kind of server-side data (purpose)
+// from an order key, so a leaked file key never opens order tokens.
+func atRestKey(k orderKey, purpose string) []byte {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte("zero-at-rest:" + purpose))
+	return mac.Sum(nil)
+}
+
+// sealAtRest encrypts data the server keeps on disk (Telegram alerts and
+// the like) with a key derived from the current order key. Layout matches
+// order tokens: version (1) + key ID (1) + IV (12) + ciphertext + tag (16).
+func sealAtRest(purpose string, plaintext []byte) ([]byte, error) {
+	k := keyring.current()
+	gcm, err := newOrderGCM(atRestKey(k, purpose))
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("generate iv: %w", err)
+	}
+	header := []byte{tokenVersion, k.id}
+	packed := append(append(header, iv...), gcm.Seal(nil, iv, plaintext, header)...)
+	return packed, nil
+}
+
+// openAtRest decrypts sealAtRest output with whichever key in the ring
+// sealed it, so data survives a key rotation until the old key is retired.
+func openAtRest(purpose string, packed []byte) ([]byte, error) {
+	const nonceSize, overhead = 12, 16
+	if len(packed) < 2+nonceSize+overhead || packed[0] != tokenVersion {
+		return nil, fmt.Errorf("not sealed data")
+	}
+	header, iv, sealed := packed[:2], packed[2:2+nonceSize], packed[2+nonceSize:]
+	for _, k := range keyring.keys {
+		if k.id != header[1] {
+			continue
+		}
+		gcm, err := newOrderGCM(atRestKey(k, purpose))
+		if err != nil {
+			return nil, err
+		}
+		if plaintext, err := gcm.Open(nil, iv, sealed, header); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("decrypt: no key in the keyring opens this data")
+}
 
-	plaintext, err := gcm.Open(nil, iv, ciphertext, nil)
+// loadSealedFile reads a file written by saveSealedFile into v and reports
+// whether it did. A missing file, a file sealed with a key no longer in the
+// keyring, or undecodable contents leave v untouched (the file stays in
+// place). With an ephemeral key nothing is read, and the log says why.
+// what names the data in log lines.
+func loadSealedFile(path, purpose, what string, v interface{}) bool {
+	if keyring.ephemeral {
+		log.Printf("Keeping %s in memory only: without ORDER_SECRET %s could not be read after a restart", what, path)
+		return false
+	}
+	packed, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("decrypt: %w", err)
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("%s read error: %v", what, err)
+		}
+		return false
+	}
+	data, err := openAtRest(purpose, packed)
+	if err == nil {
+		err = json.Unmarshal(data, v)
 	}
+	if err != nil {
+		log.Printf("%s ignored: %v", what, err)
+		return false
+	}
+	return true
+}
 
-	var data OrderData
-	if err := json.Unmarshal(plaintext, &data); err != nil {
-		return nil, fmt.Errorf("unmarshal: %w", err)
+// saveSealedFile encodes v as JSON, seals it with sealAtRest and writes it
+// to path. It writes nothing with an ephemeral key (see loadSealedFile).
+func saveSealedFile(path, purpose, what string, v interface{}) {
+	if keyring.ephemeral {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("%s encode error: %v", what, err)
+		return
+	}
+	packed, err := sealAtRest(purpose, data)
+	if err != nil {
+		log.Printf("%s seal error: %v", what, err)
+		return
+	}
+	if err := writeFileAtomic(path, packed); err != nil {
+		log.Printf("%s write error: %v", what, err)
 	}
-	return &data, nil
 }
 
-// generateCSRFToken creates a stateless CSRF token using HMAC.
+// generateCSRFToken creates a stateless CSRF token using HMAC with the
+// current order key.
 func generateCSRFToken(formID string) string {
 	ts := strconv.FormatInt(time.Now().UnixMilli(), 36)
 	payload := formID + ":" + ts
-	mac := hmac.New(sha256.New, orderKey)
+	return payload + ":" + csrfSignature(keyring.current().key, payload)
+}
+
+func csrfSignature(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
 	mac.Write([]byte(payload))
-	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:16]
-	return payload + ":" + sig
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:16]
 }
 
-// verifyCSRFToken validates a stateless CSRF token.
+// verifyCSRFToken validates a stateless CSRF token against every key in the
+// ring, so forms rendered just before a rotation still submit.
 func verifyCSRFToken(token, formID string, maxAge time.Duration) bool {
 	parts := strings.SplitN(token, ":", 3)
 	if len(parts) != 3 {
@@ -153,8 +354,10 @@ func verifyCSRFToken(token, formID string, maxAge time.Duration) bool {
 		return false
 	}
 	payload := fid + ":" + ts
-	mac := hmac.New(sha256.New, orderKey)
-	mac.Write([]byte(payload))
-	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:16]
-	return hmac.Equal([]byte(sig), []byte(expected))
+	for _, k := range keyring.keys {
+		if hmac.Equal([]byte(sig), []byte(csrfSignature(k.key, payload))) {
+			return true
+		}
+	}
+	return false
 }
@@ -68,7 +68,9 @@ type ExplorerAppFee struct {
 func explorerGet(endpoint string) ([]byte, error) {
 	// Throttle: max 1 request per 6 seconds globally across all callers.
 	if explorerRateCh != nil {
+		start := time.Now()
 		<-explorerRateCh
+		explorerWait.observe(time.Since(start).Seconds())
 	}
 
 	req, err := http.NewRequest("GET", explorerBaseURL+endpoint, nil)
@@ -154,27 +156,12 @@ func txTokenLabel(assetID string) string {
 
 // txChainLabel returns the display chain name for a defuse asset ID.
 func txChainLabel(assetID string) string {
+	code := strings.SplitN(assetID, ":", 2)[0]
 	if t := findTokenByAssetID(assetID); t != nil && t.ChainName != "" {
-		return explorerChainName(t.ChainName)
+		code = t.ChainName
 	}
-	parts := strings.SplitN(assetID, ":", 2)
-	if len(parts) >= 1 {
-		return explorerChainName(parts[0])
-	}
-	return assetID
-}
-
-func explorerChainName(code string) string {
-	m := map[string]string{
-		"eth": "Ethereum", "btc": "Bitcoin", "sol": "Solana", "base": "Base",
-		"arb": "Arbitrum", "ton": "TON", "tron": "TRON", "trx": "TRON",
-		"bsc": "BNB Chain", "pol": "Polygon", "op": "Optimism",
-		"avax": "Avalanche", "near": "NEAR", "sui": "Sui",
-		"doge": "Dogecoin", "ltc": "Litecoin", "xrp": "XRP",
-		"bch": "Bitcoin Cash", "xlm": "Stellar", "nep141": "NEAR",
-	}
-	if dn, ok := m[strings.ToLower(code)]; ok {
-		return dn
+	if c := lookupChain(code); c != nil {
+		return c.Name
 	}
 	return strings.ToUpper(code)
 }
@@ -60,7 +60,8 @@ func tokenColorPair(ticker string) (string, string) {
 type PageData struct {
 	Title       string
 	Error       string
-	MetaRefresh int // seconds; 0 = no refresh
+	MetaRefresh int  // seconds; 0 = no refresh
+	LiveUpdates bool // page streams updates via JS; MetaRefresh becomes the <noscript> fallback
 	FromColor   string
 	FromColorA  string
 	ToColor     string
@@ -69,9 +70,16 @@ type PageData struct {
 	BuildTime   string
 	BuildLogURL string
 	OnionURL    string
+	Degraded    bool   // 1Click circuit breaker is not closed; show a banner
+	TokensAsOf  string // set while tokens come from the on-disk snapshot
+	AtomFeed    string // path of an Atom feed to advertise in <head>
 }
 
 func newPageData(title string) PageData {
+	var tokensAsOf string
+	if at := tokenCacheSnapshotTime(); !at.IsZero() {
+		tokensAsOf = at.UTC().Format("Jan 2, 15:04 UTC")
+	}
 	return PageData{
 		Title:       title,
 		FromColor:   "#ffffff",
@@ -82,6 +90,8 @@ func newPageData(title string) PageData {
 		BuildTime:   buildTime,
 		BuildLogURL: buildLogURL,
 		OnionURL:    onionURL,
+		Degraded:    upstreamDegraded(),
+		TokensAsOf:  tokensAsOf,
 	}
 }
 
@@ -143,10 +153,13 @@ type OrderPageData struct {
 	Order         *OrderData
 	Status        *StatusResponse
 	QRCode        string
+	PaymentURI    string // empty when the chain has no supported URI scheme
+	PlainQR       bool   // QR shows the bare address (?qr=address)
 	TimeRemaining string
 	IsTerminal    bool
 	StatusStep    int // 0=pending, 1=processing, 2=complete
 	Withdrawals   *AnyInputWithdrawalsResponse
+	Chain         *chainInfo // deposit chain; nil when the registry doesn't know it
 }
 
 // CurrenciesPageData is the data for the currencies list page.
@@ -155,6 +168,8 @@ type CurrenciesPageData struct {
 	Networks   []NetworkGroup
 	TotalCount int
 	Search     string
+	NewAssets  map[string]bool // DefuseAssetIDs listed recently
+	Changes    []listingChange // latest listings/delistings, newest first
 }
 
 func renderError(w http.ResponseWriter, status int, title, message, action, actionURL string) {
@@ -266,214 +281,80 @@ func handleQuote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fromTicker := strings.ToUpper(r.FormValue("from"))
-	fromNet := r.FormValue("from_net")
-	toTicker := strings.ToUpper(r.FormValue("to"))
-	toNet := r.FormValue("to_net")
-	amount := r.FormValue("amount")
-	amountOutForm := r.FormValue("amount_out")
-	recipient := strings.TrimSpace(r.FormValue("recipient"))
-	refundAddr := strings.TrimSpace(r.FormValue("refund_addr"))
-	slippage := r.FormValue("slippage")
+	in := &swapInput{
+		FromTicker: r.FormValue("from"),
+		FromNet:    r.FormValue("from_net"),
+		ToTicker:   r.FormValue("to"),
+		ToNet:      r.FormValue("to_net"),
+		Amount:     r.FormValue("amount"),
+		AmountOut:  r.FormValue("amount_out"),
+		Recipient:  r.FormValue("recipient"),
+		RefundAddr: r.FormValue("refund_addr"),
+		Slippage:   r.FormValue("slippage"),
+	}
+	in.normalize()
 
 	// Validation (amount is optional — determines swap type)
-	var errors []string
-	if recipient == "" {
-		errors = append(errors, "Recipient address is required")
-	}
-	if refundAddr == "" {
-		errors = append(errors, "Refund address is required")
-	}
-	if len(errors) > 0 {
+	if errors := in.validate(); len(errors) > 0 {
 		renderError(w, 400, "Validation Error", "Please check your input:\n"+strings.Join(errors, "\n"), "Go Back", "/")
 		return
 	}
 
-	// Find tokens
-	fromToken := findToken(fromTicker, fromNet)
-	toToken := findToken(toTicker, toNet)
-	if fromToken == nil || toToken == nil {
-		renderError(w, 400, "Unknown Token", "Could not find the selected tokens. Try selecting them again.", "Go Back", "/")
+	fromToken, toToken, serr := in.resolveTokens()
+	if serr != nil {
+		renderSwapError(w, serr, "Go Back")
 		return
 	}
 
-	slippageBPS, err := slippageToBPS(slippage)
-	if err != nil {
-		slippageBPS = 100 // default 1%
-	}
-
-	// Auto-detect swap type based on which amount field is filled.
-	// Both filled: prefer send amount (FLEX_INPUT).
-	swapType := "FLEX_INPUT"
-	if amount == "" && amountOutForm == "" {
-		swapType = "ANY_INPUT"
-	} else if amountOutForm != "" && amount == "" {
-		swapType = "EXACT_OUTPUT"
-	}
-
 	// ANY_INPUT: skip dry quote, go directly to real quote → deposit page.
-	if swapType == "ANY_INPUT" {
-		refAmount, _ := humanToAtomic("1", fromToken.Decimals)
-		quoteReq := &QuoteRequest{
-			Dry:                false,
-			SwapType:           "ANY_INPUT",
-			SlippageTolerance:  slippageBPS,
-			OriginAsset:        fromToken.DefuseAssetID,
-			DepositType:        "ORIGIN_CHAIN",
-			DestinationAsset:   toToken.DefuseAssetID,
-			Amount:             refAmount,
-			RefundTo:           refundAddr,
-			RefundType:         "ORIGIN_CHAIN",
-			Recipient:          recipient,
-			RecipientType:      "DESTINATION_CHAIN",
-			Deadline:           buildDeadline(time.Hour),
-			QuoteWaitingTimeMs: 8000,
-			AppFees:            []struct{}{},
-		}
-		quoteResp, err := requestQuote(quoteReq)
-		if err != nil {
-			renderError(w, 502, "Quick Swap Failed", "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.", "Try Again", "/")
-			return
-		}
-		orderData := &OrderData{
-			DepositAddr: quoteResp.Quote.DepositAddress,
-			Memo:        quoteResp.Quote.DepositMemo,
-			FromTicker:  fromTicker,
-			FromNet:     fromNet,
-			ToTicker:    toTicker,
-			ToNet:       toNet,
-			AmountIn:    "any",
-			AmountOut:   "market rate",
-			Deadline:    quoteResp.Quote.Deadline,
-			CorrID:      quoteResp.CorrelationID,
-			RefundAddr:  refundAddr,
-			RecvAddr:    recipient,
-			SwapType:    "ANY_INPUT",
-		}
-		token, err := encryptOrderData(orderData)
-		if err != nil {
-			renderError(w, 500, "Internal Error", "Failed to create order token.", "Back to Home", "/")
+	if in.swapType() == "ANY_INPUT" {
+		refAmount, _ := in.atomicAmount("ANY_INPUT", fromToken, toToken)
+		_, token, serr := placeOrder(r.Context(), in, fromToken, toToken, "ANY_INPUT", refAmount, in.slippageBPS(), "", "")
+		if serr != nil {
+			renderSwapError(w, serr, "Try Again")
 			return
 		}
 		http.Redirect(w, r, "/order/"+token, http.StatusFound)
 		return
 	}
 
-	// FLEX_INPUT or EXACT_OUTPUT: convert the appropriate amount to atomic.
-	var atomicAmount string
-	if swapType == "EXACT_OUTPUT" {
-		atomicAmount, err = humanToAtomic(amountOutForm, toToken.Decimals)
-	} else {
-		atomicAmount, err = humanToAtomic(amount, fromToken.Decimals)
-	}
-	if err != nil {
-		renderError(w, 400, "Invalid Amount", "Could not parse the amount: "+err.Error(), "Go Back", "/")
-		return
-	}
-
-	// Request dry quote from NEAR Intents
-	quoteReq := &QuoteRequest{
-		Dry:                true,
-		SwapType:           swapType,
-		SlippageTolerance:  slippageBPS,
-		OriginAsset:        fromToken.DefuseAssetID,
-		DepositType:        "ORIGIN_CHAIN",
-		DestinationAsset:   toToken.DefuseAssetID,
-		Amount:             atomicAmount,
-		RefundTo:           refundAddr,
-		RefundType:         "ORIGIN_CHAIN",
-		Recipient:          recipient,
-		RecipientType:      "DESTINATION_CHAIN",
-		Deadline:           buildDeadline(time.Hour),
-		QuoteWaitingTimeMs: 8000,
-		AppFees:            []struct{}{},
-	}
-
-	dryResp, err := requestDryQuote(quoteReq)
-	if err != nil {
-		renderError(w, 502, "Quote Failed", "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.", "Try Again", "/")
-		return
-	}
-
-	// Extract amounts from dry quote response.
-	// For EXACT_OUTPUT, AmountIn is estimated and AmountOut is exact.
-	// For FLEX_INPUT, both are approximate.
-	humanIn := dryResp.Quote.AmountInFormatted
-	humanOut := dryResp.Quote.AmountOutFormatted
-	if humanIn == "" {
-		humanIn = amount
-	}
-	if humanOut == "" {
-		humanOut = atomicToHuman(dryResp.Quote.AmountOut, toToken.Decimals)
-	}
-
-	if dryResp.Quote.AmountOut == "" || dryResp.Quote.AmountOut == "0" {
-		renderError(w, 502, "Quote Unavailable", "No market makers are currently offering a rate for this pair/amount. Try a larger amount or a different pair.", "Go Back", "/")
+	pq, serr := prepareQuote(r.Context(), in, fromToken, toToken)
+	if serr != nil {
+		renderSwapError(w, serr, "Go Back")
 		return
 	}
 
-	// USD values
-	amountInUSD := ""
-	amountOutUSD := ""
-	spreadUSD := ""
-	spreadPct := ""
-	rate := ""
-
-	inFloat, _ := parseFloat(humanIn)
-	outFloat, _ := parseFloat(humanOut)
-
-	if fromToken.Price > 0 && inFloat > 0 {
-		inUSD := inFloat * fromToken.Price
-		amountInUSD = formatUSD(inUSD)
-
-		if toToken.Price > 0 && outFloat > 0 {
-			outUSD := outFloat * toToken.Price
-			amountOutUSD = formatUSD(outUSD)
-
-			spread := inUSD - outUSD
-			if spread < 0 {
-				spread = 0
-			}
-			spreadUSD = formatUSD(spread)
-			if inUSD > 0 {
-				spreadPct = fmt.Sprintf("%.2f%%", (spread/inUSD)*100)
-			}
-
-			rate = fmt.Sprintf("1 %s = %s %s", fromTicker, formatRate(outFloat/inFloat), toTicker)
-		}
-	}
-
 	data := QuotePageData{
 		PageData:     newPageData("Quote Preview"),
-		From:         fromTicker,
-		FromNet:      fromNet,
-		FromTicker:   fromTicker,
-		To:           toTicker,
-		ToNet:        toNet,
-		ToTicker:     toTicker,
-		AmountIn:     humanIn,
-		AmountInUSD:  amountInUSD,
-		AmountOut:    humanOut,
-		AmountOutUSD: amountOutUSD,
-		Rate:         rate,
-		Recipient:    recipient,
-		RefundAddr:   refundAddr,
-		Slippage:     slippage,
-		SlippageBPS:  slippageBPS,
+		From:         in.FromTicker,
+		FromNet:      in.FromNet,
+		FromTicker:   in.FromTicker,
+		To:           in.ToTicker,
+		ToNet:        in.ToNet,
+		ToTicker:     in.ToTicker,
+		AmountIn:     pq.AmountIn,
+		AmountInUSD:  pq.AmountInUSD,
+		AmountOut:    pq.AmountOut,
+		AmountOutUSD: pq.AmountOutUSD,
+		Rate:         pq.Rate,
+		Recipient:    in.Recipient,
+		RefundAddr:   in.RefundAddr,
+		Slippage:     in.Slippage,
+		SlippageBPS:  pq.SlippageBPS,
 		CSRFToken:    generateCSRFToken("swap"),
 		OriginAsset:  fromToken.DefuseAssetID,
 		DestAsset:    toToken.DefuseAssetID,
-		AtomicAmount: atomicAmount,
-		SpreadUSD:    spreadUSD,
-		SpreadPct:    spreadPct,
+		AtomicAmount: pq.AtomicAmount,
+		SpreadUSD:    pq.SpreadUSD,
+		SpreadPct:    pq.SpreadPct,
 		FromToken:    fromToken,
 		ToToken:      toToken,
 		HasJWT:       nearIntentsJWT != "",
-		SwapType:     swapType,
+		SwapType:     pq.SwapType,
 	}
 
-	data.FromColor, data.FromColorA = tokenColorPair(fromTicker)
-	data.ToColor, data.ToColorA = tokenColorPair(toTicker)
+	data.FromColor, data.FromColorA = tokenColorPair(in.FromTicker)
+	data.ToColor, data.ToColorA = tokenColorPair(in.ToTicker)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	templates.ExecuteTemplate(w, "quote.html", data)
@@ -499,100 +380,69 @@ func handleSwapConfirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fromTicker := strings.ToUpper(r.FormValue("from"))
-	fromNet := r.FormValue("from_net")
-	toTicker := strings.ToUpper(r.FormValue("to"))
-	toNet := r.FormValue("to_net")
+	in := &swapInput{
+		FromTicker: r.FormValue("from"),
+		FromNet:    r.FormValue("from_net"),
+		ToTicker:   r.FormValue("to"),
+		ToNet:      r.FormValue("to_net"),
+		Recipient:  r.FormValue("recipient"),
+		RefundAddr: r.FormValue("refund_addr"),
+
+		CallbackURL:    r.FormValue("callback_url"),
+		CallbackSecret: r.FormValue("callback_secret"),
+	}
+	in.normalize()
+	if errors := in.validate(); len(errors) > 0 {
+		renderError(w, 400, "Validation Error", "Please check your input:\n"+strings.Join(errors, "\n"), "Go Back", "/")
+		return
+	}
 	atomicAmount := r.FormValue("atomic_amount")
 	userAmountIn := r.FormValue("amount_in")   // user's original input
-	userAmountOut := r.FormValue("amount_out")  // user's original output (EXACT_OUTPUT)
-	recipient := r.FormValue("recipient")
-	refundAddr := r.FormValue("refund_addr")
-	slippageBPS := r.FormValue("slippage_bps")
+	userAmountOut := r.FormValue("amount_out") // user's original output (EXACT_OUTPUT)
 	swapType := r.FormValue("swap_type")
 	if swapType == "" {
 		swapType = "FLEX_INPUT"
 	}
 
-	fromToken := findToken(fromTicker, fromNet)
-	toToken := findToken(toTicker, toNet)
-	if fromToken == nil || toToken == nil {
-		renderError(w, 400, "Unknown Token", "Token not found.", "Back to Home", "/")
+	fromToken, toToken, serr := in.resolveTokens()
+	if serr != nil {
+		renderSwapError(w, serr, "Back to Home")
 		return
 	}
 
 	bps := 100
-	fmt.Sscanf(slippageBPS, "%d", &bps)
-
-	// Real quote (not dry)
-	quoteReq := &QuoteRequest{
-		Dry:                false,
-		SwapType:           swapType,
-		SlippageTolerance:  bps,
-		OriginAsset:        fromToken.DefuseAssetID,
-		DepositType:        "ORIGIN_CHAIN",
-		DestinationAsset:   toToken.DefuseAssetID,
-		Amount:             atomicAmount,
-		RefundTo:           refundAddr,
-		RefundType:         "ORIGIN_CHAIN",
-		Recipient:          recipient,
-		RecipientType:      "DESTINATION_CHAIN",
-		Deadline:           buildDeadline(time.Hour),
-		QuoteWaitingTimeMs: 8000,
-		AppFees:            []struct{}{},
-	}
-
-	quoteResp, err := requestQuote(quoteReq)
-	if err != nil {
-		renderError(w, 502, "Swap Failed", "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.", "Try Again", "/")
-		return
-	}
-
-	// For FLEX_INPUT, use the user's original amount (the API may return a
-	// different amountIn since FLEX_INPUT accepts a range). For EXACT_OUTPUT,
-	// use the user's desired output and the API's estimated input.
-	amountIn := quoteResp.Quote.AmountInFmt
-	amountOut := quoteResp.Quote.AmountOutFmt
-	if swapType == "FLEX_INPUT" && userAmountIn != "" {
-		amountIn = userAmountIn
-	}
-	if swapType == "EXACT_OUTPUT" && userAmountOut != "" {
-		amountOut = userAmountOut
-	}
-
-	orderData := &OrderData{
-		DepositAddr: quoteResp.Quote.DepositAddress,
-		Memo:        quoteResp.Quote.DepositMemo,
-		FromTicker:  fromTicker,
-		FromNet:     fromNet,
-		ToTicker:    toTicker,
-		ToNet:       toNet,
-		AmountIn:    amountIn,
-		AmountOut:   amountOut,
-		Deadline:    quoteResp.Quote.Deadline,
-		CorrID:      quoteResp.CorrelationID,
-		RefundAddr:  refundAddr,
-		RecvAddr:    recipient,
-		SwapType:    swapType,
-	}
+	fmt.Sscanf(r.FormValue("slippage_bps"), "%d", &bps)
 
-	token, err := encryptOrderData(orderData)
-	if err != nil {
-		renderError(w, 500, "Internal Error", "Failed to create order token.", "Back to Home", "/")
+	_, token, serr := placeOrder(r.Context(), in, fromToken, toToken, swapType, atomicAmount, bps, userAmountIn, userAmountOut)
+	if serr != nil {
+		renderSwapError(w, serr, "Try Again")
 		return
 	}
 
 	http.Redirect(w, r, "/order/"+token, http.StatusFound)
 }
 
+// renderSwapError renders a shared swap-flow failure as an HTML error page.
+func renderSwapError(w http.ResponseWriter, serr *swapError, action string) {
+	renderError(w, serr.Status, serr.Title, serr.Message, action, "/")
+}
+
 // handleOrder renders the order status page.
 func handleOrder(w http.ResponseWriter, r *http.Request) {
-	// Extract token from path: /order/{token} or /order/{token}/raw
+	// Extract token from path: /order/{token}, /order/{token}/raw or /order/{token}/events
 	path := strings.TrimPrefix(r.URL.Path, "/order/")
 	isRaw := strings.HasSuffix(path, "/raw")
 	if isRaw {
 		path = strings.TrimSuffix(path, "/raw")
 	}
+	isEvents := strings.HasSuffix(path, "/events")
+	if isEvents {
+		path = strings.TrimSuffix(path, "/events")
+	}
+	isTranscript := strings.HasSuffix(path, "/transcript")
+	if isTranscript {
+		path = strings.TrimSuffix(path, "/transcript")
+	}
 
 	if path == "" {
 		renderError(w, 400, "Missing Order", "No order token provided.", "Create New Swap", "/")
@@ -605,14 +455,25 @@ func handleOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch live status from NEAR Intents
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
-	if err != nil {
-		// If API is down, still show what we know from the token
-		status = &StatusResponse{Status: "UNKNOWN"}
+	// Resume the status webhook if this process isn't already watching.
+	webhooks.resume(order)
+
+	if isEvents {
+		handleOrderEvents(w, r, order)
+		return
+	}
+
+	if isTranscript {
+		handleOrderTranscript(w, r, order)
+		return
 	}
 
 	if isRaw {
+		// Fetch live status from NEAR Intents
+		status, err := fetchStatus(r.Context(), order.DepositAddr, order.Memo)
+		if err != nil {
+			status = &StatusResponse{Status: "UNKNOWN"}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		if status.RawJSON != nil {
 			w.Write(status.RawJSON)
@@ -622,45 +483,17 @@ func handleOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Determine status step and terminal state
-	isTerminal := false
-	statusStep := 0
-	switch status.Status {
-	case "PENDING_DEPOSIT":
-		statusStep = 0
-	case "PROCESSING":
-		statusStep = 1
-	case "SUCCESS":
-		statusStep = 2
-		isTerminal = true
-	case "REFUNDED", "FAILED", "INCOMPLETE_DEPOSIT":
-		statusStep = 2
-		isTerminal = true
-	default:
-		statusStep = 0
-	}
-
-	// Calculate time remaining
-	timeRemaining := ""
-	if order.Deadline != "" {
-		dl, err := time.Parse(time.RFC3339, order.Deadline)
-		if err == nil {
-			remaining := time.Until(dl)
-			if remaining > 0 {
-				mins := int(remaining.Minutes())
-				if mins >= 60 {
-					timeRemaining = fmt.Sprintf("%dh %dm", mins/60, mins%60)
-				} else {
-					timeRemaining = fmt.Sprintf("%dm", mins)
-				}
-			} else {
-				timeRemaining = "Expired"
-			}
-		}
-	}
+	status, withdrawals := loadOrderStatus(r.Context(), order)
+	statusStep, isTerminal := orderStatusStep(status.Status)
 
-	// Generate QR code
+	// Generate QR code: a payment URI where the chain supports one, unless
+	// the visitor asked for the plain address (?qr=address).
+	payURI := paymentURI(order)
+	plainQR := r.URL.Query().Get("qr") == "address"
 	qrData := order.DepositAddr
+	if payURI != "" && !plainQR {
+		qrData = payURI
+	}
 	qrSVG := generateQRSVG(qrData, 200)
 
 	refresh := 0
@@ -668,24 +501,22 @@ func handleOrder(w http.ResponseWriter, r *http.Request) {
 		refresh = 10
 	}
 
-	// For ANY_INPUT orders, fetch withdrawal history.
-	var withdrawals *AnyInputWithdrawalsResponse
-	if order.SwapType == "ANY_INPUT" {
-		withdrawals, _ = fetchAnyInputWithdrawals(order.DepositAddr)
-	}
-
 	data := OrderPageData{
 		PageData:      newPageData("Order Status"),
 		Token:         path,
 		Order:         order,
 		Status:        status,
 		QRCode:        qrSVG,
-		TimeRemaining: timeRemaining,
+		PaymentURI:    payURI,
+		PlainQR:       plainQR,
+		TimeRemaining: orderTimeRemaining(order.Deadline),
 		IsTerminal:    isTerminal,
 		StatusStep:    statusStep,
 		Withdrawals:   withdrawals,
+		Chain:         lookupChain(order.FromNet),
 	}
 	data.MetaRefresh = refresh
+	data.LiveUpdates = !isTerminal
 	data.FromColor, data.FromColorA = tokenColorPair(order.FromTicker)
 	data.ToColor, data.ToColorA = tokenColorPair(order.ToTicker)
 
@@ -716,6 +547,11 @@ func handleCurrencies(w http.ResponseWriter, r *http.Request) {
 		Networks:   networks,
 		TotalCount: totalCount,
 		Search:     search,
+		NewAssets:  newListings(time.Now()),
+	}
+	data.AtomFeed = "/currencies/feed.atom"
+	if search == "" {
+		data.Changes = recentListingChanges(8)
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -863,9 +699,9 @@ func handleVerify(w http.ResponseWriter, r *http.Request) {
 
 	// Env var status (key names only — never values)
 	envKeys := []string{
-		"ORDER_SECRET", "NEAR_INTENTS_JWT", "NEAR_INTENTS_EXPLORER_JWT", "NEAR_INTENTS_API_URL", "PORT",
-		"TG_BOT_TOKEN", "TG_APP_URL", "TG_WEBHOOK_SECRET",
-		"TG_MONITOR_GROUP_ID", "TG_MAIN_CHAT_ID",
+		"ORDER_SECRET", "ORDER_SECRET_PREVIOUS", "NEAR_INTENTS_JWT", "NEAR_INTENTS_EXPLORER_JWT", "NEAR_INTENTS_SIGNER_KEY", "NEAR_INTENTS_API_URL", "NEAR_INTENTS_SIMULATOR", "TOKEN_SNAPSHOT_PATH", "PRICE_HISTORY_PATH", "CHAIN_REGISTRY_PATH", "PORT", "METRICS_TOKEN",
+		"TG_BOT_TOKEN", "TG_APP_URL", "TG_WEBHOOK_SECRET", "TG_UPDATE_MODE", "TG_OFFSET_PATH",
+		"TG_MONITOR_GROUP_ID", "TG_MAIN_CHAT_ID", "TG_ANNOUNCE_LISTINGS", "TG_ORDER_RETENTION", "TG_ALERTS_PATH", "TG_ADDRESS_BOOK_PATH",
 		"TG_SWAPMY_THREAD_ID", "TG_EAGLESWAP_THREAD_ID", "TG_LIZARDSWAP_THREAD_ID",
 	}
 	var envVars []EnvVarStatus
@@ -899,22 +735,29 @@ func handleGenIcon(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, generateTokenIconSVG(ticker))
 }
 
-// filterNetworks filters network groups by a search query.
+// filterNetworks filters network groups by a search query. Groups and the
+// tokens in them are ordered by best match.
 func filterNetworks(networks []NetworkGroup, query string) []NetworkGroup {
-	q := strings.ToLower(query)
-	var filtered []NetworkGroup
+	var all []TokenInfo
+	groupOf := make(map[string]string)
 	for _, ng := range networks {
-		var tokens []TokenInfo
+		all = append(all, ng.Tokens...)
 		for _, t := range ng.Tokens {
-			if strings.Contains(strings.ToLower(t.Ticker), q) ||
-				strings.Contains(strings.ToLower(t.Name), q) ||
-				strings.Contains(strings.ToLower(ng.Name), q) {
-				tokens = append(tokens, t)
-			}
+			groupOf[t.DefuseAssetID] = ng.Name
 		}
-		if len(tokens) > 0 {
-			filtered = append(filtered, NetworkGroup{Name: ng.Name, Tokens: tokens})
+	}
+
+	var filtered []NetworkGroup
+	index := make(map[string]int)
+	for _, t := range rankTokens(all, query) {
+		name := groupOf[t.DefuseAssetID]
+		i, ok := index[name]
+		if !ok {
+			i = len(filtered)
+			index[name] = i
+			filtered = append(filtered, NetworkGroup{Name: name})
 		}
+		filtered[i].Tokens = append(filtered[i].Tokens, t)
 	}
 	return filtered
 }
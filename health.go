@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Liveness and readiness probes for orchestrators.
+//
+//	/healthz — the process is up and serving HTTP. Always 200.
+//	/readyz  — the swap flow can work: the token cache is populated and
+//	           fresh enough, and 1Click answers. 503 otherwise.
+//
+// Telegram webhook state is reported but never fails readiness; the web
+// swap flow doesn't depend on it.
+
+// readyMaxCacheAge is the oldest token cache /readyz accepts. The refresher
+// runs every tokenCacheTTL, so this allows two missed refreshes.
+const readyMaxCacheAge = 3 * tokenCacheTTL
+
+// upstreamProbeTTL limits how often /readyz actually calls 1Click, so a
+// tight orchestrator probe loop doesn't turn into upstream load.
+const upstreamProbeTTL = 30 * time.Second
+
+type upstreamProbe struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	latency   time.Duration
+	err       error
+}
+
+var nearProbe = &upstreamProbe{}
+
+// check returns the cached probe result, refreshing it when older than
+// upstreamProbeTTL. Concurrent callers wait for a single in-flight probe.
+func (p *upstreamProbe) check() (checkedAt time.Time, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.checkedAt) >= upstreamProbeTTL {
+		// Not tied to the caller's request: the result is shared.
+		ctx, cancel := upstreamContext()
+		start := time.Now()
+		_, p.err = intents.Tokens(ctx)
+		cancel()
+		p.latency = time.Since(start)
+		p.checkedAt = time.Now()
+	}
+	return p.checkedAt, p.latency, p.err
+}
+
+type readyCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type readyTokenCache struct {
+	readyCheck
+	Tokens      int    `json:"tokens"`
+	UpdatedAt   string `json:"updatedAt,omitempty"`
+	AgeSeconds  int64  `json:"ageSeconds,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+	LastErrorAt string `json:"lastErrorAt,omitempty"`
+	Source      string `json:"source,omitempty"` // "live" or "snapshot"
+}
+
+type readyUpstream struct {
+	readyCheck
+	CheckedAt string `json:"checkedAt"`
+	LatencyMS int64  `json:"latencyMs"`
+	Circuit   string `json:"circuit"` // closed, open or half-open
+}
+
+type readyTelegram struct {
+	Enabled     bool   `json:"enabled"`
+	Mode        string `json:"mode,omitempty"` // "webhook" or "polling"
+	Registered  bool   `json:"registered"`
+	LastError   string `json:"lastError,omitempty"`
+	AttemptedAt string `json:"attemptedAt,omitempty"`
+}
+
+type readyResponse struct {
+	Status     string          `json:"status"` // "ready" or "unavailable"
+	Commit     string          `json:"commit"`
+	TokenCache readyTokenCache `json:"tokenCache"`
+	Upstream   readyUpstream   `json:"upstream"`
+	Telegram   readyTelegram   `json:"telegram"`
+}
+
+// handleHealthz reports liveness only — no upstream calls.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":        "ok",
+		"commit":        commitHash,
+		"uptimeSeconds": int64(time.Since(serverStartTime).Seconds()),
+	})
+}
+
+// handleReadyz reports whether the swap flow can currently work.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := readyResponse{Commit: commitHash}
+
+	// Token cache
+	cache.mu.RLock()
+	tc := readyTokenCache{Tokens: len(cache.tokens)}
+	updatedAt := cache.updatedAt
+	if !updatedAt.IsZero() {
+		tc.Source = "live"
+		if cache.fromSnapshot {
+			tc.Source = "snapshot"
+		}
+	}
+	if cache.lastErr != nil {
+		tc.LastError = cache.lastErr.Error()
+		tc.LastErrorAt = cache.lastErrAt.UTC().Format(time.RFC3339)
+	}
+	cache.mu.RUnlock()
+
+	if !updatedAt.IsZero() {
+		age := time.Since(updatedAt)
+		tc.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		tc.AgeSeconds = int64(age.Seconds())
+		tc.OK = tc.Tokens > 0 && age <= readyMaxCacheAge
+		if !tc.OK {
+			tc.Detail = "token cache is stale"
+		}
+	} else {
+		tc.Detail = "token cache has never loaded"
+	}
+	resp.TokenCache = tc
+
+	// 1Click reachability
+	checkedAt, latency, err := nearProbe.check()
+	resp.Upstream = readyUpstream{
+		readyCheck: readyCheck{OK: err == nil},
+		CheckedAt:  checkedAt.UTC().Format(time.RFC3339),
+		LatencyMS:  latency.Milliseconds(),
+		Circuit:    upstreamBreaker.state().String(),
+	}
+	if err != nil {
+		resp.Upstream.Detail = err.Error()
+	}
+
+	// Telegram (informational)
+	tgWebhook.mu.Lock()
+	resp.Telegram = readyTelegram{
+		Enabled:    tgWebhook.enabled,
+		Registered: tgWebhook.registered,
+		LastError:  tgWebhook.lastErr,
+	}
+	if tgWebhook.enabled {
+		resp.Telegram.Mode = "webhook"
+		if tgUsePolling {
+			resp.Telegram.Mode = "polling"
+		}
+	}
+	if !tgWebhook.at.IsZero() {
+		resp.Telegram.AttemptedAt = tgWebhook.at.UTC().Format(time.RFC3339)
+	}
+	tgWebhook.mu.Unlock()
+
+	status := http.StatusOK
+	resp.Status = "ready"
+	if !resp.TokenCache.OK || !resp.Upstream.OK {
+		status = http.StatusServiceUnavailable
+		resp.Status = "unavailable"
+	}
+	writeJSON(w, status, resp)
+}
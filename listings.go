@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Each successful token refresh is compared with the previous list by
+// DefuseAssetID. Assets that appear are logged as listings, assets that
+// disappear as delistings. The log backs the "New" badges on /currencies,
+// the Atom feed at /currencies/feed.atom and, when TG_ANNOUNCE_LISTINGS is
+// set, posts to the TG_MAIN_CHAT_ID channel. With TOKEN_SNAPSHOT_PATH set
+// the log and the last known list survive restarts inside the snapshot.
+
+const (
+	listingLogMax = 200                // changes kept, newest first
+	listingNewFor = 7 * 24 * time.Hour // how long a listing shows as "New"
+
+	// A refresh that drops more than a quarter of the known assets is more
+	// likely a partial upstream response than a mass delisting. It is held
+	// back until it repeats listingHoldRefreshes times in a row.
+	listingHoldRefreshes = 3
+)
+
+type listingChange struct {
+	Kind  string    `json:"kind"` // "listed" or "delisted"
+	At    time.Time `json:"at"`
+	Token TokenInfo `json:"token"`
+}
+
+// Network is the display name of the token's chain.
+func (c listingChange) Network() string {
+	return chainName(c.Token.ChainName)
+}
+
+var listings struct {
+	mu      sync.RWMutex
+	known   map[string]TokenInfo // by DefuseAssetID, as of the last accepted diff
+	changes []listingChange      // newest first
+	held    int                  // consecutive suspicious refreshes held back
+}
+
+// seedListings restores the known asset list and change log, e.g. from the
+// token snapshot, without recording anything.
+func seedListings(tokens []TokenInfo, changes []listingChange) {
+	known := make(map[string]TokenInfo, len(tokens))
+	for _, t := range tokens {
+		known[t.DefuseAssetID] = t
+	}
+	if len(changes) > listingLogMax {
+		changes = changes[:listingLogMax]
+	}
+	listings.mu.Lock()
+	listings.known, listings.changes, listings.held = known, changes, 0
+	listings.mu.Unlock()
+}
+
+// recordListingChanges diffs tokens against the known list, logs what was
+// listed or delisted at `at` and returns the new changes. The first list
+// seen only establishes the baseline.
+func recordListingChanges(tokens []TokenInfo, at time.Time) []listingChange {
+	next := make(map[string]TokenInfo, len(tokens))
+	for _, t := range tokens {
+		next[t.DefuseAssetID] = t
+	}
+
+	listings.mu.Lock()
+	defer listings.mu.Unlock()
+	if len(listings.known) == 0 {
+		listings.known = next
+		return nil
+	}
+
+	var changes []listingChange
+	removed := 0
+	for id, t := range next {
+		if _, ok := listings.known[id]; !ok {
+			changes = append(changes, listingChange{Kind: "listed", At: at, Token: t})
+		}
+	}
+	for id, t := range listings.known {
+		if _, ok := next[id]; !ok {
+			changes = append(changes, listingChange{Kind: "delisted", At: at, Token: t})
+			removed++
+		}
+	}
+	if removed*4 > len(listings.known) && listings.held < listingHoldRefreshes-1 {
+		listings.held++
+		log.Printf("Token listings: %d of %d assets missing from refresh; holding back (%d/%d)",
+			removed, len(listings.known), listings.held, listingHoldRefreshes)
+		return nil
+	}
+	listings.held = 0
+	listings.known = next
+	if len(changes) == 0 {
+		return nil
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind == "listed"
+		}
+		if changes[i].Token.Ticker != changes[j].Token.Ticker {
+			return changes[i].Token.Ticker < changes[j].Token.Ticker
+		}
+		return changes[i].Token.DefuseAssetID < changes[j].Token.DefuseAssetID
+	})
+	for _, c := range changes {
+		tokenListingChanges.inc(c.Kind)
+		log.Printf("Token %s: %s on %s (%s)", c.Kind, c.Token.Ticker, c.Network(), c.Token.DefuseAssetID)
+	}
+
+	merged := make([]listingChange, 0, len(changes)+len(listings.changes))
+	merged = append(merged, changes...)
+	merged = append(merged, listings.changes...)
+	listings.changes = merged[:min(len(merged), listingLogMax)]
+	return changes
+}
+
+// recentListingChanges returns up to n logged changes, newest first
+// (all of them for n <= 0).
+func recentListingChanges(n int) []listingChange {
+	listings.mu.RLock()
+	defer listings.mu.RUnlock()
+	if n <= 0 || n > len(listings.changes) {
+		n = len(listings.changes)
+	}
+	return append([]listingChange(nil), listings.changes[:n]...)
+}
+
+// newListings returns the asset IDs listed within listingNewFor that have
+// not been delisted since.
+func newListings(now time.Time) map[string]bool {
+	listings.mu.RLock()
+	defer listings.mu.RUnlock()
+	fresh := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, c := range listings.changes {
+		if now.Sub(c.At) > listingNewFor {
+			break
+		}
+		id := c.Token.DefuseAssetID
+		if !seen[id] {
+			seen[id] = true
+			fresh[id] = c.Kind == "listed"
+		}
+	}
+	return fresh
+}
+
+// ── Atom feed ──
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+// listingEntryID is a stable tag: URI for one change.
+func listingEntryID(c listingChange) string {
+	return fmt.Sprintf("tag:zero.uswap.net,2026:listings/%s/%s/%d", c.Kind, url.PathEscape(c.Token.DefuseAssetID), c.At.Unix())
+}
+
+// handleListingsFeed serves the listing log as an Atom feed.
+func handleListingsFeed(w http.ResponseWriter, r *http.Request) {
+	base := requestBaseURL(r)
+	changes := recentListingChanges(0)
+
+	updated := serverStartTime
+	if len(changes) > 0 {
+		updated = changes[0].At
+	}
+	feed := atomFeed{
+		Title:   "uSwap Zero — token listings",
+		ID:      "tag:zero.uswap.net,2026:listings",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "uSwap Zero"},
+		Links: []atomLink{
+			{Href: base + "/currencies/feed.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/currencies", Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, c := range changes {
+		verb, link := "Listed", base+"/?from="+url.QueryEscape(c.Token.Ticker)+"&from_net="+url.QueryEscape(strings.ToLower(c.Token.ChainName))
+		if c.Kind == "delisted" {
+			verb, link = "Delisted", base+"/currencies"
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   fmt.Sprintf("%s: %s on %s", verb, c.Token.Ticker, c.Network()),
+			ID:      listingEntryID(c),
+			Updated: c.At.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: link},
+			Summary: fmt.Sprintf("%s %s on %s (asset %s).", verb, c.Token.Ticker, c.Network(), c.Token.DefuseAssetID),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Printf("listings feed encode error: %v", err)
+	}
+}
+
+// requestBaseURL returns the scheme and host the request came in on.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// ── Telegram announcements ──
+
+// listingAnnounceChatID is TG_MAIN_CHAT_ID when TG_ANNOUNCE_LISTINGS is
+// set; zero disables announcements. Changes found before the bot is up
+// wait in listingAnnouncements.
+var (
+	listingAnnounceChatID = func() int64 {
+		if os.Getenv("TG_ANNOUNCE_LISTINGS") == "" || os.Getenv("TG_BOT_TOKEN") == "" {
+			return 0
+		}
+		return envInt64("TG_MAIN_CHAT_ID")
+	}()
+	listingAnnouncements = make(chan listingChange, 64)
+)
+
+// queueListingAnnouncements hands changes to the announcer, dropping them
+// if it has fallen far behind.
+func queueListingAnnouncements(changes []listingChange) {
+	if listingAnnounceChatID == 0 {
+		return
+	}
+	for _, c := range changes {
+		select {
+		case listingAnnouncements <- c:
+		default:
+			log.Printf("Token listings: announcement queue full, dropping %s %s", c.Kind, c.Token.Ticker)
+		}
+	}
+}
+
+// startListingAnnouncer posts queued changes to the main chat, spaced out
+// to stay under Telegram's per-chat rate limit.
+func startListingAnnouncer() {
+	if listingAnnounceChatID == 0 {
+		return
+	}
+	go func() {
+		for c := range listingAnnouncements {
+			if _, err := tgSendMessage(listingAnnounceChatID, listingAnnouncementText(c), nil); err != nil {
+				log.Printf("listing announcement failed: %v", err)
+			}
+			time.Sleep(3 * time.Second)
+		}
+	}()
+}
+
+func listingAnnouncementText(c listingChange) string {
+	name := html.EscapeString(c.Token.Ticker) + " on " + html.EscapeString(c.Network())
+	if c.Kind == "delisted" {
+		return "🚫 <b>Delisted:</b> " + name + "\n<code>" + html.EscapeString(c.Token.DefuseAssetID) + "</code>"
+	}
+	swapURL := tgAppURL + "/?from=" + url.QueryEscape(c.Token.Ticker) + "&from_net=" + url.QueryEscape(strings.ToLower(c.Token.ChainName))
+	return "🆕 <b>New listing:</b> " + name + "\n<code>" + html.EscapeString(c.Token.DefuseAssetID) + "</code>\n" +
+		"<a href=\"" + html.EscapeString(swapURL) + "\">Swap now →</a>"
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withListings starts from an empty listing log and restores it afterwards.
+func withListings(t *testing.T) {
+	t.Helper()
+	listings.mu.Lock()
+	known, changes, held := listings.known, listings.changes, listings.held
+	listings.known, listings.changes, listings.held = nil, nil, 0
+	listings.mu.Unlock()
+	t.Cleanup(func() {
+		listings.mu.Lock()
+		listings.known, listings.changes, listings.held = known, changes, held
+		listings.mu.Unlock()
+	})
+}
+
+func listingTokens(ids ...string) []TokenInfo {
+	var tokens []TokenInfo
+	for _, id := range ids {
+		tokens = append(tokens, TokenInfo{DefuseAssetID: "nep141:" + id, Ticker: strings.ToUpper(id), ChainName: "base"})
+	}
+	return tokens
+}
+
+func TestRecordListingChanges(t *testing.T) {
+	withListings(t)
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if c := recordListingChanges(listingTokens("aaa", "bbb", "ccc", "ddd"), t0); c != nil {
+		t.Fatalf("first list is the baseline, got %+v", c)
+	}
+	changes := recordListingChanges(listingTokens("aaa", "bbb", "ccc", "eee", "fff"), t0.Add(time.Hour))
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Kind+" "+c.Token.Ticker)
+	}
+	if want := "listed EEE,listed FFF,delisted DDD"; strings.Join(got, ",") != want {
+		t.Errorf("changes = %v, want %s", got, want)
+	}
+	if c := recordListingChanges(listingTokens("aaa", "bbb", "ccc", "eee", "fff"), t0.Add(2*time.Hour)); c != nil {
+		t.Errorf("unchanged list logged %+v", c)
+	}
+
+	fresh := newListings(t0.Add(24 * time.Hour))
+	if !fresh["nep141:eee"] || !fresh["nep141:fff"] || fresh["nep141:ddd"] || fresh["nep141:aaa"] {
+		t.Errorf("newListings = %v", fresh)
+	}
+	if fresh := newListings(t0.Add(listingNewFor + 2*time.Hour)); fresh["nep141:eee"] {
+		t.Error("listings stop being new after listingNewFor")
+	}
+
+	// Relisting a delisted asset makes it new again; delisting a new one
+	// drops the badge.
+	recordListingChanges(listingTokens("aaa", "bbb", "ccc", "ddd", "eee"), t0.Add(3*time.Hour))
+	fresh = newListings(t0.Add(4 * time.Hour))
+	if !fresh["nep141:ddd"] || fresh["nep141:fff"] {
+		t.Errorf("after relist/delist newListings = %v", fresh)
+	}
+	if n := len(recentListingChanges(0)); n != 5 {
+		t.Errorf("log has %d changes, want 5", n)
+	}
+}
+
+func TestRecordListingChangesHoldsMassDelisting(t *testing.T) {
+	withListings(t)
+	at := time.Now()
+	full := listingTokens("a", "b", "c", "d", "e", "f", "g", "h")
+	recordListingChanges(full, at)
+
+	partial := full[:4]
+	for i := 1; i < listingHoldRefreshes; i++ {
+		if c := recordListingChanges(partial, at); c != nil {
+			t.Fatalf("refresh %d: mass delisting should be held back, got %d changes", i, len(c))
+		}
+	}
+	// A recovered list in between resets the hold and logs nothing.
+	if c := recordListingChanges(full, at); c != nil {
+		t.Fatalf("recovered list logged %+v", c)
+	}
+	for i := 1; i < listingHoldRefreshes; i++ {
+		recordListingChanges(partial, at)
+	}
+	if c := recordListingChanges(partial, at); len(c) != 4 {
+		t.Errorf("a persistent drop should be accepted after %d refreshes, got %d changes", listingHoldRefreshes, len(c))
+	}
+}
+
+func TestListingsOnCurrenciesAndFeed(t *testing.T) {
+	withListings(t)
+	withTokenSnapshot(t)
+	tokens := `{"assetId":"nep141:eth.omft.near","symbol":"eth","blockchain":"eth","decimals":18,"price":2500}`
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "[%s]", tokens)
+	})
+	if err := refreshTokenCache(); err != nil {
+		t.Fatal(err)
+	}
+	tokens += `,{"assetId":"nep141:new.omft.near","symbol":"nwt","blockchain":"base","decimals":6,"price":1}`
+	if err := refreshTokenCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	handleCurrencies(w, httptest.NewRequest("GET", "/currencies", nil))
+	body := w.Body.String()
+	if strings.Count(body, `class="token-card__new"`) != 1 {
+		t.Errorf("want exactly one New badge:\n%s", body)
+	}
+	if !strings.Contains(body, `application/atom+xml`) || !strings.Contains(body, "NWT <span") {
+		t.Error("currencies page should link the feed and list the recent change")
+	}
+
+	req := httptest.NewRequest("GET", "/currencies/feed.atom", nil)
+	req.Host = "zero.example"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	handleListingsFeed(w, req)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not XML: %v\n%s", err, w.Body.String())
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Title != "Listed: NWT on Base" {
+		t.Fatalf("entries = %+v", feed.Entries)
+	}
+	if e := feed.Entries[0]; e.Link.Href != "https://zero.example/?from=NWT&from_net=base" || !strings.HasPrefix(e.ID, "tag:") {
+		t.Errorf("entry = %+v", e)
+	}
+
+	// The log travels with the snapshot.
+	data, _ := os.ReadFile(tokenSnapshotPath)
+	var snap tokenSnapshot
+	json.Unmarshal(data, &snap)
+	if len(snap.Changes) != 1 || snap.Changes[0].Token.Ticker != "NWT" {
+		t.Fatalf("snapshot changes = %+v", snap.Changes)
+	}
+	seedListings(nil, nil)
+	if !loadTokenSnapshot() || len(recentListingChanges(0)) != 1 {
+		t.Error("loading the snapshot should restore the listing log")
+	}
+	if c := recordListingChanges(snap.Tokens, time.Now()); c != nil {
+		t.Errorf("snapshot should seed the known list, got %+v", c)
+	}
+}
+
+func TestListingAnnouncementText(t *testing.T) {
+	tok := TokenInfo{DefuseAssetID: "nep141:x<y>.near", Ticker: "X<Y>", ChainName: "arb"}
+	msg := listingAnnouncementText(listingChange{Kind: "listed", Token: tok})
+	if !strings.Contains(msg, "X&lt;Y&gt; on Arbitrum") || !strings.Contains(msg, "from_net=arb") {
+		t.Errorf("listed text = %q", msg)
+	}
+	if msg := listingAnnouncementText(listingChange{Kind: "delisted", Token: tok}); !strings.Contains(msg, "Delisted") || strings.Contains(msg, "href") {
+		t.Errorf("delisted text = %q", msg)
+	}
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -113,6 +117,9 @@ func initTemplates() {
 			return addr[:8] + "..." + addr[len(addr)-6:]
 		},
 		"sortIndicator": sortIndicator,
+		"txLink":        txExplorerURL,
+		"sparkline":     sparklineFunc,
+		"priceChange":   priceChangeFunc,
 	}
 
 	var err error
@@ -124,11 +131,13 @@ func initTemplates() {
 
 func main() {
 	initCrypto()
+	initChains()
 	initNearIntents()
 	initTemplates()
 	initCaseStudy()
 	startCacheRefresher()
 	limiter.startCleanup()
+	apiLimiter.startCleanup()
 
 	mux := http.NewServeMux()
 
@@ -145,6 +154,7 @@ func main() {
 	mux.HandleFunc("/swap", handleSwapConfirm)
 	mux.HandleFunc("/order/", handleOrder)
 	mux.HandleFunc("/currencies", handleCurrencies)
+	mux.HandleFunc("/currencies/feed.atom", handleListingsFeed)
 	mux.HandleFunc("/how-it-works", handleHowItWorks)
 	mux.HandleFunc("/case-study", handleCaseStudy)
 	mux.HandleFunc("/verify", handleVerify)
@@ -152,11 +162,35 @@ func main() {
 		http.Redirect(w, r, "https://github.com/uSwapExchange/zero", http.StatusFound)
 	})
 
+	// Liveness/readiness probes
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+	mux.HandleFunc("/metrics", handleMetrics)
+
+	// JSON API (stateless; no CSRF)
+	mux.HandleFunc("/api/v1/tokens", handleAPITokens)
+	mux.HandleFunc("/api/v1/prices", handleAPIPrices)
+	mux.HandleFunc("/api/v1/quote", handleAPIQuote)
+	mux.HandleFunc("/api/v1/swap", handleAPISwap)
+	mux.HandleFunc("/api/v1/order/", handleAPIOrder)
+
+	// SIGINT/SIGTERM stop the server and the Telegram poller gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Telegram bot (optional — disabled if TG_BOT_TOKEN is unset)
+	var tgPollerDone <-chan struct{}
 	if initTelegramBot() {
-		mux.HandleFunc("/tg/webhook/"+tgWebhookSecret, handleTelegramWebhook)
+		if tgUsePolling {
+			tgPollerDone = startTGPoller(ctx)
+		} else {
+			mux.HandleFunc("/tg/webhook/"+tgWebhookSecret, handleTelegramWebhook)
+		}
 		tgSessions.startCleanup()
 		subscribers.load()
+		priceAlerts.load()
+		addressBook.load()
+		startListingAnnouncer()
 		log.Printf("Telegram bot enabled (%d subscribers)", subscribers.count())
 	}
 
@@ -176,9 +210,24 @@ func main() {
 	log.Printf("uSwap Zero starting on :%s", port)
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		incrementRequests()
-		mux.ServeHTTP(w, r)
+		serveInstrumented(mux, w, r)
 	})
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
+	srv := &http.Server{Addr: ":" + port, Handler: handler}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Printf("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
+	<-shutdownDone
+	if tgPollerDone != nil {
+		<-tgPollerDone
+	}
+	waitTGUpdates()
 }
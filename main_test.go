@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -238,6 +244,92 @@ func TestCSRFToken(t *testing.T) {
 	}
 }
 
+// withKeyring replaces the order keyring for one test. keys[0] is current.
+func withKeyring(t *testing.T, keys ...[]byte) {
+	t.Helper()
+	saved, savedEphemeral := keyring.keys, keyring.ephemeral
+	keyring.keys, keyring.ephemeral = nil, false
+	for _, k := range keys {
+		keyring.keys = append(keyring.keys, newOrderKey(k))
+	}
+	t.Cleanup(func() { keyring.keys, keyring.ephemeral = saved, savedEphemeral })
+}
+
+func testKey(b byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = b
+	}
+	return k
+}
+
+func TestOrderKeyRotation(t *testing.T) {
+	keyA, keyB := testKey(0xAA), testKey(0xBB)
+	order := &OrderData{DepositAddr: "0xrotate", FromTicker: "ETH", ToTicker: "USDT"}
+
+	withKeyring(t, keyA)
+	oldToken, err := encryptOrderData(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate: B is current, A is previous.
+	withKeyring(t, keyB, keyA)
+	if _, err := decryptOrderData(oldToken); err != nil {
+		t.Errorf("token sealed with the previous key should still open: %v", err)
+	}
+	newToken, _ := encryptOrderData(order)
+	packed, _ := base64.RawURLEncoding.DecodeString(newToken)
+	if packed[0] != tokenVersion || packed[1] != newOrderKey(keyB).id {
+		t.Errorf("new token header = %x %x, want version %x and current key ID", packed[0], packed[1], tokenVersion)
+	}
+
+	// Retire A.
+	withKeyring(t, keyB)
+	if _, err := decryptOrderData(oldToken); err == nil {
+		t.Error("token sealed with a retired key must not open")
+	}
+	if _, err := decryptOrderData(newToken); err != nil {
+		t.Errorf("current-key token should open: %v", err)
+	}
+}
+
+func TestLegacyOrderTokenStillOpens(t *testing.T) {
+	key := testKey(0x42)
+	withKeyring(t, key)
+
+	// Pre-keyring layout: IV + ciphertext + tag, no header, no AAD.
+	plaintext, _ := json.Marshal(&OrderData{DepositAddr: "0xlegacy"})
+	gcm, _ := newOrderGCM(key)
+	iv := make([]byte, gcm.NonceSize())
+	iv[0] = tokenVersion // worst case: IV starts like a versioned header
+	legacy := base64.RawURLEncoding.EncodeToString(gcm.Seal(append([]byte{}, iv...), iv, plaintext, nil))
+
+	order, err := decryptOrderData(legacy)
+	if err != nil {
+		t.Fatalf("legacy token should open: %v", err)
+	}
+	if order.DepositAddr != "0xlegacy" {
+		t.Errorf("DepositAddr = %q", order.DepositAddr)
+	}
+}
+
+func TestCSRFTokenAcrossRotation(t *testing.T) {
+	keyA, keyB := testKey(0x01), testKey(0x02)
+	withKeyring(t, keyA)
+	token := generateCSRFToken("swap")
+
+	withKeyring(t, keyB, keyA)
+	if !verifyCSRFToken(token, "swap", time.Hour) {
+		t.Error("CSRF token signed with the previous key should verify")
+	}
+
+	withKeyring(t, keyB)
+	if verifyCSRFToken(token, "swap", time.Hour) {
+		t.Error("CSRF token signed with a retired key must not verify")
+	}
+}
+
 // ════════════════════════════════════════════════════════════
 // Integration Tests — NEAR Intents Production API
 // ════════════════════════════════════════════════════════════
@@ -247,7 +339,7 @@ func TestFetchTokensFromAPI(t *testing.T) {
 		t.Skip("skipping API test in short mode")
 	}
 
-	tokens, err := fetchTokens()
+	tokens, err := fetchTokens(context.Background())
 	if err != nil {
 		t.Fatalf("fetchTokens() failed: %v", err)
 	}
@@ -384,7 +476,7 @@ func TestDryQuoteETHtoUSDT(t *testing.T) {
 		AppFees:            []struct{}{},
 	}
 
-	resp, err := requestDryQuote(quoteReq)
+	resp, err := requestDryQuote(context.Background(), quoteReq)
 	if err != nil {
 		t.Skipf("dry quote API unavailable (may be temporary): %v", err)
 	}
@@ -445,7 +537,7 @@ func TestDryQuoteBTCtoETH(t *testing.T) {
 		AppFees:            []struct{}{},
 	}
 
-	resp, err := requestDryQuote(quoteReq)
+	resp, err := requestDryQuote(context.Background(), quoteReq)
 	if err != nil {
 		t.Skipf("dry quote API unavailable (may be temporary): %v", err)
 	}
@@ -739,6 +831,75 @@ func TestGenerateQRSVG(t *testing.T) {
 	}
 }
 
+func TestPaymentURI(t *testing.T) {
+	withTokenCache(t, []TokenInfo{
+		{Ticker: "USDC", ChainName: "eth", Decimals: 6, ContractAddress: "0xa0b86991c6218b36c1d19d4a2e9eb10ce3606eb48"},
+		{Ticker: "USDC", ChainName: "sol", Decimals: 6, ContractAddress: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"},
+	}, time.Now())
+
+	tests := []struct {
+		name  string
+		order OrderData
+		want  string
+	}{
+		{"bip21", OrderData{DepositAddr: "bc1qdep", FromTicker: "BTC", FromNet: "btc", AmountIn: "0.015"},
+			"bitcoin:bc1qdep?amount=0.015"},
+		{"bip21 any input", OrderData{DepositAddr: "Ldep", FromTicker: "LTC", FromNet: "ltc", AmountIn: "1", SwapType: "ANY_INPUT"},
+			"litecoin:Ldep"},
+		{"eip681 native", OrderData{DepositAddr: "0xdep", FromTicker: "ETH", FromNet: "base", AmountIn: "0.5"},
+			"ethereum:0xdep@8453?value=500000000000000000"},
+		{"eip681 erc20", OrderData{DepositAddr: "0xdep", FromTicker: "USDC", FromNet: "eth", AmountIn: "25.5"},
+			"ethereum:0xa0b86991c6218b36c1d19d4a2e9eb10ce3606eb48@1/transfer?address=0xdep&uint256=25500000"},
+		{"erc20 not cached", OrderData{DepositAddr: "0xdep", FromTicker: "PEPE", FromNet: "eth", AmountIn: "1"},
+			""},
+		{"solana pay spl", OrderData{DepositAddr: "SoDep", FromTicker: "USDC", FromNet: "sol", AmountIn: "10"},
+			"solana:SoDep?amount=10&spl-token=EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"},
+		{"solana pay memo", OrderData{DepositAddr: "SoDep", FromTicker: "SOL", FromNet: "sol", AmountIn: "2", Memo: "a b"},
+			"solana:SoDep?amount=2&memo=a%20b"},
+		{"ton comment", OrderData{DepositAddr: "EQdep", FromTicker: "TON", FromNet: "ton", AmountIn: "1.25", Memo: "98765"},
+			"ton://transfer/EQdep?amount=1250000000&text=98765"},
+		{"ton jetton", OrderData{DepositAddr: "EQdep", FromTicker: "USDT", FromNet: "ton", AmountIn: "1"},
+			""},
+		{"no scheme", OrderData{DepositAddr: "rDep", FromTicker: "XRP", FromNet: "xrp", AmountIn: "1", Memo: "1"},
+			""},
+	}
+	for _, tt := range tests {
+		if got := paymentURI(&tt.order); got != tt.want {
+			t.Errorf("%s: paymentURI = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrderPageQRToggle(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v0/status") {
+			fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+			return
+		}
+		fmt.Fprint(w, `{"withdrawals":[]}`)
+	})
+
+	order := &OrderData{DepositAddr: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", FromTicker: "BTC", FromNet: "btc", ToTicker: "ETH", AmountIn: "0.01"}
+	token, _ := encryptOrderData(order)
+
+	render := func(query string) string {
+		w := httptest.NewRecorder()
+		handleOrder(w, httptest.NewRequest("GET", "/order/"+token+query, nil))
+		return w.Body.String()
+	}
+	payQR := generateQRSVG(paymentURI(order), 200)
+	plainQR := generateQRSVG(order.DepositAddr, 200)
+
+	body := render("")
+	if !strings.Contains(body, payQR) || !strings.Contains(body, "?qr=address") {
+		t.Error("order page should show the payment-URI QR with a plain-address toggle")
+	}
+	body = render("?qr=address")
+	if !strings.Contains(body, plainQR) || strings.Contains(body, payQR) {
+		t.Error("?qr=address should show the plain address QR")
+	}
+}
+
 // ════════════════════════════════════════════════════════════
 // Rate Limiter Tests
 // ════════════════════════════════════════════════════════════
@@ -967,6 +1128,481 @@ func TestQuoteRequestJSON(t *testing.T) {
 	}
 }
 
+// ════════════════════════════════════════════════════════════
+// JSON API Tests
+// ════════════════════════════════════════════════════════════
+
+func decodeAPIError(t *testing.T, w *httptest.ResponseRecorder) apiErrorDetail {
+	t.Helper()
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type: got %q, want application/json", ct)
+	}
+	var body apiErrorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("error body is not JSON: %v\n%s", err, w.Body.String())
+	}
+	return body.Error
+}
+
+func TestAPIQuoteMethodNotAllowed(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/quote", nil)
+	w := httptest.NewRecorder()
+	handleAPIQuote(w, req)
+
+	if w.Code != 405 {
+		t.Errorf("GET /api/v1/quote: got %d, want 405", w.Code)
+	}
+	if e := decodeAPIError(t, w); e.Code != "method_not_allowed" {
+		t.Errorf("error code: got %q, want method_not_allowed", e.Code)
+	}
+}
+
+func TestAPIQuoteInvalidJSON(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/quote", strings.NewReader("from=ETH"))
+	w := httptest.NewRecorder()
+	handleAPIQuote(w, req)
+
+	if w.Code != 400 {
+		t.Errorf("POST /api/v1/quote with form body: got %d, want 400", w.Code)
+	}
+	if e := decodeAPIError(t, w); e.Code != "invalid_json" {
+		t.Errorf("error code: got %q, want invalid_json", e.Code)
+	}
+}
+
+func TestAPISwapValidation(t *testing.T) {
+	body := `{"from":"ETH","fromNet":"eth","to":"USDT","toNet":"eth","amount":"1"}`
+	req := httptest.NewRequest("POST", "/api/v1/swap", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleAPISwap(w, req)
+
+	if w.Code != 400 {
+		t.Errorf("POST /api/v1/swap without addresses: got %d, want 400", w.Code)
+	}
+	e := decodeAPIError(t, w)
+	if e.Code != "validation_error" {
+		t.Errorf("error code: got %q, want validation_error", e.Code)
+	}
+	if len(e.Details) != 2 {
+		t.Errorf("expected 2 validation details, got %v", e.Details)
+	}
+}
+
+func TestAPIQuoteRequiresAmount(t *testing.T) {
+	body := `{"from":"ETH","fromNet":"eth","to":"USDT","toNet":"eth","recipient":"0x000000000000000000000000000000000000dEaD","refundAddr":"0x000000000000000000000000000000000000dEaD"}`
+	req := httptest.NewRequest("POST", "/api/v1/quote", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handleAPIQuote(w, req)
+
+	if w.Code != 400 {
+		t.Errorf("POST /api/v1/quote without amount: got %d, want 400", w.Code)
+	}
+	if e := decodeAPIError(t, w); e.Code != "amount_required" {
+		t.Errorf("error code: got %q, want amount_required", e.Code)
+	}
+}
+
+func TestAPIOrderInvalidToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/order/not-a-token", nil)
+	w := httptest.NewRecorder()
+	handleAPIOrder(w, req)
+
+	if w.Code != 400 {
+		t.Errorf("GET /api/v1/order/not-a-token: got %d, want 400", w.Code)
+	}
+	if e := decodeAPIError(t, w); e.Code != "invalid_token" {
+		t.Errorf("error code: got %q, want invalid_token", e.Code)
+	}
+}
+
+func TestAPIRateLimitIsSeparate(t *testing.T) {
+	saved := apiLimiter
+	apiLimiter = &rateLimiter{counters: make(map[string]*rateBucket)}
+	defer func() { apiLimiter = saved }()
+
+	// Exhaust the web limiter for this IP; the API must still accept it.
+	for i := 0; i < 40; i++ {
+		limiter.allow("203.0.113.9", 30, time.Minute)
+	}
+	req := httptest.NewRequest("GET", "/api/v1/order/x", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	w := httptest.NewRecorder()
+	handleAPIOrder(w, req)
+	if w.Code == 429 {
+		t.Error("API should not share the web rate limit")
+	}
+}
+
+func TestSwapInputSwapType(t *testing.T) {
+	tests := []struct {
+		amount, amountOut, want string
+	}{
+		{"1", "", "FLEX_INPUT"},
+		{"", "5", "EXACT_OUTPUT"},
+		{"1", "5", "FLEX_INPUT"},
+		{"", "", "ANY_INPUT"},
+	}
+	for _, tt := range tests {
+		in := &swapInput{Amount: tt.amount, AmountOut: tt.amountOut}
+		if got := in.swapType(); got != tt.want {
+			t.Errorf("swapType(%q, %q) = %q, want %q", tt.amount, tt.amountOut, got, tt.want)
+		}
+	}
+}
+
+func TestOrderStatusStep(t *testing.T) {
+	tests := []struct {
+		status   string
+		step     int
+		terminal bool
+	}{
+		{"PENDING_DEPOSIT", 0, false},
+		{"KNOWN_DEPOSIT_TX", 0, false},
+		{"PROCESSING", 1, false},
+		{"SUCCESS", 2, true},
+		{"REFUNDED", 2, true},
+		{"UNKNOWN", 0, false},
+	}
+	for _, tt := range tests {
+		step, terminal := orderStatusStep(tt.status)
+		if step != tt.step || terminal != tt.terminal {
+			t.Errorf("orderStatusStep(%q) = (%d, %v), want (%d, %v)", tt.status, step, terminal, tt.step, tt.terminal)
+		}
+	}
+}
+
+// ════════════════════════════════════════════════════════════
+// Order Events (SSE) Tests
+// ════════════════════════════════════════════════════════════
+
+// withFakeUpstream points the NEAR Intents client at a test server for the
+// duration of a test.
+func withFakeUpstream(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	saved, savedBreaker, savedBackoff := nearIntentsBaseURL, upstreamBreaker, upstreamBackoffBase
+	nearIntentsBaseURL = srv.URL
+	upstreamBreaker = newCircuitBreaker(upstreamBreakerThreshold, upstreamBreakerCooldown)
+	upstreamBackoffBase = time.Millisecond
+	statusCache.reset()
+	withdrawalsCache.reset()
+	t.Cleanup(func() {
+		nearIntentsBaseURL, upstreamBreaker, upstreamBackoffBase = saved, savedBreaker, savedBackoff
+		srv.Close()
+	})
+}
+
+// withoutStatusCache makes every status lookup reach the fake upstream,
+// for tests that script one response per poll.
+func withoutStatusCache(t *testing.T) {
+	t.Helper()
+	saved, savedTerminal := statusCacheTTL, statusCacheTerminalTTL
+	statusCacheTTL, statusCacheTerminalTTL = 0, 0
+	t.Cleanup(func() { statusCacheTTL, statusCacheTerminalTTL = saved, savedTerminal })
+}
+
+func TestOrderEventsStream(t *testing.T) {
+	statuses := []string{"PENDING_DEPOSIT", "PENDING_DEPOSIT", "PROCESSING", "SUCCESS"}
+	calls := 0
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		s := statuses[min(calls, len(statuses)-1)]
+		calls++
+		fmt.Fprintf(w, `{"status":%q}`, s)
+	})
+	withoutStatusCache(t)
+
+	savedInterval := orderEventsInterval
+	orderEventsInterval = 5 * time.Millisecond
+	defer func() { orderEventsInterval = savedInterval }()
+
+	token, err := encryptOrderData(&OrderData{DepositAddr: "0xevents", FromTicker: "ETH", ToTicker: "USDT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/order/"+token+"/events", nil)
+	w := httptest.NewRecorder()
+	handleOrder(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type: got %q, want text/event-stream", ct)
+	}
+	body := w.Body.String()
+	if n := strings.Count(body, "event: status"); n != 3 {
+		t.Errorf("expected 3 status events (one per change), got %d\n%s", n, body)
+	}
+	if !strings.Contains(body, `"status":"PROCESSING"`) {
+		t.Error("stream missing PROCESSING transition")
+	}
+	// Past the deposit step each event carries the re-rendered status block
+	// for the page to swap in; the deposit step's block isn't resent.
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "{}" {
+			continue
+		}
+		var ev orderEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{"PENDING_DEPOSIT": "", "PROCESSING": "Processing your swap", "SUCCESS": "Swap Complete"}[ev.Status]
+		if (want == "") != (ev.HTML == "") || !strings.Contains(ev.HTML, want) || strings.Contains(ev.HTML, "<svg") {
+			t.Errorf("%s event html = %q", ev.Status, ev.HTML)
+		}
+	}
+	if !strings.HasSuffix(body, "event: end\ndata: {}\n\n") {
+		t.Errorf("stream should close with an end event after SUCCESS\n%s", body)
+	}
+}
+
+func TestOrderPageNoScriptFallback(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v0/status") {
+			fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+			return
+		}
+		fmt.Fprint(w, `{"withdrawals":[]}`)
+	})
+
+	token, _ := encryptOrderData(&OrderData{DepositAddr: "0xevents", FromTicker: "ETH", ToTicker: "USDT"})
+	req := httptest.NewRequest("GET", "/order/"+token, nil)
+	w := httptest.NewRecorder()
+	handleOrder(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, `<noscript><meta http-equiv="refresh" content="10"></noscript>`) {
+		t.Error("pending order page should keep meta-refresh as a <noscript> fallback")
+	}
+	if !strings.Contains(body, "/events") || !strings.Contains(body, `id="order-progress"`) {
+		t.Error("pending order page should subscribe to the events stream")
+	}
+	if strings.Count(body, "location.reload") != 1 {
+		t.Error("live updates should apply in place; reload is only for browsers without EventSource")
+	}
+}
+
+func TestOrderTranscript(t *testing.T) {
+	quoteBody := `{"correlationId":"3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f", "timestamp":"2026-03-01T12:00:00.000Z","signature":"ed25519:abc",` +
+		`"quote":{"depositAddress":"0x000000000000000000000000000000000000dEaD","amountIn":"1000000","amountInFormatted":"1.0","amountOutFormatted":"0.999"}}`
+	var sentBody []byte
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v0/quote" {
+			sentBody, _ = io.ReadAll(r.Body)
+			fmt.Fprint(w, quoteBody)
+			return
+		}
+		fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+	})
+
+	in := &swapInput{FromTicker: "ETH", FromNet: "eth", ToTicker: "USDC", ToNet: "eth",
+		Recipient: "0x000000000000000000000000000000000000dEaD", RefundAddr: "0x000000000000000000000000000000000000dEaD"}
+	from := &TokenInfo{DefuseAssetID: "nep141:eth.omft.near", Ticker: "ETH", Decimals: 18}
+	to := &TokenInfo{DefuseAssetID: "nep141:usdc.omft.near", Ticker: "USDC", Decimals: 6}
+	_, token, serr := placeOrder(context.Background(), in, from, to, "EXACT_INPUT", "1000000", 100, "", "")
+	if serr != nil {
+		t.Fatalf("placeOrder: %+v", serr)
+	}
+
+	w := httptest.NewRecorder()
+	handleOrder(w, httptest.NewRequest("GET", "/order/"+token+"/transcript", nil))
+	if w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "zero-quote-3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f.json") {
+		t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"request":`+string(sentBody)+`,`) {
+		t.Errorf("transcript should embed the exact request body %s", sentBody)
+	}
+	if !strings.Contains(body, `"response":`+quoteBody+`}`) {
+		t.Error("transcript should embed the exact upstream response")
+	}
+	if !strings.Contains(body, `"appFees":[]`) {
+		t.Error("transcript request should show appFees: []")
+	}
+	var doc struct {
+		RequestedAt, ReceivedAt string
+		Response                struct{ Signature string }
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("transcript is not JSON: %v", err)
+	}
+	if doc.RequestedAt == "" || doc.ReceivedAt == "" || doc.Response.Signature != "ed25519:abc" {
+		t.Errorf("transcript = %+v", doc)
+	}
+
+	// The token carries only the transcript's hash.
+	if len(token) > 300 {
+		t.Errorf("order token is %d characters; the transcript should not be inside it", len(token))
+	}
+
+	// A saved copy is checked against that hash, as a raw body or a form.
+	saved := w.Body.String()
+	checkTranscript := func(req *http.Request) bool {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handleOrder(w, req)
+		var res struct{ Match bool }
+		if w.Code != 200 || json.Unmarshal(w.Body.Bytes(), &res) != nil {
+			t.Fatalf("check: status %d, body %s", w.Code, w.Body.String())
+		}
+		return res.Match
+	}
+	if !checkTranscript(httptest.NewRequest("POST", "/order/"+token+"/transcript", strings.NewReader(saved))) {
+		t.Error("the downloaded transcript should match its order")
+	}
+	tampered := strings.Replace(saved, "dEaD", "bEEF", 1)
+	if checkTranscript(httptest.NewRequest("POST", "/order/"+token+"/transcript", strings.NewReader(tampered))) {
+		t.Error("an edited transcript must not match")
+	}
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("transcript", "zero-quote.json")
+	io.WriteString(fw, saved)
+	mw.Close()
+	req := httptest.NewRequest("POST", "/order/"+token+"/transcript", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if !checkTranscript(req) {
+		t.Error("a transcript uploaded as a form file should match")
+	}
+
+	// Once the server no longer holds it, downloads are gone but checks
+	// still work.
+	heldTranscripts.mu.Lock()
+	clear(heldTranscripts.entries)
+	heldTranscripts.mu.Unlock()
+	w = httptest.NewRecorder()
+	handleOrder(w, httptest.NewRequest("GET", "/order/"+token+"/transcript", nil))
+	if w.Code != 410 || decodeAPIError(t, w).Code != "transcript_expired" {
+		t.Errorf("expired transcript: status %d, body %s", w.Code, w.Body.String())
+	}
+	if !checkTranscript(httptest.NewRequest("POST", "/order/"+token+"/transcript", strings.NewReader(saved))) {
+		t.Error("a saved transcript should still match after the server drops it")
+	}
+
+	// Tokens from before the hash carried the whole transcript.
+	legacy, _ := encryptOrderData(&OrderData{DepositAddr: "0xlegacy", CorrID: "3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f",
+		Transcript: legacyTranscript(time.UnixMilli(1772366400000), []byte(`{"a":1}`), []byte(quoteBody))})
+	w = httptest.NewRecorder()
+	handleOrder(w, httptest.NewRequest("GET", "/order/"+legacy+"/transcript", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"request":{"a":1},"response":`+quoteBody) {
+		t.Errorf("legacy transcript: status %d, body %s", w.Code, w.Body.String())
+	}
+
+	// Orders without a transcript (older tokens) get a JSON 404.
+	old, _ := encryptOrderData(&OrderData{DepositAddr: "0xold"})
+	w = httptest.NewRecorder()
+	handleOrder(w, httptest.NewRequest("GET", "/order/"+old+"/transcript", nil))
+	if w.Code != 404 || decodeAPIError(t, w).Code != "no_transcript" {
+		t.Errorf("old order: status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+// legacyTranscript builds the DEFLATEd transcript that tokens carried
+// before TranscriptHash: requestedAt, receivedAt (Unix millis as uvarints),
+// uvarint request length, request, response.
+func legacyTranscript(at time.Time, request, response []byte) string {
+	var doc []byte
+	doc = binary.AppendUvarint(doc, uint64(at.UnixMilli()))
+	doc = binary.AppendUvarint(doc, uint64(at.UnixMilli()))
+	doc = binary.AppendUvarint(doc, uint64(len(request)))
+	doc = append(doc, request...)
+	doc = append(doc, response...)
+
+	var buf bytes.Buffer
+	zw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	zw.Write(doc)
+	zw.Close()
+	return buf.String()
+}
+
+// ════════════════════════════════════════════════════════════
+// Health / Readiness Tests
+// ════════════════════════════════════════════════════════════
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	handleHealthz(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != 200 {
+		t.Errorf("GET /healthz: got %d, want 200", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"status":"ok"`) {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// withTokenCache swaps in a fixed token cache state for one test.
+func withTokenCache(t *testing.T, tokens []TokenInfo, updatedAt time.Time) {
+	t.Helper()
+	cache.mu.Lock()
+	savedTokens, savedAt, savedErr := cache.tokens, cache.updatedAt, cache.lastErr
+	cache.tokens, cache.updatedAt, cache.lastErr = tokens, updatedAt, nil
+	cache.mu.Unlock()
+	t.Cleanup(func() {
+		cache.mu.Lock()
+		cache.tokens, cache.updatedAt, cache.lastErr = savedTokens, savedAt, savedErr
+		cache.mu.Unlock()
+	})
+}
+
+func getReadyz(t *testing.T) (int, readyResponse) {
+	t.Helper()
+	nearProbe = &upstreamProbe{} // drop any cached probe
+	w := httptest.NewRecorder()
+	handleReadyz(w, httptest.NewRequest("GET", "/readyz", nil))
+	var resp readyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("readyz body is not JSON: %v\n%s", err, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+func TestReadyzReady(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	withTokenCache(t, []TokenInfo{{Ticker: "ETH"}}, time.Now().Add(-time.Minute))
+
+	code, resp := getReadyz(t)
+	if code != 200 || resp.Status != "ready" {
+		t.Fatalf("got %d %q, want 200 ready: %+v", code, resp.Status, resp)
+	}
+	if resp.TokenCache.Tokens != 1 || resp.TokenCache.AgeSeconds < 60 {
+		t.Errorf("token cache report: %+v", resp.TokenCache)
+	}
+}
+
+func TestReadyzUpstreamDown(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	})
+	withTokenCache(t, []TokenInfo{{Ticker: "ETH"}}, time.Now())
+
+	code, resp := getReadyz(t)
+	if code != 503 || resp.Status != "unavailable" {
+		t.Fatalf("got %d %q, want 503 unavailable", code, resp.Status)
+	}
+	if resp.Upstream.OK || resp.Upstream.Detail == "" {
+		t.Errorf("upstream should be reported down with a reason: %+v", resp.Upstream)
+	}
+	if !resp.TokenCache.OK {
+		t.Errorf("fresh token cache should be ok: %+v", resp.TokenCache)
+	}
+}
+
+func TestReadyzStaleCache(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	withTokenCache(t, []TokenInfo{{Ticker: "ETH"}}, time.Now().Add(-readyMaxCacheAge-time.Minute))
+
+	code, resp := getReadyz(t)
+	if code != 503 || resp.TokenCache.OK {
+		t.Errorf("stale cache: got %d, tokenCache %+v; want 503 and not ok", code, resp.TokenCache)
+	}
+}
+
 // Helper
 func min(a, b int) int {
 	if a < b {
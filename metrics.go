@@ -0,0 +1,339 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prometheus text exposition (format 0.0.4), stdlib only.
+//
+// Privacy rule: labels are limited to fixed, low-cardinality values — route
+// patterns, upstream endpoints, status classes, Telegram method names. Never
+// add a label that carries an IP, order token, address, chat ID or anything
+// else that identifies a user.
+
+// metricsToken, when set via METRICS_TOKEN, is required as a bearer token.
+var metricsToken = os.Getenv("METRICS_TOKEN")
+
+var (
+	httpRequests = newCounterVec("zero_http_requests_total",
+		"HTTP requests by route pattern, method and status class.", "route", "method", "code")
+	httpDuration = newHistogramVec("zero_http_request_duration_seconds",
+		"HTTP request latency by route pattern.", defaultLatencyBuckets, "route")
+
+	upstreamRequests = newCounterVec("zero_upstream_requests_total",
+		"1Click API attempts by endpoint and result (2xx, 4xx, 5xx, error). Retries count as separate attempts.", "endpoint", "result")
+	upstreamDuration = newHistogramVec("zero_upstream_request_duration_seconds",
+		"1Click API attempt latency by endpoint.", defaultLatencyBuckets, "endpoint")
+	upstreamRetries = newCounterVec("zero_upstream_retries_total",
+		"1Click API retries by endpoint.", "endpoint")
+
+	tokenCacheRefreshes = newCounterVec("zero_token_cache_refreshes_total",
+		"Token cache refreshes by result (success, failure).", "result")
+	tokenListingChanges = newCounterVec("zero_token_listing_changes_total",
+		"Assets listed or delisted between token refreshes, by kind.", "kind")
+
+	explorerWait = newHistogramVec("zero_explorer_limiter_wait_seconds",
+		"Time spent waiting on the Explorer API rate limiter.", []float64{0.01, 0.1, 0.5, 1, 2, 5, 10, 30, 60})
+
+	tgAPIErrors = newCounterVec("zero_telegram_api_errors_total",
+		"Failed Telegram Bot API calls by method.", "method")
+	tgPriceAlertsFired = newCounterVec("zero_telegram_price_alerts_fired_total",
+		"Telegram price alerts fired, by check (price, quote).", "check")
+
+	statusCacheLookups = newCounterVec("zero_status_cache_lookups_total",
+		"Status cache lookups by endpoint and result (hit, miss, coalesced).", "endpoint", "result")
+
+	quoteSignatureChecks = newCounterVec("zero_quote_signature_checks_total",
+		"1Click quote signature checks by result (verified, failed, unchecked).", "result")
+
+	tgWatchersDropped = newCounterVec("zero_telegram_watchers_dropped_total",
+		"Telegram order watchers not started because tgWatchMaxWatchers were already running.")
+	webhookWatchersDropped = newCounterVec("zero_webhook_watchers_dropped_total",
+		"Webhook watchers not started because webhookMaxWatchers were already running.")
+)
+
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// gauges are computed at scrape time.
+var metricGauges = []struct {
+	name, help string
+	value      func() float64
+}{
+	{"zero_uptime_seconds", "Seconds since the process started.", func() float64 {
+		return time.Since(serverStartTime).Seconds()
+	}},
+	{"zero_token_cache_tokens", "Tokens currently in the cache.", func() float64 {
+		cache.mu.RLock()
+		defer cache.mu.RUnlock()
+		return float64(len(cache.tokens))
+	}},
+	{"zero_token_cache_age_seconds", "Seconds since the last successful token refresh (-1 if never).", func() float64 {
+		cache.mu.RLock()
+		defer cache.mu.RUnlock()
+		if cache.updatedAt.IsZero() {
+			return -1
+		}
+		return time.Since(cache.updatedAt).Seconds()
+	}},
+	{"zero_upstream_circuit_state", "1Click circuit breaker state (0 closed, 1 half-open, 2 open).", func() float64 {
+		return float64(upstreamBreaker.state())
+	}},
+	{"zero_webhook_watchers", "Active order webhook watchers.", func() float64 {
+		return float64(webhooks.count())
+	}},
+	{"zero_telegram_order_watchers", "Active Telegram order watchers.", func() float64 {
+		return float64(tgWatchers.count())
+	}},
+	{"zero_telegram_price_alerts", "Telegram price alerts waiting to fire.", func() float64 {
+		return float64(priceAlerts.count())
+	}},
+	{"zero_telegram_address_books", "Telegram chats with a saved address book.", func() float64 {
+		return float64(addressBook.count())
+	}},
+}
+
+// metricFamilies lists every vector in exposition order.
+var metricFamilies = []interface{ writeTo(io.Writer) }{
+	httpRequests, httpDuration,
+	upstreamRequests, upstreamDuration, upstreamRetries,
+	tokenCacheRefreshes, tokenListingChanges, statusCacheLookups, explorerWait, tgAPIErrors, tgPriceAlertsFired, quoteSignatureChecks,
+	webhookWatchersDropped, tgWatchersDropped,
+}
+
+// --- Primitives ---
+
+type counterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64 // key: label values joined by "\xff"
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	c.values[key]++
+	c.mu.Unlock()
+}
+
+func (c *counterVec) get(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[strings.Join(labelValues, "\xff")]
+}
+
+func (c *counterVec) writeTo(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key, "", ""), formatFloat(c.values[key]))
+	}
+}
+
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, non-cumulative
+	sum    float64
+	count  uint64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogramSeries)}
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, ub := range h.buckets {
+		if v <= ub {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *histogramVec) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cum uint64
+		for i, ub := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", formatFloat(ub)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, "", ""), s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels renders {a="x",b="y"} from a joined key, optionally
+// appending one extra label (used for "le").
+func formatLabels(names []string, key, extraName, extraValue string) string {
+	var pairs []string
+	if len(names) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			if i < len(names) {
+				pairs = append(pairs, names[i]+"="+strconv.Quote(v))
+			}
+		}
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+"="+strconv.Quote(extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// statusClass maps an HTTP status code to "2xx", "4xx", etc.
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "other"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// --- HTTP instrumentation ---
+
+// statusRecorder captures the response status while passing through
+// Flush so SSE streams keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// metricsRoute returns the registered pattern that will serve r, never
+// the raw path (which may hold an order token).
+func metricsRoute(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	switch {
+	case pattern == "":
+		return "unmatched"
+	case strings.HasPrefix(pattern, "/tg/webhook/"):
+		return "/tg/webhook/" // the pattern embeds the webhook secret
+	}
+	return pattern
+}
+
+func metricsMethod(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost:
+		return m
+	}
+	return "other"
+}
+
+// serveInstrumented serves r through mux and records request metrics.
+func serveInstrumented(mux *http.ServeMux, w http.ResponseWriter, r *http.Request) {
+	route := metricsRoute(mux, r)
+	rec := &statusRecorder{ResponseWriter: w}
+	start := time.Now()
+	mux.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	httpDuration.observe(time.Since(start).Seconds(), route)
+	httpRequests.inc(route, metricsMethod(r.Method), statusClass(rec.status))
+}
+
+// upstreamEndpoint strips the query string (which carries deposit
+// addresses) from a 1Click API path.
+func upstreamEndpoint(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// handleMetrics serves the Prometheus text exposition.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if metricsToken != "" {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(metricsToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	for _, f := range metricFamilies {
+		f.writeTo(w)
+	}
+	for _, g := range metricGauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.value()))
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrapeMetrics(t *testing.T, auth string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	handleMetrics(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := newHistogramVec("test_latency_seconds", "Test.", []float64{0.1, 1}, "route")
+	h.observe(0.05, "/a")
+	h.observe(0.5, "/a")
+	h.observe(5, "/a")
+
+	var sb strings.Builder
+	h.writeTo(&sb)
+	out := sb.String()
+	for _, want := range []string{
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{route="/a",le="0.1"} 1`,
+		`test_latency_seconds_bucket{route="/a",le="1"} 2`,
+		`test_latency_seconds_bucket{route="/a",le="+Inf"} 3`,
+		`test_latency_seconds_sum{route="/a"} 5.55`,
+		`test_latency_seconds_count{route="/a"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricsRouteUsesPattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/order/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mux.HandleFunc("/tg/webhook/supersecretpath", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/order/SECRETORDERTOKEN", "/order/SECRETORDERTOKEN/events", "/tg/webhook/supersecretpath"} {
+		serveInstrumented(mux, httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	_, body := scrapeMetrics(t, "")
+	for _, leak := range []string{"SECRETORDERTOKEN", "supersecretpath"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("metrics leak %q", leak)
+		}
+	}
+	if got := httpRequests.get("/order/", "GET", "4xx"); got < 2 {
+		t.Errorf(`route="/order/" 4xx count = %v, want >= 2`, got)
+	}
+	if !strings.Contains(body, `route="/tg/webhook/"`) {
+		t.Error("webhook route should be reported without its secret")
+	}
+}
+
+func TestUpstreamMetricsStripQuery(t *testing.T) {
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"PROCESSING"}`)
+	})
+	before := upstreamRequests.get("/v0/status", "2xx")
+	if _, err := fetchStatus(context.Background(), "0xmetricsdeposit", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := upstreamRequests.get("/v0/status", "2xx"); got != before+1 {
+		t.Errorf("upstream 2xx count = %v, want %v", got, before+1)
+	}
+	if _, body := scrapeMetrics(t, ""); strings.Contains(body, "0xmetricsdeposit") {
+		t.Error("upstream metrics must not include query parameters")
+	}
+}
+
+func TestMetricsBearerToken(t *testing.T) {
+	saved := metricsToken
+	metricsToken = "s3cret"
+	defer func() { metricsToken = saved }()
+
+	for auth, want := range map[string]int{
+		"":              401,
+		"s3cret":        401,
+		"Bearer wrong":  401,
+		"Bearer s3cret": 200,
+	} {
+		if code, _ := scrapeMetrics(t, auth); code != want {
+			t.Errorf("Authorization %q: got %d, want %d", auth, code, want)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -14,7 +17,8 @@ import (
 var (
 	nearIntentsBaseURL = "https://1click.chaindefuser.com"
 	nearIntentsJWT     string
-	nearHTTPClient     = &http.Client{Timeout: 30 * time.Second}
+	nearHTTPClient     = &http.Client{} // timeouts come from the request context
+
 )
 
 func initNearIntents() {
@@ -23,6 +27,11 @@ func initNearIntents() {
 	}
 	nearIntentsJWT = os.Getenv("NEAR_INTENTS_JWT")
 	explorerJWT = os.Getenv("NEAR_INTENTS_EXPLORER_JWT")
+	if os.Getenv("NEAR_INTENTS_SIMULATOR") != "" {
+		intents = newIntentsSimulator()
+		log.Println("WARNING: NEAR_INTENTS_SIMULATOR set — using the offline 1Click simulator. Quotes, deposit addresses and statuses are fake.")
+	}
+	initQuoteSigner()
 }
 
 // QuoteRequest is the payload for POST /v0/quote
@@ -50,6 +59,13 @@ type QuoteResponse struct {
 	Timestamp     string      `json:"timestamp"`
 	Signature     string      `json:"signature"`
 	Quote         QuoteDetail `json:"quote"`
+	// Raw body, needed to check Signature (see quotesig.go)
+	RawJSON json.RawMessage `json:"-"`
+
+	// What we sent and when, for the order transcript (see transcript.go)
+	RequestJSON json.RawMessage `json:"-"`
+	RequestedAt time.Time       `json:"-"`
+	ReceivedAt  time.Time       `json:"-"`
 }
 
 // QuoteDetail contains the swap parameters inside a QuoteResponse.
@@ -130,9 +146,74 @@ type TokenInfo struct {
 	ContractAddress string  `json:"contractAddress,omitempty"`
 }
 
+// IntentsClient is the slice of the 1Click API the app uses. The live
+// backend is httpIntents; NEAR_INTENTS_SIMULATOR switches to the offline
+// simulator in simulator.go. Callers go through the package-level helpers
+// below rather than the interface directly.
+type IntentsClient interface {
+	Tokens(ctx context.Context) ([]TokenInfo, error)
+	DryQuote(ctx context.Context, req *QuoteRequest) (*DryQuoteResponse, error)
+	Quote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error)
+	Status(ctx context.Context, depositAddress, depositMemo string) (*StatusResponse, error)
+	AnyInputWithdrawals(ctx context.Context, depositAddress string) (*AnyInputWithdrawalsResponse, error)
+}
+
+// intents is the active backend.
+var intents IntentsClient = httpIntents{}
+
+// httpIntents talks to the 1Click API at nearIntentsBaseURL.
+type httpIntents struct{}
+
+// fetchTokens retrieves the supported token list from NEAR Intents.
+func fetchTokens(ctx context.Context) ([]TokenInfo, error) {
+	return intents.Tokens(ctx)
+}
+
+// requestDryQuote sends a dry quote request and parses the nested response.
+func requestDryQuote(ctx context.Context, req *QuoteRequest) (*DryQuoteResponse, error) {
+	req.Dry = true
+	return intents.DryQuote(ctx, req)
+}
+
+// requestQuote sends a real (non-dry) quote request to NEAR Intents and
+// records what was sent for the order transcript.
+func requestQuote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error) {
+	req.Dry = false
+	// nearRequest marshals req the same way, so these are the bytes sent.
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	requestedAt := time.Now()
+	resp, err := intents.Quote(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.RequestJSON = reqJSON
+	resp.RequestedAt, resp.ReceivedAt = requestedAt, time.Now()
+	return resp, nil
+}
+
+// fetchStatus checks the status of a swap by deposit address.
+// depositMemo is optional but required for chains that use memos (TON, XRP, NEAR, Stellar).
+// Results are shared through statusCache.
+func fetchStatus(ctx context.Context, depositAddress, depositMemo string) (*StatusResponse, error) {
+	return statusCache.get(ctx, depositAddress+"\x00"+depositMemo, func(ctx context.Context) (*StatusResponse, error) {
+		return intents.Status(ctx, depositAddress, depositMemo)
+	})
+}
+
+// fetchAnyInputWithdrawals retrieves completed swap history for an ANY_INPUT deposit address.
+func fetchAnyInputWithdrawals(ctx context.Context, depositAddress string) (*AnyInputWithdrawalsResponse, error) {
+	return withdrawalsCache.get(ctx, depositAddress, func(ctx context.Context) (*AnyInputWithdrawalsResponse, error) {
+		return intents.AnyInputWithdrawals(ctx, depositAddress)
+	})
+}
+
 // nearRequest makes an authenticated request to the NEAR Intents API.
-// On 5xx responses it retries once after a short delay.
-func nearRequest(method, path string, body interface{}) ([]byte, error) {
+// Errors, 5xx and 429 responses are retried with backoff; see upstream.go
+// for the timeouts and the circuit breaker.
+func nearRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
 	var bodyBytes []byte
 	if body != nil {
 		b, err := json.Marshal(body)
@@ -142,57 +223,115 @@ func nearRequest(method, path string, body interface{}) ([]byte, error) {
 		bodyBytes = b
 	}
 
-	const maxAttempts = 2
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		var bodyReader io.Reader
-		if bodyBytes != nil {
-			bodyReader = bytes.NewReader(bodyBytes)
-		}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, upstreamCallBudget)
+		defer cancel()
+	}
+	if err := upstreamBreaker.allow(); err != nil {
+		return nil, err
+	}
 
-		req, err := http.NewRequest(method, nearIntentsBaseURL+path, bodyReader)
-		if err != nil {
-			return nil, fmt.Errorf("create request: %w", err)
+	endpoint := upstreamEndpoint(path)
+	var lastErr error
+	for attempt := 0; attempt < upstreamMaxAttempts; attempt++ {
+		if attempt > 0 {
+			upstreamRetries.inc(endpoint)
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-		if nearIntentsJWT != "" {
-			req.Header.Set("Authorization", "Bearer "+nearIntentsJWT)
+		data, retryAfter, err := nearAttempt(ctx, method, path, endpoint, bodyBytes)
+		if err == nil {
+			upstreamBreaker.success()
+			return data, nil
 		}
-
-		resp, err := nearHTTPClient.Do(req)
-		if err != nil {
-			if attempt < maxAttempts-1 {
-				time.Sleep(2 * time.Second)
-				continue
-			}
-			return nil, fmt.Errorf("request failed: %w", err)
+		if ctx.Err() != nil {
+			upstreamBreaker.abandon()
+			return nil, fmt.Errorf("request cancelled: %w", ctx.Err())
 		}
-
-		data, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("read response: %w", err)
+		var apiErr *upstreamStatusError
+		if errors.As(err, &apiErr) && !apiErr.retryable() {
+			upstreamBreaker.success() // upstream is up; the request was refused
+			return nil, err
 		}
+		lastErr = err
 
-		if resp.StatusCode >= 500 && attempt < maxAttempts-1 {
-			time.Sleep(2 * time.Second)
-			continue
+		if attempt == upstreamMaxAttempts-1 {
+			break
 		}
-
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(data))
+		delay := backoffDelay(attempt + 1)
+		if retryAfter > upstreamRetryAfterMax {
+			break
 		}
+		delay = max(delay, retryAfter)
+		if dl, ok := ctx.Deadline(); ok && time.Until(dl) < delay {
+			break
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			upstreamBreaker.abandon()
+			return nil, fmt.Errorf("request cancelled: %w", err)
+		}
+	}
+
+	upstreamBreaker.failure()
+	return nil, lastErr
+}
+
+// upstreamStatusError is a non-2xx answer from 1Click.
+type upstreamStatusError struct {
+	Code int
+	Body string
+}
+
+func (e *upstreamStatusError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.Code, e.Body)
+}
+
+func (e *upstreamStatusError) retryable() bool {
+	return e.Code >= 500 || e.Code == http.StatusTooManyRequests
+}
+
+// nearAttempt makes a single request. retryAfter is the server's
+// Retry-After hint, if any.
+func nearAttempt(ctx context.Context, method, path, endpoint string, bodyBytes []byte) (data []byte, retryAfter time.Duration, err error) {
+	ctx, cancel := context.WithTimeout(ctx, upstreamAttemptTimeout)
+	defer cancel()
 
-		return data, nil
+	var bodyReader io.Reader
+	if bodyBytes != nil {
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, nearIntentsBaseURL+path, bodyReader)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if nearIntentsJWT != "" {
+		req.Header.Set("Authorization", "Bearer "+nearIntentsJWT)
 	}
 
-	return nil, fmt.Errorf("request failed after %d attempts", maxAttempts)
+	start := time.Now()
+	resp, err := nearHTTPClient.Do(req)
+	upstreamDuration.observe(time.Since(start).Seconds(), endpoint)
+	if err != nil {
+		upstreamRequests.inc(endpoint, "error")
+		return nil, 0, fmt.Errorf("request failed: %w", err)
+	}
+	data, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	upstreamRequests.inc(endpoint, statusClass(resp.StatusCode))
+	if err != nil {
+		return nil, 0, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryAfter, _ = parseRetryAfter(resp.Header, time.Now())
+		return nil, retryAfter, &upstreamStatusError{Code: resp.StatusCode, Body: string(data)}
+	}
+	return data, 0, nil
 }
 
-// fetchTokens retrieves the supported token list from NEAR Intents.
-func fetchTokens() ([]TokenInfo, error) {
-	data, err := nearRequest("GET", "/v0/tokens", nil)
+func (httpIntents) Tokens(ctx context.Context) ([]TokenInfo, error) {
+	data, err := nearRequest(ctx, "GET", "/v0/tokens", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -213,10 +352,8 @@ func fetchTokens() ([]TokenInfo, error) {
 	return tokens, nil
 }
 
-// requestDryQuote sends a dry quote request and parses the nested response.
-func requestDryQuote(req *QuoteRequest) (*DryQuoteResponse, error) {
-	req.Dry = true
-	data, err := nearRequest("POST", "/v0/quote", req)
+func (httpIntents) DryQuote(ctx context.Context, req *QuoteRequest) (*DryQuoteResponse, error) {
+	data, err := nearRequest(ctx, "POST", "/v0/quote", req)
 	if err != nil {
 		return nil, err
 	}
@@ -228,29 +365,29 @@ func requestDryQuote(req *QuoteRequest) (*DryQuoteResponse, error) {
 	return &resp, nil
 }
 
-// requestQuote sends a real (non-dry) quote request to NEAR Intents.
-func requestQuote(req *QuoteRequest) (*QuoteResponse, error) {
-	req.Dry = false
-	data, err := nearRequest("POST", "/v0/quote", req)
+func (httpIntents) Quote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error) {
+	data, err := nearRequest(ctx, "POST", "/v0/quote", req)
 	if err != nil {
 		return nil, err
 	}
+	return parseQuoteResponse(data)
+}
 
+func parseQuoteResponse(data []byte) (*QuoteResponse, error) {
 	var resp QuoteResponse
 	if err := json.Unmarshal(data, &resp); err != nil {
 		return nil, fmt.Errorf("parse quote response: %w", err)
 	}
+	resp.RawJSON = data
 	return &resp, nil
 }
 
-// fetchStatus checks the status of a swap by deposit address.
-// depositMemo is optional but required for chains that use memos (TON, XRP, NEAR, Stellar).
-func fetchStatus(depositAddress, depositMemo string) (*StatusResponse, error) {
+func (httpIntents) Status(ctx context.Context, depositAddress, depositMemo string) (*StatusResponse, error) {
 	q := url.Values{"depositAddress": {depositAddress}}
 	if depositMemo != "" {
 		q.Set("depositMemo", depositMemo)
 	}
-	data, err := nearRequest("GET", "/v0/status?"+q.Encode(), nil)
+	data, err := nearRequest(ctx, "GET", "/v0/status?"+q.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -286,10 +423,9 @@ type AnyInputWithdrawalsResponse struct {
 	Withdrawals []AnyInputWithdrawal `json:"withdrawals"`
 }
 
-// fetchAnyInputWithdrawals retrieves completed swap history for an ANY_INPUT deposit address.
-func fetchAnyInputWithdrawals(depositAddress string) (*AnyInputWithdrawalsResponse, error) {
+func (httpIntents) AnyInputWithdrawals(ctx context.Context, depositAddress string) (*AnyInputWithdrawalsResponse, error) {
 	q := url.Values{"depositAddress": {depositAddress}}
-	data, err := nearRequest("GET", "/v0/any-input/withdrawals?"+q.Encode(), nil)
+	data, err := nearRequest(ctx, "GET", "/v0/any-input/withdrawals?"+q.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
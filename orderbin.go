@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Compact binary encoding of OrderData, used as the plaintext of order
+// tokens to keep /order URLs, Telegram /status commands and QR codes short.
+//
+// Layout: orderBinVersion, then every field in orderBinFields order. Each
+// field is a uvarint header (n<<3 | mode) followed by a mode-specific body:
+//
+//	mode 0  literal      n bytes of UTF-8
+//	mode 1  interned     n is an index into orderInternTable; no body
+//	mode 2  0x-hex       "0x" + lowercase hex of n raw bytes
+//	mode 3  EIP-55 hex   as mode 2, re-checksummed on decode
+//	mode 4  UUID         16 raw bytes, n unused
+//	mode 5  unix secs    n is a Unix time, formatted as RFC 3339 UTC
+//	mode 6  unix millis  n is Unix milliseconds, formatted with .000Z
+//	mode 7  bare hex     lowercase hex of n raw bytes (NEAR implicit accounts)
+//
+// An empty string is a mode-0 literal of length 0. Decoding tolerates
+// missing trailing fields, so new fields can be appended to the end.
+//
+// JSON plaintexts (always starting with '{') are still accepted; see
+// unmarshalOrderData.
+
+const orderBinVersion = 0x01
+
+const (
+	binLiteral = iota
+	binInterned
+	binHex0x
+	binHexEIP55
+	binUUID
+	binUnixSecs
+	binUnixMillis
+	binHexBare
+)
+
+// orderInternTable holds common chain codes, tickers and swap types.
+// APPEND ONLY: indexes are baked into outstanding tokens.
+var orderInternTable = []string{
+	// Chains
+	"eth", "btc", "sol", "base", "arb", "ton", "tron", "bsc", "pol", "op",
+	"avax", "near", "sui", "apt", "aptos", "doge", "ltc", "xrp", "bch", "xlm",
+	"stellar", "zec", "cardano", "starknet", "gnosis", "bera", "monad",
+	"plasma", "xlayer", "aleo", "adi",
+	// Tickers
+	"ETH", "BTC", "SOL", "USDT", "USDC", "WETH", "WBTC", "DAI", "NEAR",
+	"TON", "TRX", "BNB", "POL", "OP", "ARB", "AVAX", "SUI", "APT", "DOGE",
+	"LTC", "XRP", "BCH", "XLM", "ZEC", "ADA", "STRK", "GNO", "BERA", "MON",
+	"XPL", "OKB", "ALEO", "CBBTC", "USD1", "WNEAR", "AURORA", "SWEAT",
+	// Swap types
+	"FLEX_INPUT", "EXACT_OUTPUT", "ANY_INPUT", "EXACT_INPUT",
+	// Quote signature status
+	"verified", "unchecked",
+}
+
+var orderInternIndex = func() map[string]int {
+	m := make(map[string]int, len(orderInternTable))
+	for i, s := range orderInternTable {
+		m[s] = i
+	}
+	return m
+}()
+
+// orderBinFields lists the fields in wire order. APPEND ONLY.
+func orderBinFields(d *OrderData) []*string {
+	return []*string{
+		&d.DepositAddr, &d.Memo,
+		&d.FromTicker, &d.FromNet, &d.ToTicker, &d.ToNet,
+		&d.AmountIn, &d.AmountOut, &d.Deadline, &d.CorrID,
+		&d.RefundAddr, &d.RecvAddr, &d.SwapType,
+		&d.CallbackURL, &d.CallbackSecret,
+		&d.QuoteSig, &d.Transcript,
+		&d.TranscriptHash,
+	}
+}
+
+// marshalOrderData encodes an order in the compact binary layout.
+func marshalOrderData(d *OrderData) []byte {
+	buf := []byte{orderBinVersion}
+	for _, f := range orderBinFields(d) {
+		buf = appendBinField(buf, *f)
+	}
+	return buf
+}
+
+func appendBinHeader(buf []byte, n uint64, mode int) []byte {
+	return binary.AppendUvarint(buf, n<<3|uint64(mode))
+}
+
+func appendBinField(buf []byte, s string) []byte {
+	if i, ok := orderInternIndex[s]; ok {
+		return appendBinHeader(buf, uint64(i), binInterned)
+	}
+	if raw, ok := uuidBytes(s); ok {
+		buf = appendBinHeader(buf, 0, binUUID)
+		return append(buf, raw...)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil && t.Unix() >= 0 {
+		if t.UTC().Format(time.RFC3339) == s {
+			return appendBinHeader(buf, uint64(t.Unix()), binUnixSecs)
+		}
+		if t.UTC().Format(millisLayout) == s {
+			return appendBinHeader(buf, uint64(t.UnixMilli()), binUnixMillis)
+		}
+	}
+	if strings.HasPrefix(s, "0x") && len(s) > 2 && len(s)%2 == 0 {
+		if raw, err := hex.DecodeString(s[2:]); err == nil {
+			switch {
+			case s[2:] == hex.EncodeToString(raw):
+				buf = appendBinHeader(buf, uint64(len(raw)), binHex0x)
+				return append(buf, raw...)
+			case len(raw) == 20 && s == eip55Checksum(s[2:]):
+				buf = appendBinHeader(buf, uint64(len(raw)), binHexEIP55)
+				return append(buf, raw...)
+			}
+		}
+	}
+	if len(s) >= 32 && len(s)%2 == 0 {
+		if raw, err := hex.DecodeString(s); err == nil && s == hex.EncodeToString(raw) {
+			buf = appendBinHeader(buf, uint64(len(raw)), binHexBare)
+			return append(buf, raw...)
+		}
+	}
+	buf = appendBinHeader(buf, uint64(len(s)), binLiteral)
+	return append(buf, s...)
+}
+
+const millisLayout = "2006-01-02T15:04:05.000Z"
+
+// uuidBytes parses a canonical lowercase 8-4-4-4-12 UUID.
+func uuidBytes(s string) ([]byte, bool) {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return nil, false
+	}
+	h := s[:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	raw, err := hex.DecodeString(h)
+	if err != nil || hex.EncodeToString(raw) != h {
+		return nil, false
+	}
+	return raw, true
+}
+
+func formatUUID(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// unmarshalOrderData decodes either plaintext format.
+func unmarshalOrderData(plaintext []byte) (*OrderData, error) {
+	if len(plaintext) == 0 {
+		return nil, fmt.Errorf("empty order data")
+	}
+	var d OrderData
+	switch plaintext[0] {
+	case '{':
+		if err := json.Unmarshal(plaintext, &d); err != nil {
+			return nil, fmt.Errorf("unmarshal: %w", err)
+		}
+	case orderBinVersion:
+		if err := decodeOrderBin(plaintext[1:], &d); err != nil {
+			return nil, fmt.Errorf("decode order data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown order data format 0x%02x", plaintext[0])
+	}
+	return &d, nil
+}
+
+func decodeOrderBin(b []byte, d *OrderData) error {
+	for _, f := range orderBinFields(d) {
+		if len(b) == 0 {
+			return nil // older token without trailing fields
+		}
+		hdr, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("bad field header")
+		}
+		b = b[n:]
+		size, mode := hdr>>3, int(hdr&7)
+
+		take := func(k uint64) ([]byte, error) {
+			if k > uint64(len(b)) {
+				return nil, fmt.Errorf("field truncated")
+			}
+			out := b[:k]
+			b = b[k:]
+			return out, nil
+		}
+
+		switch mode {
+		case binLiteral:
+			v, err := take(size)
+			if err != nil {
+				return err
+			}
+			*f = string(v)
+		case binInterned:
+			if size >= uint64(len(orderInternTable)) {
+				return fmt.Errorf("unknown interned code %d", size)
+			}
+			*f = orderInternTable[size]
+		case binHex0x, binHexEIP55, binHexBare:
+			v, err := take(size)
+			if err != nil {
+				return err
+			}
+			h := hex.EncodeToString(v)
+			switch mode {
+			case binHex0x:
+				*f = "0x" + h
+			case binHexEIP55:
+				*f = eip55Checksum(h)
+			default:
+				*f = h
+			}
+		case binUUID:
+			v, err := take(16)
+			if err != nil {
+				return err
+			}
+			*f = formatUUID(v)
+		case binUnixSecs:
+			*f = time.Unix(int64(size), 0).UTC().Format(time.RFC3339)
+		case binUnixMillis:
+			*f = time.UnixMilli(int64(size)).UTC().Format(millisLayout)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOrderBinRoundTrip(t *testing.T) {
+	orders := []OrderData{
+		{
+			DepositAddr: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // EIP-55
+			FromTicker:  "ETH", FromNet: "eth", ToTicker: "BTC", ToNet: "btc",
+			AmountIn: "1.5", AmountOut: "0.05123",
+			Deadline:   "2026-03-01T12:00:00.000Z",
+			CorrID:     "3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f",
+			RefundAddr: "0x000000000000000000000000000000000000dead", // lowercase
+			RecvAddr:   "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+			SwapType:   "EXACT_OUTPUT",
+		},
+		{
+			DepositAddr: "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90", // NEAR implicit
+			Memo:        "123456789",
+			FromTicker:  "wNEAR", FromNet: "near", ToTicker: "FOO", ToNet: "someNewChain",
+			AmountIn: "10", AmountOut: "0",
+			Deadline:       "2026-03-01T12:00:00Z",
+			CorrID:         "3F2A9C1E-7B4D-4E8A-9C2F-1A2B3C4D5E6F",       // not canonical: literal
+			RecvAddr:       "0x5AAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // bad checksum: literal
+			CallbackURL:    "https://example.com/hook",
+			CallbackSecret: "0123456789abcdef0123",
+		},
+		{
+			Deadline: "2026-03-01T12:00:00.123456789Z", // too precise: literal
+			CorrID:   "0xABC",
+		},
+		{},
+	}
+	for i, want := range orders {
+		got, err := unmarshalOrderData(marshalOrderData(&want))
+		if err != nil {
+			t.Fatalf("order %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("order %d round trip:\n got %+v\nwant %+v", i, *got, want)
+		}
+	}
+}
+
+func TestOrderBinSmallerThanJSON(t *testing.T) {
+	order := &OrderData{
+		DepositAddr: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		FromTicker:  "USDC", FromNet: "base", ToTicker: "SOL", ToNet: "sol",
+		AmountIn: "250", AmountOut: "1.734",
+		Deadline:   "2026-03-01T12:00:00.000Z",
+		CorrID:     "3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f",
+		RefundAddr: "0x000000000000000000000000000000000000dEaD",
+		RecvAddr:   "So11111111111111111111111111111111111111112",
+	}
+	js, _ := json.Marshal(order)
+	bin := marshalOrderData(order)
+	if len(bin)*2 > len(js) {
+		t.Errorf("binary encoding is %d bytes, JSON %d — expected at least 2x smaller", len(bin), len(js))
+	}
+}
+
+func TestOrderBinOlderTokenWithoutTrailingFields(t *testing.T) {
+	// An older encoder that only knew the first two fields.
+	old := appendBinField(appendBinField([]byte{orderBinVersion}, "abc"), "m")
+	got, err := unmarshalOrderData(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.DepositAddr != "abc" || got.Memo != "m" || got.FromTicker != "" || got.QuoteSig != "" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestOrderBinRejectsGarbage(t *testing.T) {
+	for _, b := range [][]byte{
+		{},
+		{0x7f},
+		{orderBinVersion, 0xff},               // unterminated varint
+		{orderBinVersion, 10<<3 | binLiteral}, // 10-byte literal, no body
+		{orderBinVersion, 0xf9, 0x7f},         // interned code out of range
+	} {
+		if _, err := unmarshalOrderData(b); err == nil {
+			t.Errorf("unmarshalOrderData(%x) should fail", b)
+		}
+	}
+}
+
+func TestJSONPlaintextTokenStillOpens(t *testing.T) {
+	key := testKey(0x42)
+	withKeyring(t, key)
+
+	// Versioned header, JSON plaintext: tokens issued before the binary format.
+	plaintext, _ := json.Marshal(&OrderData{DepositAddr: "0xjson", FromNet: "eth", SwapType: "ANY_INPUT"})
+	k := keyring.current()
+	gcm, _ := newOrderGCM(k.key)
+	iv := make([]byte, gcm.NonceSize())
+	rand.Read(iv)
+	header := []byte{tokenVersion, k.id}
+	packed := gcm.Seal(append(append([]byte{}, header...), iv...), iv, plaintext, header)
+
+	order, err := decryptOrderData(base64.RawURLEncoding.EncodeToString(packed))
+	if err != nil {
+		t.Fatalf("JSON token should open: %v", err)
+	}
+	if order.DepositAddr != "0xjson" || order.FromNet != "eth" || order.SwapType != "ANY_INPUT" {
+		t.Errorf("got %+v", order)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// orderEventsInterval matches the page's meta-refresh cadence so the
+// stream never polls 1Click harder than the no-JS fallback does.
+var orderEventsInterval = 10 * time.Second
+
+// orderEventsMaxAge bounds a single stream; EventSource reconnects.
+const orderEventsMaxAge = 30 * time.Minute
+
+// orderEvent is the payload of each "status" event.
+type orderEvent struct {
+	Status      string       `json:"status"`
+	StatusStep  int          `json:"statusStep"`
+	IsTerminal  bool         `json:"isTerminal"`
+	SwapDetails *SwapDetails `json:"swapDetails,omitempty"`
+	// HTML is the re-rendered "order-progress" block, which the order page
+	// swaps in place. It is left out at the deposit step, whose block (with
+	// the QR code) the page already shows.
+	HTML string `json:"html,omitempty"`
+}
+
+// handleOrderEvents streams order status changes as Server-Sent Events.
+// It polls fetchStatus server-side and emits a "status" event only when
+// Status or SwapDetails change, then an "end" event once terminal.
+func handleOrderEvents(w http.ResponseWriter, r *http.Request, order *OrderData) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	if !limiter.allow("events|"+clientIP(r), 30, time.Minute) {
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	// Reconnect no faster than the poll interval.
+	fmt.Fprintf(w, "retry: %d\n\n", orderEventsInterval.Milliseconds())
+	flusher.Flush()
+
+	ctx := r.Context()
+	deadline := time.NewTimer(orderEventsMaxAge)
+	defer deadline.Stop()
+	ticker := time.NewTicker(orderEventsInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
+		if err == nil {
+			if fp := statusFingerprint(status); fp != last {
+				last = fp
+				step, terminal := orderStatusStep(status.Status)
+				payload, _ := json.Marshal(orderEvent{
+					Status:      status.Status,
+					StatusStep:  step,
+					IsTerminal:  terminal,
+					SwapDetails: status.SwapDetails,
+					HTML:        renderOrderProgress(order, status, step),
+				})
+				fmt.Fprintf(w, "event: status\ndata: %s\n\n", payload)
+				if terminal {
+					fmt.Fprint(w, "event: end\ndata: {}\n\n")
+					flusher.Flush()
+					return
+				}
+			} else {
+				fmt.Fprint(w, ": ping\n\n")
+			}
+		} else {
+			// Upstream hiccup — keep the stream open and try again.
+			fmt.Fprint(w, ": upstream unavailable\n\n")
+		}
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusFingerprint identifies the parts of a status response the stream
+// reports on, so unchanged polls produce no event.
+func statusFingerprint(s *StatusResponse) string {
+	details, _ := json.Marshal(s.SwapDetails)
+	return s.Status + "|" + string(details)
+}
+
+// renderOrderProgress renders the order page's status block for an event,
+// or "" at the deposit step.
+func renderOrderProgress(order *OrderData, status *StatusResponse, step int) string {
+	if step == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	err := templates.ExecuteTemplate(&buf, "order-progress", OrderPageData{
+		Order:      order,
+		Status:     status,
+		StatusStep: step,
+		Chain:      lookupChain(order.FromNet),
+	})
+	if err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Payment URIs let wallets prefill the amount (and memo) when scanning the
+// deposit QR code. Each chain's scheme, EIP-155 ID and gas token come from
+// the chain registry (chains.go). Chains without a supported scheme fall
+// back to the bare address, as do orders whose token isn't in the cache when we need its
+// contract or decimals.
+
+// paymentURI builds a wallet payment URI for the order's deposit, or ""
+// when the chain has no supported scheme. ANY_INPUT orders get a URI
+// without an amount.
+func paymentURI(order *OrderData) string {
+	chain := strings.ToLower(order.FromNet)
+	amount := order.AmountIn
+	if order.SwapType == "ANY_INPUT" {
+		amount = ""
+	}
+	c := lookupChain(chain)
+	if c == nil {
+		return ""
+	}
+	native := c.NativeTicker != "" && strings.EqualFold(order.FromTicker, c.NativeTicker)
+
+	if scheme := c.URIScheme; scheme != "" {
+		// Memo-bearing deposits can't be expressed in BIP21.
+		if order.Memo != "" {
+			return ""
+		}
+		return bip21URI(scheme, order.DepositAddr, amount)
+	}
+
+	if chainID := c.EVMChainID; chainID != 0 {
+		if order.Memo != "" {
+			return ""
+		}
+		if native {
+			return eip681NativeURI(order.DepositAddr, chainID, amount, 18)
+		}
+		tok := findToken(order.FromTicker, chain)
+		if tok == nil || tok.ContractAddress == "" {
+			return ""
+		}
+		return eip681TokenURI(tok.ContractAddress, chainID, order.DepositAddr, amount, tok.Decimals)
+	}
+
+	switch c.Code {
+	case "sol":
+		mint := ""
+		if !native {
+			tok := findToken(order.FromTicker, chain)
+			if tok == nil || tok.ContractAddress == "" {
+				return ""
+			}
+			mint = tok.ContractAddress
+		}
+		return solanaPayURI(order.DepositAddr, amount, mint, order.Memo)
+	case "ton":
+		// Jetton transfers aren't part of ton://transfer.
+		if !native {
+			return ""
+		}
+		return tonTransferURI(order.DepositAddr, amount, order.Memo)
+	}
+	return ""
+}
+
+// bip21URI: bitcoin:<address>?amount=<decimal coins>
+func bip21URI(scheme, addr, amount string) string {
+	uri := scheme + ":" + addr
+	if amount != "" {
+		uri += "?amount=" + amount
+	}
+	return uri
+}
+
+// eip681NativeURI: ethereum:<address>@<chainId>?value=<wei>
+func eip681NativeURI(addr string, chainID int, amount string, decimals int) string {
+	uri := "ethereum:" + addr + "@" + strconv.Itoa(chainID)
+	if amount != "" {
+		atomic, err := humanToAtomic(amount, decimals)
+		if err != nil {
+			return ""
+		}
+		uri += "?value=" + atomic
+	}
+	return uri
+}
+
+// eip681TokenURI: ethereum:<contract>@<chainId>/transfer?address=<to>&uint256=<atomic>
+func eip681TokenURI(contract string, chainID int, to, amount string, decimals int) string {
+	uri := "ethereum:" + contract + "@" + strconv.Itoa(chainID) + "/transfer?address=" + to
+	if amount != "" {
+		atomic, err := humanToAtomic(amount, decimals)
+		if err != nil {
+			return ""
+		}
+		uri += "&uint256=" + atomic
+	}
+	return uri
+}
+
+// solanaPayURI: solana:<recipient>?amount=<decimal>&spl-token=<mint>&memo=<memo>
+func solanaPayURI(addr, amount, mint, memo string) string {
+	q := make([]string, 0, 3)
+	if amount != "" {
+		q = append(q, "amount="+amount)
+	}
+	if mint != "" {
+		q = append(q, "spl-token="+mint)
+	}
+	if memo != "" {
+		q = append(q, "memo="+uriEscape(memo))
+	}
+	return joinURI("solana:"+addr, q)
+}
+
+// tonTransferURI: ton://transfer/<address>?amount=<nanotons>&text=<comment>
+func tonTransferURI(addr, amount, memo string) string {
+	q := make([]string, 0, 2)
+	if amount != "" {
+		atomic, err := humanToAtomic(amount, 9)
+		if err != nil {
+			return ""
+		}
+		q = append(q, "amount="+atomic)
+	}
+	if memo != "" {
+		q = append(q, "text="+uriEscape(memo))
+	}
+	return joinURI("ton://transfer/"+addr, q)
+}
+
+// uriEscape percent-encodes a query value, using %20 for spaces since
+// not every wallet decodes '+'.
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func joinURI(base string, query []string) string {
+	if len(query) == 0 {
+		return base
+	}
+	return base + "?" + strings.Join(query, "&")
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every live token refresh appends each asset's USD price to a per-asset
+// series covering the last priceHistoryWindow. The series drive the
+// sparklines and 24h/7d change on /currencies and in the swap modal, and
+// /api/v1/prices. With PRICE_HISTORY_PATH set the series are written there
+// after each refresh and reloaded at startup; otherwise they start empty
+// and changes appear once enough history has built up.
+
+const (
+	priceHistoryWindow = 7*24*time.Hour + time.Hour
+	// Refreshes closer together than this (manual retries, restarts)
+	// don't add a sample.
+	priceHistoryMinGap = 4 * time.Minute
+	sparklinePoints    = 48
+)
+
+var priceHistoryPath = os.Getenv("PRICE_HISTORY_PATH")
+
+// pricePoint is one sample: unix seconds and USD price. It encodes as a
+// two-element JSON array to keep the file and API responses small.
+type pricePoint struct {
+	T int64
+	P float64
+}
+
+func (p pricePoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{float64(p.T), p.P})
+}
+
+func (p *pricePoint) UnmarshalJSON(b []byte) error {
+	var v [2]float64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	p.T, p.P = int64(v[0]), v[1]
+	return nil
+}
+
+var priceHistory = struct {
+	mu     sync.RWMutex
+	series map[string][]pricePoint // by DefuseAssetID, oldest first
+	// sparklines holds each asset's rendered 7-day sparkline. Pages draw
+	// one per token card, so they are built once per refresh rather than
+	// from the full series on every render.
+	sparklines map[string]template.HTML
+}{series: make(map[string][]pricePoint), sparklines: make(map[string]template.HTML)}
+
+// recordPrices appends the current price of every priced token and drops
+// samples (and assets) older than the window.
+func recordPrices(tokens []TokenInfo, now time.Time) {
+	cutoff := now.Add(-priceHistoryWindow).Unix()
+	priceHistory.mu.Lock()
+	defer priceHistory.mu.Unlock()
+	for _, t := range tokens {
+		if t.Price <= 0 {
+			continue
+		}
+		s := priceHistory.series[t.DefuseAssetID]
+		if n := len(s); n > 0 && now.Unix()-s[n-1].T < int64(priceHistoryMinGap/time.Second) {
+			continue
+		}
+		priceHistory.series[t.DefuseAssetID] = append(s, pricePoint{now.Unix(), t.Price})
+	}
+	for id, s := range priceHistory.series {
+		i := 0
+		for i < len(s) && s[i].T < cutoff {
+			i++
+		}
+		switch {
+		case i == len(s):
+			delete(priceHistory.series, id)
+		case i > 0:
+			priceHistory.series[id] = append([]pricePoint(nil), s[i:]...)
+		}
+	}
+	rebuildSparklinesLocked(now)
+}
+
+// rebuildSparklinesLocked renders every asset's sparkline from its last 7
+// days. The caller holds priceHistory.mu for writing.
+func rebuildSparklinesLocked(now time.Time) {
+	from := now.Add(-7 * 24 * time.Hour).Unix()
+	sparklines := make(map[string]template.HTML, len(priceHistory.series))
+	for id, s := range priceHistory.series {
+		i := 0
+		for i < len(s) && s[i].T < from {
+			i++
+		}
+		if svg := sparklineSVG(s[i:], 64, 18); svg != "" {
+			sparklines[id] = template.HTML(svg)
+		}
+	}
+	priceHistory.sparklines = sparklines
+}
+
+// priceSeries returns a copy of an asset's samples from the last d.
+func priceSeries(assetID string, d time.Duration, now time.Time) []pricePoint {
+	from := now.Add(-d).Unix()
+	priceHistory.mu.RLock()
+	defer priceHistory.mu.RUnlock()
+	s := priceHistory.series[assetID]
+	i := 0
+	for i < len(s) && s[i].T < from {
+		i++
+	}
+	return append([]pricePoint{}, s[i:]...)
+}
+
+// priceMove is a price change over a window, for templates.
+type priceMove struct {
+	Pct float64
+}
+
+// Label formats the change as "+1.2%" / "-0.4%".
+func (m *priceMove) Label() string {
+	return fmt.Sprintf("%+.1f%%", m.Pct)
+}
+
+// Dir is "up", "down" or "flat", for CSS.
+func (m *priceMove) Dir() string {
+	switch {
+	case m.Pct >= 0.05:
+		return "up"
+	case m.Pct <= -0.05:
+		return "down"
+	}
+	return "flat"
+}
+
+// priceChange compares the latest sample with the last one at least d
+// old. Returns nil until the history reaches back that far, or when a gap
+// leaves no sample near the start of the window.
+func priceChange(assetID string, d time.Duration, now time.Time) *priceMove {
+	at := now.Add(-d).Unix()
+	priceHistory.mu.RLock()
+	defer priceHistory.mu.RUnlock()
+	s := priceHistory.series[assetID]
+	if len(s) < 2 || s[0].T > at {
+		return nil
+	}
+	base := s[0]
+	for _, p := range s {
+		if p.T > at {
+			break
+		}
+		base = p
+	}
+	if base.P <= 0 || at-base.T > int64(d/time.Second)/8 {
+		return nil
+	}
+	return &priceMove{Pct: (s[len(s)-1].P/base.P - 1) * 100}
+}
+
+// parsePriceWindow accepts the windows the UI and API offer.
+func parsePriceWindow(s string) (time.Duration, bool) {
+	switch s {
+	case "24h":
+		return 24 * time.Hour, true
+	case "7d":
+		return 7 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// priceChangeFunc is the "priceChange" template func: {{priceChange .DefuseAssetID "24h"}}.
+func priceChangeFunc(assetID, window string) *priceMove {
+	d, ok := parsePriceWindow(window)
+	if !ok {
+		return nil
+	}
+	return priceChange(assetID, d, time.Now())
+}
+
+// sparklineFunc is the "sparkline" template func: a small inline SVG of
+// the asset's last 7 days, or nothing without enough history. It returns
+// the copy rendered at the last refresh.
+func sparklineFunc(assetID string) template.HTML {
+	priceHistory.mu.RLock()
+	defer priceHistory.mu.RUnlock()
+	return priceHistory.sparklines[assetID]
+}
+
+// sparklineSVG draws points as a polyline scaled to w×h, downsampled to
+// sparklinePoints. Returns "" for fewer than two points.
+func sparklineSVG(points []pricePoint, w, h int) string {
+	if len(points) < 2 {
+		return ""
+	}
+	if len(points) > sparklinePoints {
+		step := float64(len(points)-1) / float64(sparklinePoints-1)
+		sampled := make([]pricePoint, sparklinePoints)
+		for i := range sampled {
+			sampled[i] = points[int(math.Round(float64(i)*step))]
+		}
+		points = sampled
+	}
+
+	lo, hi := points[0].P, points[0].P
+	for _, p := range points {
+		lo, hi = math.Min(lo, p.P), math.Max(hi, p.P)
+	}
+	t0, t1 := points[0].T, points[len(points)-1].T
+	var b strings.Builder
+	for i, p := range points {
+		x := float64(w) * float64(i) / float64(len(points)-1)
+		if t1 > t0 {
+			x = float64(w) * float64(p.T-t0) / float64(t1-t0)
+		}
+		y := float64(h) / 2
+		if hi > lo {
+			y = 1 + (float64(h)-2)*(hi-p.P)/(hi-lo)
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+	}
+
+	dir := "flat"
+	if last, first := points[len(points)-1].P, points[0].P; last > first {
+		dir = "up"
+	} else if last < first {
+		dir = "down"
+	}
+	return fmt.Sprintf(`<svg class="sparkline sparkline--%s" viewBox="0 0 %d %d" width="%d" height="%d" aria-hidden="true"><polyline points="%s" fill="none" stroke="currentColor" stroke-width="1.2" stroke-linejoin="round" stroke-linecap="round"/></svg>`,
+		dir, w, h, w, h, b.String())
+}
+
+// ── Persistence ──
+
+type priceHistoryFile struct {
+	SavedAt time.Time               `json:"savedAt"`
+	Series  map[string][]pricePoint `json:"series"`
+}
+
+// savePriceHistory writes the series to PRICE_HISTORY_PATH, if set.
+func savePriceHistory() {
+	if priceHistoryPath == "" {
+		return
+	}
+	priceHistory.mu.RLock()
+	data, err := json.Marshal(priceHistoryFile{SavedAt: time.Now().UTC(), Series: priceHistory.series})
+	priceHistory.mu.RUnlock()
+	if err != nil {
+		log.Printf("price history encode error: %v", err)
+		return
+	}
+	if err := writeFileAtomic(priceHistoryPath, data); err != nil {
+		log.Printf("price history write error: %v", err)
+	}
+}
+
+// loadPriceHistory restores the series from PRICE_HISTORY_PATH, dropping
+// anything older than the window.
+func loadPriceHistory(now time.Time) {
+	if priceHistoryPath == "" {
+		return
+	}
+	data, err := os.ReadFile(priceHistoryPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("price history read error: %v", err)
+		}
+		return
+	}
+	var f priceHistoryFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		log.Printf("price history ignored: %v", err)
+		return
+	}
+	cutoff := now.Add(-priceHistoryWindow).Unix()
+	series := make(map[string][]pricePoint, len(f.Series))
+	for id, s := range f.Series {
+		var kept []pricePoint
+		for _, p := range s {
+			if p.T >= cutoff && p.P > 0 {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) > 0 {
+			series[id] = kept
+		}
+	}
+	priceHistory.mu.Lock()
+	priceHistory.series = series
+	rebuildSparklinesLocked(now)
+	priceHistory.mu.Unlock()
+	log.Printf("Loaded price history for %d assets", len(series))
+}
+
+// ── API ──
+
+// handleAPIPrices serves one asset's series: GET /api/v1/prices?asset=<id>&window=24h|7d.
+func handleAPIPrices(w http.ResponseWriter, r *http.Request) {
+	if !apiGuard(w, r, http.MethodGet, "prices", 60) {
+		return
+	}
+	assetID := r.URL.Query().Get("asset")
+	if assetID == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing_asset", "Pass an asset ID as ?asset=.")
+		return
+	}
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "7d"
+	}
+	d, ok := parsePriceWindow(window)
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, "invalid_window", "window must be 24h or 7d.")
+		return
+	}
+	tok := findTokenByAssetID(assetID)
+	if tok == nil {
+		writeAPIError(w, http.StatusNotFound, "unknown_asset", "No token with that asset ID.")
+		return
+	}
+
+	now := time.Now()
+	resp := map[string]interface{}{
+		"assetId": tok.DefuseAssetID,
+		"ticker":  tok.Ticker,
+		"network": tok.ChainName,
+		"price":   tok.Price,
+		"window":  window,
+		"points":  priceSeries(assetID, d, now),
+	}
+	for name, d := range map[string]time.Duration{"change24h": 24 * time.Hour, "change7d": 7 * 24 * time.Hour} {
+		if m := priceChange(assetID, d, now); m != nil {
+			resp[name] = math.Round(m.Pct*100) / 100
+		} else {
+			resp[name] = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withPriceHistory starts from empty series and restores them afterwards.
+func withPriceHistory(t *testing.T) {
+	t.Helper()
+	priceHistory.mu.Lock()
+	saved, savedSparklines := priceHistory.series, priceHistory.sparklines
+	priceHistory.series = make(map[string][]pricePoint)
+	priceHistory.sparklines = make(map[string]template.HTML)
+	priceHistory.mu.Unlock()
+	savedPath := priceHistoryPath
+	t.Cleanup(func() {
+		priceHistoryPath = savedPath
+		priceHistory.mu.Lock()
+		priceHistory.series, priceHistory.sparklines = saved, savedSparklines
+		priceHistory.mu.Unlock()
+	})
+}
+
+// feedPrices records one sample every 5 minutes for d, price(i) giving the
+// price at step i. Returns the time of the last sample.
+func feedPrices(start time.Time, d time.Duration, assetID string, price func(i int) float64) time.Time {
+	now := start
+	for i := 0; now.Sub(start) <= d; i++ {
+		recordPrices([]TokenInfo{{DefuseAssetID: assetID, Price: price(i)}}, now)
+		now = now.Add(5 * time.Minute)
+	}
+	return now.Add(-5 * time.Minute)
+}
+
+func TestPriceHistoryChanges(t *testing.T) {
+	withPriceHistory(t)
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// 100 for six days, then climbing to 110 over the last day.
+	last := feedPrices(start, 7*24*time.Hour, "nep141:x", func(i int) float64 {
+		if i < 6*24*12 {
+			return 100
+		}
+		return 100 + 10*float64(i-6*24*12)/(24*12)
+	})
+	if m := priceChange("nep141:x", 24*time.Hour, last); m == nil || m.Label() != "+10.0%" || m.Dir() != "up" {
+		t.Errorf("24h change = %+v", m)
+	}
+	if m := priceChange("nep141:x", 7*24*time.Hour, last); m == nil || m.Label() != "+10.0%" {
+		t.Errorf("7d change = %+v", m)
+	}
+	if priceChange("nep141:x", 7*24*time.Hour, start.Add(6*24*time.Hour)) != nil {
+		t.Error("7d change needs history reaching back 7 days")
+	}
+	if priceChange("nep141:unknown", 24*time.Hour, last) != nil {
+		t.Error("unknown asset has no change")
+	}
+
+	// The sparkline is rendered once per refresh, already downsampled.
+	svg := string(sparklineFunc("nep141:x"))
+	if !strings.HasPrefix(svg, `<svg class="sparkline sparkline--up"`) || strings.Count(svg, ",") != sparklinePoints {
+		t.Errorf("cached sparkline = %.80s… (%d points)", svg, strings.Count(svg, ","))
+	}
+
+	// The window is bounded.
+	priceHistory.mu.RLock()
+	n := len(priceHistory.series["nep141:x"])
+	oldest := priceHistory.series["nep141:x"][0].T
+	priceHistory.mu.RUnlock()
+	if n > int(priceHistoryWindow/(5*time.Minute))+1 || oldest < last.Add(-priceHistoryWindow).Unix() {
+		t.Errorf("series holds %d samples from %v", n, time.Unix(oldest, 0))
+	}
+
+	// Assets that stop being quoted age out.
+	recordPrices(nil, last.Add(priceHistoryWindow+time.Minute))
+	if len(priceSeries("nep141:x", priceHistoryWindow, last)) != 0 || sparklineFunc("nep141:x") != "" {
+		t.Error("stale series and their sparklines should be dropped")
+	}
+}
+
+func TestPriceHistorySkipsBurstsAndGaps(t *testing.T) {
+	withPriceHistory(t)
+	now := time.Now()
+	recordPrices([]TokenInfo{{DefuseAssetID: "a", Price: 1}}, now)
+	recordPrices([]TokenInfo{{DefuseAssetID: "a", Price: 2}}, now.Add(time.Minute))
+	if s := priceSeries("a", time.Hour, now.Add(time.Minute)); len(s) != 1 {
+		t.Errorf("refreshes within priceHistoryMinGap should not add samples: %v", s)
+	}
+
+	// A sample 3 days old says nothing about the last 24h.
+	recordPrices([]TokenInfo{{DefuseAssetID: "a", Price: 2}}, now.Add(72*time.Hour))
+	if m := priceChange("a", 24*time.Hour, now.Add(72*time.Hour)); m != nil {
+		t.Errorf("change across a gap = %+v", m)
+	}
+}
+
+func TestSparklineSVG(t *testing.T) {
+	if sparklineSVG([]pricePoint{{1, 5}}, 64, 18) != "" {
+		t.Error("one point is not a line")
+	}
+	var pts []pricePoint
+	for i := 0; i < 500; i++ {
+		pts = append(pts, pricePoint{int64(i * 300), float64(100 - i%7)})
+	}
+	svg := sparklineSVG(pts, 64, 18)
+	if !strings.HasPrefix(svg, `<svg class="sparkline sparkline--down"`) {
+		t.Errorf("svg = %.80s", svg)
+	}
+	coords := strings.Fields(svg[strings.Index(svg, `points="`)+8 : strings.Index(svg, `" fill`)])
+	if len(coords) != sparklinePoints || coords[0] != "0.0,1.0" || !strings.HasPrefix(coords[len(coords)-1], "64.0,") {
+		t.Errorf("%d coords from %s to %s", len(coords), coords[0], coords[len(coords)-1])
+	}
+	if flat := sparklineSVG([]pricePoint{{0, 1}, {300, 1}}, 64, 18); !strings.Contains(flat, "sparkline--flat") || !strings.Contains(flat, "0.0,9.0 64.0,9.0") {
+		t.Errorf("flat series = %s", flat)
+	}
+}
+
+func TestPriceHistoryPersistence(t *testing.T) {
+	withPriceHistory(t)
+	priceHistoryPath = filepath.Join(t.TempDir(), "prices.json")
+	now := time.Now()
+	feedPrices(now.Add(-time.Hour), time.Hour, "nep141:p", func(i int) float64 { return float64(10 + i) })
+	savePriceHistory()
+
+	priceHistory.mu.Lock()
+	priceHistory.series = make(map[string][]pricePoint)
+	priceHistory.mu.Unlock()
+	loadPriceHistory(now)
+	if s := priceSeries("nep141:p", 2*time.Hour, now); len(s) != 13 || s[0].P != 10 {
+		t.Fatalf("reloaded series = %v", s)
+	}
+	// Samples past the window are dropped on load.
+	later := now.Add(priceHistoryWindow - 30*time.Minute)
+	loadPriceHistory(later)
+	if s := priceSeries("nep141:p", priceHistoryWindow, later); len(s) != 7 {
+		t.Errorf("expected the 7 samples still in the window, got %d", len(s))
+	}
+}
+
+func TestPriceHistoryOnPagesAndAPI(t *testing.T) {
+	withPriceHistory(t)
+	withTokenSnapshot(t)
+	price := 2000.0
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"assetId":"nep141:eth.omft.near","symbol":"eth","blockchain":"eth","decimals":18,"price":%g}]`, price)
+	})
+	if err := refreshTokenCache(); err != nil {
+		t.Fatal(err)
+	}
+	// Backfill a day of history behind the live sample.
+	now := time.Now()
+	priceHistory.mu.Lock()
+	priceHistory.series["nep141:eth.omft.near"] = []pricePoint{{now.Add(-25 * time.Hour).Unix(), 1600}, {now.Add(-12 * time.Hour).Unix(), 1800}, {now.Unix(), 2000}}
+	rebuildSparklinesLocked(now)
+	priceHistory.mu.Unlock()
+
+	w := httptest.NewRecorder()
+	handleCurrencies(w, httptest.NewRequest("GET", "/currencies", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, `<svg class="sparkline sparkline--up"`) || !strings.Contains(body, "&#43;25.0%</span>") {
+		t.Errorf("currencies page should show the sparkline and 24h change")
+	}
+
+	w = httptest.NewRecorder()
+	handleAPIPrices(w, httptest.NewRequest("GET", "/api/v1/prices?asset=nep141:eth.omft.near&window=24h", nil))
+	var resp struct {
+		Ticker    string       `json:"ticker"`
+		Points    []pricePoint `json:"points"`
+		Change24h *float64     `json:"change24h"`
+		Change7d  *float64     `json:"change7d"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != 200 {
+		t.Fatalf("%d %s", w.Code, w.Body.String())
+	}
+	if resp.Ticker != "ETH" || len(resp.Points) != 2 || resp.Change24h == nil || *resp.Change24h != 25 || resp.Change7d != nil {
+		t.Errorf("api response = %s", w.Body.String())
+	}
+
+	for query, code := range map[string]int{"": 400, "?asset=nep141:eth.omft.near&window=1y": 400, "?asset=nope": 404} {
+		w := httptest.NewRecorder()
+		handleAPIPrices(w, httptest.NewRequest("GET", "/api/v1/prices"+query, nil))
+		if w.Code != code {
+			t.Errorf("%q: status %d, want %d", query, w.Code, code)
+		}
+	}
+}
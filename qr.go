@@ -5,12 +5,10 @@ import (
 	"strings"
 )
 
-// generateQRSVG generates an inline SVG QR code for the given data string.
-// This is a minimal QR code generator — it produces a version 2-6 QR code
-// with error correction level M. For crypto deposit addresses, this is sufficient.
-//
-// The implementation uses alphanumeric mode when possible, byte mode otherwise.
-// It generates the QR matrix, applies masking, and renders as an SVG.
+// QR code generator (ISO/IEC 18004, model 2): versions 1–40, EC levels
+// L/M/Q/H with multi-block interleaving, numeric/alphanumeric/byte modes and
+// penalty-based mask selection. encodeQR builds the module matrix;
+// generateQRSVG and generateQRPNG (tgqr.go) render it.
 
 // qrBitBuffer is a simple bit buffer for building QR data.
 type qrBitBuffer struct {
@@ -27,10 +25,7 @@ func (b *qrBitBuffer) length() int {
 	return len(b.bits)
 }
 
-// Since a full QR encoder is complex (~500 lines), we use a simplified approach:
-// encode the data as a QR code using byte mode, generate the SVG from the module matrix.
-// For production reliability, this generates valid QR codes for typical crypto addresses.
-
+// generateQRSVG generates an inline SVG QR code for the given data string.
 func generateQRSVG(data string, size int) string {
 	modules := encodeQR(data)
 	if modules == nil {
@@ -66,17 +61,62 @@ func generateQRSVG(data string, size int) string {
 	return sb.String()
 }
 
-// encodeQR generates a QR code module matrix for the given data.
-// Returns nil if the data is too long for supported QR versions.
+// qrECLevel is a QR error correction level, in increasing order of
+// redundancy (L ~7%, M ~15%, Q ~25%, H ~30% of codewords recoverable).
+type qrECLevel int
+
+const (
+	qrECLow qrECLevel = iota
+	qrECMedium
+	qrECQuartile
+	qrECHigh
+)
+
+// formatBits is the 2-bit EC level indicator used in the format info.
+func (l qrECLevel) formatBits() int {
+	return [...]int{0b01, 0b00, 0b11, 0b10}[l]
+}
+
+// Per-version tables from ISO/IEC 18004, indexed [ecLevel][version].
+var qrECCodewordsPerBlock = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var qrECBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// QR data modes.
+const (
+	qrModeNumeric      = 0b0001
+	qrModeAlphanumeric = 0b0010
+	qrModeByte         = 0b0100
+)
+
+const qrAlphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// encodeQR generates a QR code module matrix for the given data with at
+// least EC level M. Returns nil if the data doesn't fit in version 40.
 func encodeQR(data string) [][]bool {
-	dataBytes := []byte(data)
+	return encodeQRLevel(data, qrECMedium)
+}
+
+// encodeQRLevel picks the smallest version that holds data at minLevel,
+// then raises the EC level as far as that version allows. The whole
+// payload goes in one segment using the most compact mode it qualifies
+// for (numeric, alphanumeric or byte).
+func encodeQRLevel(data string, minLevel qrECLevel) [][]bool {
+	mode := qrSelectMode(data)
 
-	// Determine version (1-10) based on byte mode capacity with EC level M
-	// Byte mode capacities (EC level M): v1=14, v2=26, v3=42, v4=62, v5=84, v6=106, v7=122, v8=152, v9=180, v10=213
-	capacities := []int{0, 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
 	version := 0
-	for v := 1; v <= 10; v++ {
-		if len(dataBytes) <= capacities[v] {
+	for v := 1; v <= 40; v++ {
+		if qrSegmentBits(mode, data, v) <= qrDataCodewords(v, minLevel)*8 {
 			version = v
 			break
 		}
@@ -85,252 +125,465 @@ func encodeQR(data string) [][]bool {
 		return nil // data too long
 	}
 
-	n := 17 + version*4 // module count
-	modules := make([][]bool, n)
-	reserved := make([][]bool, n)
-	for i := range modules {
-		modules[i] = make([]bool, n)
-		reserved[i] = make([]bool, n)
+	level := minLevel
+	for l := minLevel + 1; l <= qrECHigh; l++ {
+		if qrSegmentBits(mode, data, version) <= qrDataCodewords(version, l)*8 {
+			level = l
+		}
+	}
+
+	// Data bit stream: mode, count, payload, terminator, padding.
+	buf := &qrBitBuffer{}
+	buf.put(mode, 4)
+	buf.put(len(data), qrCountBits(mode, version))
+	qrAppendPayload(buf, mode, data)
+
+	capacity := qrDataCodewords(version, level) * 8
+	buf.put(0, min(4, capacity-buf.length()))
+	for buf.length()%8 != 0 {
+		buf.put(0, 1)
+	}
+	for pad := 0xEC; buf.length() < capacity; pad ^= 0xEC ^ 0x11 {
+		buf.put(pad, 8)
 	}
 
-	// Place finder patterns
-	placeFinder := func(row, col int) {
-		for r := -1; r <= 7; r++ {
-			for c := -1; c <= 7; c++ {
-				rr, cc := row+r, col+c
-				if rr < 0 || rr >= n || cc < 0 || cc >= n {
-					continue
-				}
-				dark := (r >= 0 && r <= 6 && (c == 0 || c == 6)) ||
-					(c >= 0 && c <= 6 && (r == 0 || r == 6)) ||
-					(r >= 2 && r <= 4 && c >= 2 && c <= 4)
-				modules[rr][cc] = dark
-				reserved[rr][cc] = true
+	codewords := make([]byte, buf.length()/8)
+	for i := range codewords {
+		for bit := 0; bit < 8; bit++ {
+			if buf.bits[i*8+bit] {
+				codewords[i] |= 1 << (7 - bit)
 			}
 		}
 	}
-	placeFinder(0, 0)
-	placeFinder(0, n-7)
-	placeFinder(n-7, 0)
 
-	// Timing patterns
-	for i := 8; i < n-8; i++ {
-		modules[6][i] = i%2 == 0
-		reserved[6][i] = true
-		modules[i][6] = i%2 == 0
-		reserved[i][6] = true
+	m := newQRMatrix(version)
+	m.drawFunctionPatterns(level)
+	m.drawCodewords(qrAddECAndInterleave(codewords, version, level))
+
+	// Try every mask and keep the one with the lowest penalty.
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		m.applyMask(mask)
+		m.drawFormatBits(level, mask)
+		if p := m.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		m.applyMask(mask) // XOR again to undo
+	}
+	m.applyMask(best)
+	m.drawFormatBits(level, best)
+
+	return m.modules
+}
+
+func qrSelectMode(data string) int {
+	numeric, alnum := true, true
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c < '0' || c > '9' {
+			numeric = false
+		}
+		if strings.IndexByte(qrAlphanumericChars, c) < 0 {
+			alnum = false
+		}
 	}
+	switch {
+	case numeric && data != "":
+		return qrModeNumeric
+	case alnum && data != "":
+		return qrModeAlphanumeric
+	}
+	return qrModeByte
+}
+
+// qrCountBits is the width of the character count field.
+func qrCountBits(mode, version int) int {
+	i := 0
+	if version >= 27 {
+		i = 2
+	} else if version >= 10 {
+		i = 1
+	}
+	switch mode {
+	case qrModeNumeric:
+		return [...]int{10, 12, 14}[i]
+	case qrModeAlphanumeric:
+		return [...]int{9, 11, 13}[i]
+	}
+	return [...]int{8, 16, 16}[i]
+}
 
-	// Dark module
-	modules[n-8][8] = true
-	reserved[n-8][8] = true
+// qrSegmentBits is the total size of the segment (header included), or a
+// huge number if the character count overflows its field.
+func qrSegmentBits(mode int, data string, version int) int {
+	cc := qrCountBits(mode, version)
+	if len(data) >= 1<<cc { // numeric/alphanumeric are ASCII, so bytes == chars
+		return 1 << 30
+	}
+	n := len(data)
+	var payload int
+	switch mode {
+	case qrModeNumeric:
+		payload = n/3*10 + [...]int{0, 4, 7}[n%3]
+	case qrModeAlphanumeric:
+		payload = n/2*11 + n%2*6
+	default:
+		payload = n * 8
+	}
+	return 4 + cc + payload
+}
+
+func qrAppendPayload(buf *qrBitBuffer, mode int, data string) {
+	switch mode {
+	case qrModeNumeric:
+		for i := 0; i < len(data); i += 3 {
+			chunk := data[i:min(i+3, len(data))]
+			v := 0
+			for _, c := range []byte(chunk) {
+				v = v*10 + int(c-'0')
+			}
+			buf.put(v, len(chunk)*3+1)
+		}
+	case qrModeAlphanumeric:
+		for i := 0; i < len(data); i += 2 {
+			v := strings.IndexByte(qrAlphanumericChars, data[i])
+			if i+1 < len(data) {
+				buf.put(v*45+strings.IndexByte(qrAlphanumericChars, data[i+1]), 11)
+			} else {
+				buf.put(v, 6)
+			}
+		}
+	default:
+		for i := 0; i < len(data); i++ {
+			buf.put(int(data[i]), 8)
+		}
+	}
+}
 
-	// Alignment patterns (for version >= 2)
+// qrRawDataModules is the number of modules available for codewords
+// (data + EC + remainder bits) once function patterns are placed.
+func qrRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
 	if version >= 2 {
-		positions := alignmentPositions(version)
-		for _, r := range positions {
-			for _, c := range positions {
-				// Skip if overlapping with finder
-				if reserved[r][c] {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36 // two version info blocks
+		}
+	}
+	return result
+}
+
+// qrDataCodewords is the number of data codewords for a version and level.
+func qrDataCodewords(version int, level qrECLevel) int {
+	return qrRawDataModules(version)/8 -
+		qrECCodewordsPerBlock[level][version]*qrECBlocks[level][version]
+}
+
+// qrAddECAndInterleave splits data into blocks, appends Reed-Solomon EC to
+// each, and interleaves them column-wise as the symbol expects. The first
+// blocks are one data codeword shorter when the split isn't even.
+func qrAddECAndInterleave(data []byte, version int, level qrECLevel) []byte {
+	numBlocks := qrECBlocks[level][version]
+	ecLen := qrECCodewordsPerBlock[level][version]
+	rawCodewords := qrRawDataModules(version) / 8
+	numShort := numBlocks - rawCodewords%numBlocks
+	shortLen := rawCodewords / numBlocks
+
+	gen := rsGeneratorPoly(ecLen)
+	dataBlocks := make([][]byte, numBlocks)
+	ecBlocks := make([][]byte, numBlocks)
+	k := 0
+	for i := range dataBlocks {
+		n := shortLen - ecLen
+		if i >= numShort {
+			n++
+		}
+		dataBlocks[i] = data[k : k+n]
+		ecBlocks[i] = rsRemainder(dataBlocks[i], gen)
+		k += n
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := 0; i <= shortLen-ecLen; i++ {
+		for _, b := range dataBlocks {
+			if i < len(b) {
+				result = append(result, b[i])
+			}
+		}
+	}
+	for i := 0; i < ecLen; i++ {
+		for _, b := range ecBlocks {
+			result = append(result, b[i])
+		}
+	}
+	return result
+}
+
+// qrMatrix is a symbol under construction. function marks modules that
+// belong to patterns and format/version info, which masks skip.
+type qrMatrix struct {
+	version  int
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func newQRMatrix(version int) *qrMatrix {
+	n := 17 + version*4
+	m := &qrMatrix{version: version, size: n, modules: make([][]bool, n), function: make([][]bool, n)}
+	for i := range m.modules {
+		m.modules[i] = make([]bool, n)
+		m.function[i] = make([]bool, n)
+	}
+	return m
+}
+
+func (m *qrMatrix) setFunction(row, col int, dark bool) {
+	m.modules[row][col] = dark
+	m.function[row][col] = true
+}
+
+func (m *qrMatrix) drawFunctionPatterns(level qrECLevel) {
+	n := m.size
+
+	// Timing patterns
+	for i := 0; i < n; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their light separators
+	for _, c := range [][2]int{{3, 3}, {3, n - 4}, {n - 4, 3}} {
+		for dr := -4; dr <= 4; dr++ {
+			for dc := -4; dc <= 4; dc++ {
+				r, col := c[0]+dr, c[1]+dc
+				if r < 0 || r >= n || col < 0 || col >= n {
 					continue
 				}
-				for dr := -2; dr <= 2; dr++ {
-					for dc := -2; dc <= 2; dc++ {
-						dark := dr == -2 || dr == 2 || dc == -2 || dc == 2 || (dr == 0 && dc == 0)
-						modules[r+dr][c+dc] = dark
-						reserved[r+dr][c+dc] = true
-					}
+				dist := max(abs(dr), abs(dc))
+				m.setFunction(r, col, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	// Alignment patterns, except where they would overlap a finder
+	pos := alignmentPositions(m.version)
+	last := len(pos) - 1
+	for i, r := range pos {
+		for j, c := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dr := -2; dr <= 2; dr++ {
+				for dc := -2; dc <= 2; dc++ {
+					m.setFunction(r+dr, c+dc, max(abs(dr), abs(dc)) != 1)
 				}
 			}
 		}
 	}
 
-	// Reserve format info areas
-	for i := 0; i < 8; i++ {
-		reserved[8][i] = true
-		reserved[8][n-1-i] = true
-		reserved[i][8] = true
-		reserved[n-1-i][8] = true
+	// Reserve format info (written per mask later) and draw version info.
+	m.drawFormatBits(level, 0)
+	if m.version >= 7 {
+		bits := qrVersionBits(m.version)
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 == 1
+			a, b := n-11+i%3, i/3
+			m.setFunction(b, a, dark)
+			m.setFunction(a, b, dark)
+		}
 	}
-	reserved[8][8] = true
+}
 
-	// Encode data
-	buf := &qrBitBuffer{}
-	// Byte mode indicator
-	buf.put(0b0100, 4)
-	// Character count (8 bits for v1-9, 16 for v10+)
-	if version <= 9 {
-		buf.put(len(dataBytes), 8)
-	} else {
-		buf.put(len(dataBytes), 16)
-	}
-	// Data bytes
-	for _, b := range dataBytes {
-		buf.put(int(b), 8)
-	}
-	// Terminator
-	buf.put(0, 4)
-
-	// Pad to byte boundary
-	for buf.length()%8 != 0 {
-		buf.put(0, 1)
+// drawFormatBits writes both copies of the 15-bit format info, plus the
+// always-dark module beside the bottom-left finder.
+func (m *qrMatrix) drawFormatBits(level qrECLevel, mask int) {
+	bits := getFormatBits(level.formatBits(), mask)
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+	n := m.size
+
+	for i := 0; i <= 5; i++ {
+		m.setFunction(i, 8, bit(i))
+	}
+	m.setFunction(7, 8, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(8, 7, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(8, 14-i, bit(i))
 	}
 
-	// Total data codewords for version + EC level M
-	totalCodewords := totalDataCodewords(version)
-	// Pad with alternating 0xEC, 0x11
-	padBytes := []int{0xEC, 0x11}
-	pi := 0
-	for buf.length()/8 < totalCodewords {
-		buf.put(padBytes[pi%2], 8)
-		pi++
+	for i := 0; i < 8; i++ {
+		m.setFunction(8, n-1-i, bit(i))
 	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(n-15+i, 8, bit(i))
+	}
+	m.setFunction(n-8, 8, true)
+}
 
-	// Convert bits to bytes
-	codewords := make([]byte, totalCodewords)
-	for i := range codewords {
-		for bit := 0; bit < 8; bit++ {
-			if buf.bits[i*8+bit] {
-				codewords[i] |= 1 << (7 - bit)
+// drawCodewords places the final codeword sequence in the two-column
+// zigzag, right to left, skipping the vertical timing column. Leftover
+// remainder bits stay light.
+func (m *qrMatrix) drawCodewords(data []byte) {
+	n := m.size
+	i := 0
+	for right := n - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < n; vert++ {
+			row := vert
+			if upward {
+				row = n - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				col := right - j
+				if m.function[row][col] || i >= len(data)*8 {
+					continue
+				}
+				m.modules[row][col] = (data[i>>3]>>(7-i&7))&1 == 1
+				i++
 			}
 		}
 	}
+}
 
-	// Generate error correction
-	ecCodewords := generateEC(codewords, version)
-
-	// Combine data + EC
-	allBytes := append(codewords, ecCodewords...)
+// applyMask XORs the data modules with mask pattern 0–7; applying the same
+// mask twice restores the original.
+func (m *qrMatrix) applyMask(mask int) {
+	for r := 0; r < m.size; r++ {
+		for c := 0; c < m.size; c++ {
+			if !m.function[r][c] && qrMaskBit(mask, r, c) {
+				m.modules[r][c] = !m.modules[r][c]
+			}
+		}
+	}
+}
 
-	// Place data bits
-	allBits := &qrBitBuffer{}
-	for _, b := range allBytes {
-		allBits.put(int(b), 8)
+func qrMaskBit(mask, r, c int) bool {
+	switch mask {
+	case 0:
+		return (r+c)%2 == 0
+	case 1:
+		return r%2 == 0
+	case 2:
+		return c%3 == 0
+	case 3:
+		return (r+c)%3 == 0
+	case 4:
+		return (r/2+c/3)%2 == 0
+	case 5:
+		return r*c%2+r*c%3 == 0
+	case 6:
+		return (r*c%2+r*c%3)%2 == 0
+	default:
+		return ((r+c)%2+r*c%3)%2 == 0
 	}
+}
 
-	bitIdx := 0
-	// Traverse in the zigzag pattern
-	for col := n - 1; col >= 0; col -= 2 {
-		if col == 6 {
-			col = 5 // skip timing column
+// penalty scores the symbol with the four rules from ISO/IEC 18004 §7.8.3:
+// long runs, 2×2 blocks, finder-like patterns and dark/light imbalance.
+func (m *qrMatrix) penalty() int {
+	n := m.size
+	at := func(r, c int, vertical bool) bool {
+		if vertical {
+			return m.modules[c][r]
 		}
-		for row := 0; row < n; row++ {
-			for c := 0; c < 2; c++ {
-				cc := col - c
-				actualRow := row
-				if ((col+1)/2)%2 == 0 {
-					actualRow = n - 1 - row
-				}
-				if cc < 0 || cc >= n || actualRow < 0 || actualRow >= n {
+		return m.modules[r][c]
+	}
+
+	total := 0
+	finderA := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderB := []bool{false, false, false, false, true, false, true, true, true, false, true}
+	for _, vertical := range []bool{false, true} {
+		for r := 0; r < n; r++ {
+			// Rule 1: runs of five or more same-colour modules.
+			run := 1
+			for c := 1; c <= n; c++ {
+				if c < n && at(r, c, vertical) == at(r, c-1, vertical) {
+					run++
 					continue
 				}
-				if reserved[actualRow][cc] {
-					continue
+				if run >= 5 {
+					total += 3 + run - 5
+				}
+				run = 1
+			}
+			// Rule 3: 1:1:3:1:1 finder-like pattern with four light modules
+			// on one side.
+			for c := 0; c+11 <= n; c++ {
+				matchA, matchB := true, true
+				for k := 0; k < 11; k++ {
+					v := at(r, c+k, vertical)
+					matchA = matchA && v == finderA[k]
+					matchB = matchB && v == finderB[k]
+				}
+				if matchA {
+					total += 40
 				}
-				if bitIdx < allBits.length() {
-					modules[actualRow][cc] = allBits.bits[bitIdx]
-					bitIdx++
+				if matchB {
+					total += 40
 				}
 			}
 		}
 	}
 
-	// Apply mask (mask 0 for simplicity: (row + col) % 2 == 0)
+	// Rule 2: 2×2 blocks of one colour.
+	dark := 0
 	for r := 0; r < n; r++ {
 		for c := 0; c < n; c++ {
-			if !reserved[r][c] && (r+c)%2 == 0 {
-				modules[r][c] = !modules[r][c]
+			if m.modules[r][c] {
+				dark++
+			}
+			if r+1 < n && c+1 < n {
+				v := m.modules[r][c]
+				if m.modules[r][c+1] == v && m.modules[r+1][c] == v && m.modules[r+1][c+1] == v {
+					total += 3
+				}
 			}
 		}
 	}
 
-	// Place format info (mask 0, EC level M = 0b00)
-	formatBits := getFormatBits(0b00, 0) // EC M, mask 0
-	for i := 0; i < 15; i++ {
-		bit := (formatBits >> (14 - i)) & 1 == 1
-		// Around top-left finder
-		if i < 6 {
-			modules[8][i] = bit
-		} else if i == 6 {
-			modules[8][7] = bit
-		} else if i == 7 {
-			modules[8][8] = bit
-		} else if i == 8 {
-			modules[7][8] = bit
-		} else {
-			modules[14-i][8] = bit
-		}
-		// Around top-right and bottom-left finders
-		if i < 8 {
-			modules[n-1-i][8] = bit
-		} else {
-			modules[8][n-15+i] = bit
-		}
+	// Rule 4: 10 points per 5% the dark ratio strays from 50%.
+	cells := n * n
+	k := (abs(dark*20-cells*10)+cells-1)/cells - 1
+	if k > 0 {
+		total += k * 10
 	}
+	return total
+}
 
-	return modules
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
 }
 
+// alignmentPositions returns the row/column centres of alignment patterns.
+// Centres are evenly spaced (by an even step) back from size-7, with the
+// first always at 6.
 func alignmentPositions(version int) []int {
 	if version < 2 {
 		return nil
 	}
-	// Alignment pattern positions for versions 2-10
-	table := [][]int{
-		nil,         // v0 unused
-		nil,         // v1
-		{6, 18},     // v2
-		{6, 22},     // v3
-		{6, 26},     // v4
-		{6, 30},     // v5
-		{6, 34},     // v6
-		{6, 22, 38}, // v7
-		{6, 24, 42}, // v8
-		{6, 26, 46}, // v9
-		{6, 28, 50}, // v10
-	}
-	if version < len(table) {
-		return table[version]
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	pos := make([]int, numAlign)
+	pos[0] = 6
+	for i, p := numAlign-1, 17+version*4-7; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
 	}
-	return nil
+	return pos
 }
 
-func totalDataCodewords(version int) int {
-	// Total data codewords (EC level M) for versions 1-10
-	table := []int{0, 16, 28, 44, 64, 86, 108, 124, 154, 182, 216}
-	if version < len(table) {
-		return table[version]
-	}
-	return 0
-}
-
-func ecCodewordCount(version int) int {
-	// EC codewords per block (EC level M) for versions 1-10
-	table := []int{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26}
-	if version < len(table) {
-		return table[version]
-	}
-	return 0
-}
-
-// generateEC generates error correction codewords using Reed-Solomon.
-func generateEC(data []byte, version int) []byte {
-	ecCount := ecCodewordCount(version)
-	gen := rsGeneratorPoly(ecCount)
-
-	// Polynomial division
-	result := make([]byte, ecCount)
-	copy(result, data)
-	if len(data) > ecCount {
-		result = make([]byte, len(data)+ecCount)
-		copy(result, data)
-	} else {
-		result = make([]byte, ecCount)
-	}
-
-	work := make([]byte, len(data)+ecCount)
+// rsRemainder returns the Reed-Solomon EC codewords for data.
+func rsRemainder(data, gen []byte) []byte {
+	degree := len(gen) - 1
+	work := make([]byte, len(data)+degree)
 	copy(work, data)
-
 	for i := 0; i < len(data); i++ {
 		coeff := work[i]
 		if coeff != 0 {
@@ -339,8 +592,7 @@ func generateEC(data []byte, version int) []byte {
 			}
 		}
 	}
-
-	return work[len(data) : len(data)+ecCount]
+	return work[len(data):]
 }
 
 // GF(256) operations for Reed-Solomon
@@ -383,16 +635,10 @@ func rsGeneratorPoly(degree int) []byte {
 	return gen
 }
 
+// getFormatBits returns the 15-bit format info: 2 bits of EC level and 3
+// of mask, a BCH(15,5) remainder, XORed with 0x5412.
 func getFormatBits(ecLevel, mask int) int {
-	// Format info: 5 bits of data + 10 bits of BCH error correction
 	data := (ecLevel << 3) | mask
-	rem := data
-	for i := 0; i < 10; i++ {
-		if rem&(1<<(14-i)) != 0 {
-			rem ^= 0x537 << (4 - i)
-		}
-	}
-	// Actually, compute BCH properly
 	bits := data << 10
 	g := 0x537 // generator polynomial
 	for i := 14; i >= 10; i-- {
@@ -405,6 +651,19 @@ func getFormatBits(ecLevel, mask int) int {
 	return result
 }
 
+// qrVersionBits returns the 18-bit version info (v7+): 6 bits of version
+// and a BCH(18,6) remainder.
+func qrVersionBits(version int) int {
+	bits := version << 12
+	g := 0x1F25
+	for i := 17; i >= 12; i-- {
+		if bits&(1<<i) != 0 {
+			bits ^= g << (i - 12)
+		}
+	}
+	return version<<12 | bits
+}
+
 // generateTokenIconSVG creates a deterministic colored circle SVG for tokens without bundled icons.
 func generateTokenIconSVG(ticker string) string {
 	// Deterministic hue from ticker
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	// Worked examples from ISO/IEC 18004 Annex C/D.
+	if got := getFormatBits(qrECMedium.formatBits(), 0); got != 0b101010000010010 {
+		t.Errorf("format M/0 = %015b", got)
+	}
+	if got := getFormatBits(qrECLow.formatBits(), 4); got != 0b110011000101111 {
+		t.Errorf("format L/4 = %015b", got)
+	}
+	if got := qrVersionBits(7); got != 0b000111110010010100 {
+		t.Errorf("version 7 = %018b", got)
+	}
+}
+
+func TestQRTables(t *testing.T) {
+	for _, tt := range []struct {
+		version int
+		level   qrECLevel
+		want    int
+	}{
+		{1, qrECLow, 19}, {1, qrECMedium, 16}, {1, qrECQuartile, 13}, {1, qrECHigh, 9},
+		{10, qrECMedium, 216}, {25, qrECQuartile, 718},
+		{40, qrECLow, 2956}, {40, qrECMedium, 2334}, {40, qrECQuartile, 1666}, {40, qrECHigh, 1276},
+	} {
+		if got := qrDataCodewords(tt.version, tt.level); got != tt.want {
+			t.Errorf("qrDataCodewords(%d, %d) = %d, want %d", tt.version, tt.level, got, tt.want)
+		}
+	}
+	for v, want := range map[int][]int{
+		1: nil, 2: {6, 18}, 7: {6, 22, 38}, 32: {6, 34, 60, 86, 112, 138}, 40: {6, 30, 58, 86, 114, 142, 170},
+	} {
+		if got := alignmentPositions(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("alignmentPositions(%d) = %v, want %v", v, got, want)
+		}
+	}
+	// The drawn function patterns must leave exactly the data area the
+	// capacity formula assumes.
+	for v := 1; v <= 40; v++ {
+		m := newQRMatrix(v)
+		m.drawFunctionPatterns(qrECMedium)
+		free := 0
+		for _, row := range m.function {
+			for _, f := range row {
+				if !f {
+					free++
+				}
+			}
+		}
+		if free != qrRawDataModules(v) {
+			t.Errorf("version %d: %d data modules, want %d", v, free, qrRawDataModules(v))
+		}
+	}
+}
+
+func TestQRReedSolomon(t *testing.T) {
+	// "HELLO WORLD" at 1-M.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsGeneratorPoly(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+func TestQRModeSelection(t *testing.T) {
+	for in, want := range map[string]int{
+		"0123456789":                         qrModeNumeric,
+		"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW": qrModeAlphanumeric,
+		"bitcoin:bc1q?amount=1":              qrModeByte,
+		"":                                   qrModeByte,
+	} {
+		if got := qrSelectMode(in); got != want {
+			t.Errorf("qrSelectMode(%q) = %04b, want %04b", in, got, want)
+		}
+	}
+}
+
+// TestQRRoundTrip reads symbols back — format info, mask, zigzag order,
+// block de-interleaving, RS check, segment parse — across sizes and modes.
+func TestQRRoundTrip(t *testing.T) {
+	inputs := []string{
+		"1",
+		"01234567",
+		"HELLO WORLD",
+		"0x000000000000000000000000000000000000dEaD",
+		"ethereum:0xa0b86991c6218b36c1d19d4a2e9eb10ce3606eb48@1/transfer?address=0x000000000000000000000000000000000000dEaD&uint256=25500000",
+		strings.Repeat("31415926535897932384626433832795", 40),
+		strings.Repeat("ZERO $%*+-./: ", 90),
+		strings.Repeat("solana:So11111111111111111111111111111111111111112?memo=x ", 30),
+		strings.Repeat("\x00\xff", 1100),
+	}
+	for _, in := range inputs {
+		for level := qrECLow; level <= qrECHigh; level++ {
+			modules := encodeQRLevel(in, level)
+			if modules == nil {
+				if qrSegmentBits(qrSelectMode(in), in, 40) <= qrDataCodewords(40, level)*8 {
+					t.Errorf("encodeQRLevel(%.20q, %d) = nil but it fits version 40", in, level)
+				}
+				continue
+			}
+			got, gotLevel, err := readQR(modules)
+			if err != nil {
+				t.Errorf("read %.20q at level %d: %v", in, level, err)
+				continue
+			}
+			if got != in {
+				t.Errorf("read back %.20q, want %.20q", got, in)
+			}
+			if gotLevel < level {
+				t.Errorf("%.20q: EC level %d below requested %d", in, gotLevel, level)
+			}
+		}
+	}
+}
+
+func TestQRLongPayloads(t *testing.T) {
+	if m := encodeQR(strings.Repeat("a", 2331)); m == nil || len(m) != 177 {
+		t.Error("2331 bytes should fit version 40-M")
+	}
+	if encodeQR(strings.Repeat("a", 2332)) != nil {
+		t.Error("2332 bytes should not fit version 40-M")
+	}
+	if m := encodeQR(strings.Repeat("7", 7089)); m != nil {
+		t.Error("7089 digits only fit at level L")
+	}
+	if m := encodeQRLevel(strings.Repeat("7", 7089), qrECLow); m == nil {
+		t.Error("7089 digits should fit version 40-L")
+	}
+}
+
+// readQR is a minimal decoder for symbols produced by encodeQRLevel.
+func readQR(modules [][]bool) (string, qrECLevel, error) {
+	n := len(modules)
+	version := (n - 17) / 4
+
+	bit := func(r, c int) int {
+		if modules[r][c] {
+			return 1
+		}
+		return 0
+	}
+	var fmtA, fmtB int
+	for i := 0; i <= 5; i++ {
+		fmtA |= bit(i, 8) << i
+	}
+	fmtA |= bit(7, 8)<<6 | bit(8, 8)<<7 | bit(8, 7)<<8
+	for i := 9; i < 15; i++ {
+		fmtA |= bit(8, 14-i) << i
+	}
+	for i := 0; i < 8; i++ {
+		fmtB |= bit(8, n-1-i) << i
+	}
+	for i := 8; i < 15; i++ {
+		fmtB |= bit(n-15+i, 8) << i
+	}
+	if fmtA != fmtB {
+		return "", 0, fmt.Errorf("format copies differ: %015b vs %015b", fmtA, fmtB)
+	}
+	info := (fmtA ^ 0x5412) >> 10
+	mask := info & 7
+	var level qrECLevel = -1
+	for l := qrECLow; l <= qrECHigh; l++ {
+		if l.formatBits() == info>>3 {
+			level = l
+		}
+	}
+	if getFormatBits(info>>3, mask) != fmtA {
+		return "", 0, fmt.Errorf("format BCH mismatch")
+	}
+	if version >= 7 {
+		var vb int
+		for i := 0; i < 18; i++ {
+			vb |= bit(i/3, n-11+i%3) << i
+		}
+		if vb != qrVersionBits(version) {
+			return "", 0, fmt.Errorf("version info %018b", vb)
+		}
+	}
+
+	ref := newQRMatrix(version)
+	ref.drawFunctionPatterns(level)
+	var raw []byte
+	var cur byte
+	nbits := 0
+	for right := n - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < n; vert++ {
+			row := vert
+			if (right+1)&2 == 0 {
+				row = n - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				col := right - j
+				if ref.function[row][col] {
+					continue
+				}
+				v := modules[row][col] != qrMaskBit(mask, row, col)
+				cur <<= 1
+				if v {
+					cur |= 1
+				}
+				if nbits++; nbits%8 == 0 {
+					raw = append(raw, cur)
+					cur = 0
+				}
+			}
+		}
+	}
+
+	numBlocks := qrECBlocks[level][version]
+	ecLen := qrECCodewordsPerBlock[level][version]
+	rawCodewords := qrRawDataModules(version) / 8
+	numShort := numBlocks - rawCodewords%numBlocks
+	shortData := rawCodewords/numBlocks - ecLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for b := range blocks {
+			if i < shortData || b >= numShort {
+				blocks[b] = append(blocks[b], raw[k])
+				k++
+			}
+		}
+	}
+	ecs := make([][]byte, numBlocks)
+	for i := 0; i < ecLen; i++ {
+		for b := range ecs {
+			ecs[b] = append(ecs[b], raw[k])
+			k++
+		}
+	}
+	gen := rsGeneratorPoly(ecLen)
+	var data []byte
+	for b := range blocks {
+		if !bytes.Equal(rsRemainder(blocks[b], gen), ecs[b]) {
+			return "", 0, fmt.Errorf("block %d fails RS check", b)
+		}
+		data = append(data, blocks[b]...)
+	}
+
+	pos := 0
+	read := func(w int) int {
+		v := 0
+		for i := 0; i < w; i++ {
+			v = v<<1 | int(data[pos>>3]>>(7-pos&7)&1)
+			pos++
+		}
+		return v
+	}
+	mode := read(4)
+	count := read(qrCountBits(mode, version))
+	var out []byte
+	switch mode {
+	case qrModeNumeric:
+		for ; count >= 3; count -= 3 {
+			out = fmt.Appendf(out, "%03d", read(10))
+		}
+		if count == 2 {
+			out = fmt.Appendf(out, "%02d", read(7))
+		} else if count == 1 {
+			out = fmt.Appendf(out, "%d", read(4))
+		}
+	case qrModeAlphanumeric:
+		for ; count >= 2; count -= 2 {
+			v := read(11)
+			out = append(out, qrAlphanumericChars[v/45], qrAlphanumericChars[v%45])
+		}
+		if count == 1 {
+			out = append(out, qrAlphanumericChars[read(6)])
+		}
+	case qrModeByte:
+		for ; count > 0; count-- {
+			out = append(out, byte(read(8)))
+		}
+	default:
+		return "", 0, fmt.Errorf("unexpected mode %04b", mode)
+	}
+	return string(out), level, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// 1Click signs every real quote so the deposit address can be tied back to
+// the service. A compromised upstream or an on-path proxy could otherwise
+// swap in its own deposit address, so placeOrder and the Telegram order
+// paths check the signature before a deposit address is ever shown.
+//
+// Signature:   "ed25519:<base58 64-byte signature>"
+// Signed data: SHA-256 of the quote response JSON with the "signature"
+//              field removed, re-serialized with sorted keys and no
+//              whitespace (see quoteSigningPayload).
+//
+// The signed-data form is this server's canonicalization, which the
+// simulator also signs; it is not taken from a published 1Click
+// specification. Until the real form and signer key are confirmed there is
+// no built-in key: the key comes from NEAR_INTENTS_SIGNER_KEY
+// ("ed25519:<base58>"), and without it quotes are accepted, recorded as
+// unchecked and shown as such on the order page. The simulator uses its
+// own key.
+
+// quoteSignerKey is the 1Click quote signer; nil disables verification.
+var quoteSignerKey ed25519.PublicKey
+
+// Verification results recorded in OrderData.QuoteSig.
+const (
+	quoteSigVerified  = "verified"
+	quoteSigUnchecked = "unchecked"
+)
+
+func initQuoteSigner() {
+	v := os.Getenv("NEAR_INTENTS_SIGNER_KEY")
+	if v == "" {
+		if sim, ok := intents.(*intentsSimulator); ok {
+			quoteSignerKey = sim.publicKey()
+			return
+		}
+		quoteSignerKey = nil
+		log.Println("WARNING: NEAR_INTENTS_SIGNER_KEY not set — quote signatures will not be verified; orders are marked unchecked.")
+		return
+	}
+	key, err := parseNearEd25519(v, ed25519.PublicKeySize)
+	if err != nil {
+		log.Fatal("NEAR_INTENTS_SIGNER_KEY must be an ed25519:<base58> public key: ", err)
+	}
+	quoteSignerKey = key
+}
+
+// parseNearEd25519 decodes NEAR's "ed25519:<base58>" key/signature form.
+func parseNearEd25519(s string, size int) ([]byte, error) {
+	body, ok := strings.CutPrefix(s, "ed25519:")
+	if !ok {
+		return nil, fmt.Errorf("missing ed25519: prefix")
+	}
+	raw, ok := base58Decode(body, base58BTC)
+	if !ok {
+		return nil, fmt.Errorf("invalid base58")
+	}
+	// base58Decode drops leading zero bytes beyond the encoded ones; pad back.
+	if len(raw) < size {
+		raw = append(make([]byte, size-len(raw)), raw...)
+	}
+	if len(raw) != size {
+		return nil, fmt.Errorf("want %d bytes, got %d", size, len(raw))
+	}
+	return raw, nil
+}
+
+// quoteSigningPayload returns the digest 1Click signs for a quote response.
+func quoteSigningPayload(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("parse quote response: %w", err)
+	}
+	delete(obj, "signature")
+
+	// encoding/json sorts map keys; keep '<', '>' and '&' as-is.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return sum[:], nil
+}
+
+// checkQuoteSignature verifies a quote response. It returns the status to
+// record in the order, or an error if the quote must not be used.
+func checkQuoteSignature(resp *QuoteResponse) (string, error) {
+	if quoteSignerKey == nil {
+		quoteSignatureChecks.inc(quoteSigUnchecked)
+		return quoteSigUnchecked, nil
+	}
+	status, err := verifyQuoteSignature(resp)
+	if err != nil {
+		quoteSignatureChecks.inc("failed")
+		log.Printf("quote signature check failed: %v", err)
+		return "", err
+	}
+	quoteSignatureChecks.inc(status)
+	return status, nil
+}
+
+func verifyQuoteSignature(resp *QuoteResponse) (string, error) {
+	if resp.Signature == "" {
+		return "", fmt.Errorf("quote is unsigned")
+	}
+	sig, err := parseNearEd25519(resp.Signature, ed25519.SignatureSize)
+	if err != nil {
+		return "", fmt.Errorf("malformed signature: %w", err)
+	}
+	digest, err := quoteSigningPayload(resp.RawJSON)
+	if err != nil {
+		return "", err
+	}
+	if !ed25519.Verify(quoteSignerKey, digest, sig) {
+		return "", fmt.Errorf("signature does not match the quote")
+	}
+	return quoteSigVerified, nil
+}
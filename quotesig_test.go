@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withQuoteSigner installs a fresh signer key for one test.
+func withQuoteSigner(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := quoteSignerKey
+	quoteSignerKey = pub
+	t.Cleanup(func() { quoteSignerKey = saved })
+	return priv
+}
+
+// signedQuote returns a quote response body signed with priv.
+func signedQuote(t *testing.T, priv ed25519.PrivateKey, depositAddr string) string {
+	t.Helper()
+	body := fmt.Sprintf(`{"correlationId":"3f2a9c1e-7b4d-4e8a-9c2f-1a2b3c4d5e6f","timestamp":"2026-03-01T12:00:00.000Z",`+
+		`"quoteRequest":{"swapType":"EXACT_INPUT","amount":"1000000"},`+
+		`"quote":{"depositAddress":%q,"amountIn":"1000000","amountInFormatted":"1.0","amountOut":"999000","amountOutFormatted":"0.999","deadline":"2026-03-01T13:00:00.000Z","timeEstimate":20}}`, depositAddr)
+	digest, err := quoteSigningPayload([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := "ed25519:" + base58Encode(ed25519.Sign(priv, digest), base58BTC)
+	return strings.TrimSuffix(body, "}") + `,"signature":"` + sig + `"}`
+}
+
+func parseQuote(t *testing.T, body string) *QuoteResponse {
+	t.Helper()
+	var resp QuoteResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	resp.RawJSON = []byte(body)
+	return &resp
+}
+
+func TestQuoteSignature(t *testing.T) {
+	priv := withQuoteSigner(t)
+	body := signedQuote(t, priv, "0x000000000000000000000000000000000000dEaD")
+
+	if status, err := checkQuoteSignature(parseQuote(t, body)); err != nil || status != quoteSigVerified {
+		t.Fatalf("valid quote: status %q, err %v", status, err)
+	}
+
+	// Re-serialized with different key order and whitespace: still valid.
+	var obj map[string]interface{}
+	json.Unmarshal([]byte(body), &obj)
+	pretty, _ := json.MarshalIndent(obj, "", "  ")
+	if _, err := checkQuoteSignature(parseQuote(t, string(pretty))); err != nil {
+		t.Errorf("reformatted quote should verify: %v", err)
+	}
+
+	swapped := strings.Replace(body, "dEaD", "bEEF", 1)
+	if _, err := checkQuoteSignature(parseQuote(t, swapped)); err == nil {
+		t.Error("quote with a swapped deposit address must fail")
+	}
+
+	for _, sig := range []string{"", "ed25519:", "ed25519:0OIl", "secp256k1:abc"} {
+		resp := parseQuote(t, body)
+		resp.Signature = sig
+		if _, err := checkQuoteSignature(resp); err == nil {
+			t.Errorf("signature %q should be rejected", sig)
+		}
+	}
+
+	withQuoteSigner(t) // different key
+	if _, err := checkQuoteSignature(parseQuote(t, body)); err == nil {
+		t.Error("quote signed by another key must fail")
+	}
+
+	quoteSignerKey = nil
+	if status, err := checkQuoteSignature(parseQuote(t, swapped)); err != nil || status != quoteSigUnchecked {
+		t.Errorf("without a signer key: status %q, err %v", status, err)
+	}
+}
+
+func TestPlaceOrderVerifiesQuote(t *testing.T) {
+	priv := withQuoteSigner(t)
+	quoteBody := signedQuote(t, priv, "0x000000000000000000000000000000000000dEaD")
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v0/quote":
+			fmt.Fprint(w, quoteBody)
+		case strings.HasPrefix(r.URL.Path, "/v0/status"):
+			fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+		default:
+			fmt.Fprint(w, `{"withdrawals":[]}`)
+		}
+	})
+
+	in := &swapInput{FromTicker: "ETH", FromNet: "eth", ToTicker: "USDC", ToNet: "eth",
+		Recipient: "0x000000000000000000000000000000000000dEaD", RefundAddr: "0x000000000000000000000000000000000000dEaD"}
+	from := &TokenInfo{DefuseAssetID: "nep141:eth.omft.near", Ticker: "ETH", Decimals: 18}
+	to := &TokenInfo{DefuseAssetID: "nep141:usdc.omft.near", Ticker: "USDC", Decimals: 6}
+
+	order, token, serr := placeOrder(context.Background(), in, from, to, "EXACT_INPUT", "1000000", 100, "", "")
+	if serr != nil {
+		t.Fatalf("placeOrder: %+v", serr)
+	}
+	if order.QuoteSig != quoteSigVerified {
+		t.Errorf("QuoteSig = %q", order.QuoteSig)
+	}
+	w := httptest.NewRecorder()
+	handleOrder(w, httptest.NewRequest("GET", "/order/"+token, nil))
+	if !strings.Contains(w.Body.String(), `<span class="text-success">Verified`) {
+		t.Error("order page should show the verified quote signature")
+	}
+
+	quoteBody = strings.Replace(quoteBody, "dEaD", "bEEF", 1)
+	_, _, serr = placeOrder(context.Background(), in, from, to, "EXACT_INPUT", "1000000", 100, "", "")
+	if serr == nil || serr.Code != "quote_unverified" {
+		t.Fatalf("tampered quote: got %+v, want quote_unverified", serr)
+	}
+}
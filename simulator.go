@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The simulator is an offline IntentsClient for development and demos,
+// enabled with NEAR_INTENTS_SIMULATOR=1. It never touches the network:
+//
+//   - Tokens: a fixed fixture list with static prices.
+//   - Quotes: priced from the fixture with a 0.1% spread. The deposit
+//     address, memo and correlation ID are derived from the request, so the
+//     same request always gets the same answer. Non-dry quotes are signed with
+//     a fixed simulator key, which initQuoteSigner trusts when no
+//     NEAR_INTENTS_SIGNER_KEY is set.
+//   - Status: scripted by time since the quote, one stage per step:
+//     PENDING_DEPOSIT → KNOWN_DEPOSIT_TX → PROCESSING → SUCCESS.
+//     Amounts whose last nonzero atomic digit is 7 end in REFUNDED instead,
+//     so both outcomes can be exercised on purpose.
+//
+// Orders live in memory only; after a restart old deposit addresses are
+// unknown, as they would be to an API that never issued them.
+
+const simulatorSpreadBPS = 10
+
+// simulatorTokens is the fixture token list served by the simulator.
+var simulatorTokens = []TokenInfo{
+	{DefuseAssetID: "nep141:eth.omft.near", Symbol: "ETH", Name: "Ethereum", Decimals: 18, ChainName: "eth", Price: 2500},
+	{DefuseAssetID: "nep141:eth-0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48.omft.near", Symbol: "USDC", Name: "USD Coin", Decimals: 6, ChainName: "eth", Price: 1, ContractAddress: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"},
+	{DefuseAssetID: "nep141:eth-0xdac17f958d2ee523a2206206994597c13d831ec7.omft.near", Symbol: "USDT", Name: "Tether USD", Decimals: 6, ChainName: "eth", Price: 1, ContractAddress: "0xdAC17F958D2ee523a2206206994597C13D831ec7"},
+	{DefuseAssetID: "nep141:base.omft.near", Symbol: "ETH", Name: "Ethereum", Decimals: 18, ChainName: "base", Price: 2500},
+	{DefuseAssetID: "nep141:base-0x833589fcd6edb6e08f4c7c32d4f71b54bda02913.omft.near", Symbol: "USDC", Name: "USD Coin", Decimals: 6, ChainName: "base", Price: 1, ContractAddress: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"},
+	{DefuseAssetID: "nep141:btc.omft.near", Symbol: "BTC", Name: "Bitcoin", Decimals: 8, ChainName: "btc", Price: 60000},
+	{DefuseAssetID: "nep141:sol.omft.near", Symbol: "SOL", Name: "Solana", Decimals: 9, ChainName: "sol", Price: 150},
+	{DefuseAssetID: "nep141:sol-5ce3bf3a31af18be40ba30f721101b4341690186.omft.near", Symbol: "USDC", Name: "USD Coin", Decimals: 6, ChainName: "sol", Price: 1, ContractAddress: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"},
+	{DefuseAssetID: "nep141:wrap.near", Symbol: "NEAR", Name: "NEAR", Decimals: 24, ChainName: "near", Price: 5},
+	{DefuseAssetID: "nep141:doge.omft.near", Symbol: "DOGE", Name: "Dogecoin", Decimals: 8, ChainName: "doge", Price: 0.15},
+	{DefuseAssetID: "nep141:ltc.omft.near", Symbol: "LTC", Name: "Litecoin", Decimals: 8, ChainName: "ltc", Price: 80},
+	{DefuseAssetID: "nep141:xrp.omft.near", Symbol: "XRP", Name: "XRP", Decimals: 6, ChainName: "xrp", Price: 0.5},
+	{DefuseAssetID: "nep141:tron-d28a265909efecdcee7c5028585214ea0b96f015.omft.near", Symbol: "USDT", Name: "Tether USD", Decimals: 6, ChainName: "tron", Price: 1, ContractAddress: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"},
+	{DefuseAssetID: "nep245:v2_1.omni.hot.tg:1117_", Symbol: "TON", Name: "Toncoin", Decimals: 9, ChainName: "ton", Price: 5},
+}
+
+// simulatorSeed derives the simulator's quote signing key. Anyone can
+// recompute it, so a signature under it proves nothing outside a demo.
+const simulatorSeed = "zero-intents-simulator"
+
+type intentsSimulator struct {
+	signer ed25519.PrivateKey
+	step   time.Duration // time spent in each status stage
+	now    func() time.Time
+
+	mu     sync.Mutex
+	orders map[string]*simOrder // by deposit address
+}
+
+type simOrder struct {
+	swapType  string
+	corrID    string
+	from, to  TokenInfo
+	amountIn  string
+	amountOut string
+	createdAt time.Time
+	refund    bool
+}
+
+func newIntentsSimulator() *intentsSimulator {
+	seed := sha256.Sum256([]byte(simulatorSeed))
+	return &intentsSimulator{
+		signer: ed25519.NewKeyFromSeed(seed[:]),
+		step:   20 * time.Second,
+		now:    time.Now,
+		orders: make(map[string]*simOrder),
+	}
+}
+
+// publicKey is the key simulated quotes are signed with.
+func (s *intentsSimulator) publicKey() ed25519.PublicKey {
+	return s.signer.Public().(ed25519.PublicKey)
+}
+
+func (s *intentsSimulator) Tokens(ctx context.Context) ([]TokenInfo, error) {
+	return append([]TokenInfo(nil), simulatorTokens...), nil
+}
+
+func (s *intentsSimulator) DryQuote(ctx context.Context, req *QuoteRequest) (*DryQuoteResponse, error) {
+	p, err := s.price(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp DryQuoteResponse
+	q := &resp.Quote
+	q.AmountIn, q.AmountInFormatted = p.amountIn, atomicToHuman(p.amountIn, p.from.Decimals)
+	q.AmountOut, q.AmountOutFormatted = p.amountOut, atomicToHuman(p.amountOut, p.to.Decimals)
+	q.AmountInUSD = simUSD(p.amountIn, p.from)
+	q.AmountOutUSD = simUSD(p.amountOut, p.to)
+	q.MinAmountOut = p.minAmountOut
+	q.MinAmountIn, q.MinAmountInFmt = p.minAmountIn, atomicToHuman(p.minAmountIn, p.from.Decimals)
+	q.MaxAmountIn, q.MaxAmountInFmt = p.amountIn, q.AmountInFormatted
+	q.TimeEstimate = s.timeEstimate()
+	resp.CorrelationID = simCorrelationID(p.seed)
+	return &resp, nil
+}
+
+func (s *intentsSimulator) Quote(ctx context.Context, req *QuoteRequest) (*QuoteResponse, error) {
+	p, err := s.price(req)
+	if err != nil {
+		return nil, err
+	}
+	addr, memo := simDepositAddress(p.from.ChainName, p.seed)
+	detail := QuoteDetail{
+		DepositAddress: addr,
+		DepositMemo:    memo,
+		AmountIn:       p.amountIn,
+		AmountInFmt:    atomicToHuman(p.amountIn, p.from.Decimals),
+		AmountOut:      p.amountOut,
+		AmountOutFmt:   atomicToHuman(p.amountOut, p.to.Decimals),
+		Deadline:       req.Deadline,
+		TimeEstimate:   s.timeEstimate(),
+	}
+	if req.SwapType == "FLEX_INPUT" {
+		detail.MinAmountIn, detail.MinAmountInFmt = p.minAmountIn, atomicToHuman(p.minAmountIn, p.from.Decimals)
+		detail.MaxAmountIn, detail.MaxAmountInFmt = p.amountIn, detail.AmountInFmt
+	}
+	now := s.now().UTC()
+	corrID := simCorrelationID(p.seed)
+
+	// Shaped like the real response so signature checks and transcripts
+	// treat it the same way.
+	body := struct {
+		Quote         QuoteDetail  `json:"quote"`
+		QuoteRequest  QuoteRequest `json:"quoteRequest"`
+		Signature     string       `json:"signature,omitempty"`
+		Timestamp     string       `json:"timestamp"`
+		CorrelationID string       `json:"correlationId"`
+	}{detail, *req, "", now.Format(millisLayout), corrID}
+	unsigned, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := quoteSigningPayload(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	body.Signature = "ed25519:" + base58Encode(ed25519.Sign(s.signer, digest), base58BTC)
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.orders[addr] = &simOrder{
+		swapType:  req.SwapType,
+		corrID:    corrID,
+		from:      p.from,
+		to:        p.to,
+		amountIn:  p.amountIn,
+		amountOut: p.amountOut,
+		createdAt: now,
+		refund:    simRefunds(p.amountIn),
+	}
+	s.mu.Unlock()
+	return parseQuoteResponse(raw)
+}
+
+func (s *intentsSimulator) Status(ctx context.Context, depositAddress, depositMemo string) (*StatusResponse, error) {
+	o, stage, err := s.lookup(depositAddress)
+	if err != nil {
+		return nil, err
+	}
+	resp := StatusResponse{
+		CorrelationID: o.corrID,
+		Status:        simStages[stage],
+		UpdatedAt:     o.createdAt.Add(time.Duration(stage) * s.step).Format(millisLayout),
+	}
+	if stage == len(simStages)-1 && o.refund {
+		resp.Status = "REFUNDED"
+	}
+	if stage > 0 {
+		d := &SwapDetails{
+			AmountIn:    o.amountIn,
+			AmountInFmt: atomicToHuman(o.amountIn, o.from.Decimals),
+			OriginTxs:   []TransactionDetail{{Hash: simTxHash(o.from.ChainName, depositAddress, "in")}},
+		}
+		switch resp.Status {
+		case "SUCCESS":
+			d.AmountOut, d.AmountOutFmt = o.amountOut, atomicToHuman(o.amountOut, o.to.Decimals)
+			d.DestTxs = []TransactionDetail{{Hash: simTxHash(o.to.ChainName, depositAddress, "out")}}
+		case "REFUNDED":
+			d.RefundedAmount = o.amountIn
+			d.RefundReason = "Simulated refund: the amount ends in 7"
+			d.DestTxs = []TransactionDetail{{Hash: simTxHash(o.from.ChainName, depositAddress, "refund")}}
+		}
+		resp.SwapDetails = d
+	}
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	resp.RawJSON = raw
+	return &resp, nil
+}
+
+func (s *intentsSimulator) AnyInputWithdrawals(ctx context.Context, depositAddress string) (*AnyInputWithdrawalsResponse, error) {
+	o, stage, err := s.lookup(depositAddress)
+	if err != nil {
+		return nil, err
+	}
+	resp := &AnyInputWithdrawalsResponse{Withdrawals: []AnyInputWithdrawal{}}
+	if o.swapType != "ANY_INPUT" || stage < len(simStages)-1 || o.refund {
+		return resp, nil
+	}
+	resp.Withdrawals = append(resp.Withdrawals, AnyInputWithdrawal{
+		Status:             "SUCCESS",
+		AmountOut:          o.amountOut,
+		AmountOutFormatted: atomicToHuman(o.amountOut, o.to.Decimals),
+		AmountOutUSD:       simUSD(o.amountOut, o.to),
+		WithdrawFee:        "0",
+		WithdrawFeeFmt:     "0",
+		WithdrawFeeUSD:     "0",
+		Timestamp:          o.createdAt.Add(time.Duration(stage) * s.step).Format(millisLayout),
+		Hash:               simTxHash(o.to.ChainName, depositAddress, "out"),
+	})
+	return resp, nil
+}
+
+var simStages = []string{"PENDING_DEPOSIT", "KNOWN_DEPOSIT_TX", "PROCESSING", "SUCCESS"}
+
+// lookup returns the order behind a deposit address and its current stage
+// (an index into simStages).
+func (s *intentsSimulator) lookup(depositAddress string) (*simOrder, int, error) {
+	s.mu.Lock()
+	o := s.orders[depositAddress]
+	s.mu.Unlock()
+	if o == nil {
+		return nil, 0, fmt.Errorf(`API error 404: {"message":"Deposit address not found"}`)
+	}
+	stage := int(s.now().Sub(o.createdAt) / s.step)
+	return o, min(max(stage, 0), len(simStages)-1), nil
+}
+
+func (s *intentsSimulator) timeEstimate() int {
+	return int((time.Duration(len(simStages)-1) * s.step).Seconds())
+}
+
+type simPrice struct {
+	from, to     TokenInfo
+	amountIn     string
+	amountOut    string
+	minAmountIn  string
+	minAmountOut string
+	seed         [32]byte
+}
+
+// price quotes req against the fixture prices. Errors mimic the text of
+// real API rejections.
+func (s *intentsSimulator) price(req *QuoteRequest) (*simPrice, error) {
+	from, ok := simToken(req.OriginAsset)
+	if !ok {
+		return nil, fmt.Errorf(`API error 400: {"message":"originAsset is not valid"}`)
+	}
+	to, ok := simToken(req.DestinationAsset)
+	if !ok {
+		return nil, fmt.Errorf(`API error 400: {"message":"destinationAsset is not valid"}`)
+	}
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, fmt.Errorf(`API error 400: {"message":"amount is not valid"}`)
+	}
+
+	// rate converts atomic origin units to atomic destination units, net
+	// of the spread.
+	rate := new(big.Rat).SetFloat64(from.Price / to.Price)
+	rate.Mul(rate, simPow10(to.Decimals-from.Decimals))
+	rate.Mul(rate, big.NewRat(10000-simulatorSpreadBPS, 10000))
+
+	p := &simPrice{from: from, to: to}
+	var in, out *big.Int
+	if req.SwapType == "EXACT_OUTPUT" {
+		out = amount
+		in = ratCeil(new(big.Rat).Quo(new(big.Rat).SetInt(out), rate))
+	} else {
+		in = amount
+		out = ratFloor(new(big.Rat).Mul(new(big.Rat).SetInt(in), rate))
+	}
+	keep := big.NewRat(int64(10000-req.SlippageTolerance), 10000)
+	p.amountIn, p.amountOut = in.String(), out.String()
+	p.minAmountOut = ratFloor(new(big.Rat).Mul(new(big.Rat).SetInt(out), keep)).String()
+	p.minAmountIn = ratFloor(new(big.Rat).Mul(new(big.Rat).SetInt(in), keep)).String()
+
+	// Everything that identifies the swap, but not the deadline, which
+	// moves with the clock.
+	p.seed = sha256.Sum256([]byte(strings.Join([]string{req.SwapType, req.OriginAsset,
+		req.DestinationAsset, req.Amount, req.Recipient, req.RefundTo,
+		fmt.Sprint(req.SlippageTolerance)}, "\x00")))
+	return p, nil
+}
+
+func simToken(assetID string) (TokenInfo, bool) {
+	for _, t := range simulatorTokens {
+		if t.DefuseAssetID == assetID {
+			return t, true
+		}
+	}
+	return TokenInfo{}, false
+}
+
+func simPow10(exp int) *big.Rat {
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil))
+	}
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+}
+
+func ratFloor(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+func ratCeil(r *big.Rat) *big.Int {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if m.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+func simUSD(atomic string, t TokenInfo) string {
+	v, _ := new(big.Rat).SetString(atomicToHuman(atomic, t.Decimals))
+	if v == nil {
+		return "0"
+	}
+	v.Mul(v, new(big.Rat).SetFloat64(t.Price))
+	return v.FloatString(2)
+}
+
+// simRefunds reports whether an order for amountIn is scripted to refund.
+func simRefunds(amountIn string) bool {
+	trimmed := strings.TrimRight(amountIn, "0")
+	return strings.HasSuffix(trimmed, "7")
+}
+
+func simCorrelationID(seed [32]byte) string {
+	id := sha256.Sum256(append([]byte("corr"), seed[:]...))
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return formatUUID(id[:16])
+}
+
+// simDepositAddress derives a well-formed deposit address for chain, plus
+// a memo on chains that share one deposit address between users.
+func simDepositAddress(chain string, seed [32]byte) (addr, memo string) {
+	h := sha256.Sum256(append([]byte("deposit"), seed[:]...))
+	switch chain {
+	case "btc":
+		addr = segwitEncode("bc", h[:20])
+	case "ltc":
+		addr = segwitEncode("ltc", h[:20])
+	case "doge":
+		addr = base58CheckEncode(append([]byte{0x1e}, h[:20]...), base58BTC)
+	case "tron":
+		addr = base58CheckEncode(append([]byte{0x41}, h[:20]...), base58BTC)
+	case "xrp":
+		addr = base58CheckEncode(append([]byte{0x00}, h[:20]...), base58Ripple)
+	case "sol":
+		addr = base58Encode(h[:], base58BTC)
+	case "near":
+		addr = hex.EncodeToString(h[:])
+	case "ton":
+		addr = "0:" + hex.EncodeToString(h[:])
+	default:
+		addr = eip55Checksum(hex.EncodeToString(h[:20]))
+	}
+	if c := lookupChain(chain); c != nil && c.MemoRequired {
+		memo = simMemo(h)
+	}
+	return addr, memo
+}
+
+func simMemo(h [32]byte) string {
+	return fmt.Sprint(binary.BigEndian.Uint32(h[28:]) % 1_000_000_000)
+}
+
+func simTxHash(chain, depositAddress, leg string) string {
+	h := sha256.Sum256([]byte(depositAddress + "\x00" + leg))
+	switch chain {
+	case "btc", "ltc", "doge", "tron", "xrp", "ton":
+		return hex.EncodeToString(h[:])
+	case "sol", "near":
+		return base58Encode(append(h[:], h[:]...), base58BTC)[:64]
+	}
+	return "0x" + hex.EncodeToString(h[:])
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withSimulator installs a simulator with a fake clock as the intents
+// backend and trusts its signing key. advance moves the clock forward.
+func withSimulator(t *testing.T) (sim *intentsSimulator, advance func(time.Duration)) {
+	t.Helper()
+	sim = newIntentsSimulator()
+	clock := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sim.now = func() time.Time { return clock }
+	statusCache.reset()
+	withdrawalsCache.reset()
+	statusCache.now = sim.now
+	withdrawalsCache.now = sim.now
+	t.Cleanup(func() { statusCache.now, withdrawalsCache.now = time.Now, time.Now })
+
+	savedIntents, savedKey := intents, quoteSignerKey
+	intents, quoteSignerKey = sim, sim.publicKey()
+	t.Cleanup(func() { intents, quoteSignerKey = savedIntents, savedKey })
+	return sim, func(d time.Duration) { clock = clock.Add(d) }
+}
+
+func simQuoteRequest(from, to, amount string) *QuoteRequest {
+	return &QuoteRequest{
+		SwapType:          "EXACT_INPUT",
+		SlippageTolerance: 100,
+		OriginAsset:       from,
+		DestinationAsset:  to,
+		Amount:            amount,
+		RefundTo:          "0x000000000000000000000000000000000000dEaD",
+		Recipient:         "0x000000000000000000000000000000000000dEaD",
+		Deadline:          "2026-03-01T13:00:00Z",
+	}
+}
+
+func TestSimulatorTokens(t *testing.T) {
+	withSimulator(t)
+	tokens, err := fetchTokens(context.Background())
+	if err != nil || len(tokens) != len(simulatorTokens) {
+		t.Fatalf("fetchTokens: %d tokens, err %v", len(tokens), err)
+	}
+	seen := map[string]bool{}
+	for _, tok := range tokens {
+		if seen[tok.DefuseAssetID] {
+			t.Errorf("duplicate asset %s", tok.DefuseAssetID)
+		}
+		seen[tok.DefuseAssetID] = true
+		if tok.Price <= 0 || tok.Symbol == "" || tok.ChainName == "" {
+			t.Errorf("incomplete fixture: %+v", tok)
+		}
+	}
+}
+
+func TestSimulatorQuotes(t *testing.T) {
+	withSimulator(t)
+
+	// 1 ETH at 2500 into USDC at 1, less the 0.1% spread.
+	dry, err := requestDryQuote(context.Background(), simQuoteRequest("nep141:eth.omft.near", "nep141:eth-0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48.omft.near", "1000000000000000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Quote.AmountOut != "2497500000" || dry.Quote.AmountOutFormatted != "2497.5" {
+		t.Errorf("dry amountOut = %s (%s)", dry.Quote.AmountOut, dry.Quote.AmountOutFormatted)
+	}
+	if dry.Quote.MinAmountOut != "2472525000" {
+		t.Errorf("minAmountOut = %s, want 1%% slippage below", dry.Quote.MinAmountOut)
+	}
+
+	req := simQuoteRequest("nep141:eth.omft.near", "nep141:eth-0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48.omft.near", "2497500000")
+	req.SwapType = "EXACT_OUTPUT"
+	exact, err := requestDryQuote(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact.Quote.AmountIn != "1000000000000000000" {
+		t.Errorf("EXACT_OUTPUT amountIn = %s", exact.Quote.AmountIn)
+	}
+
+	// Same request, same answer — even with a different deadline.
+	a, err := requestQuote(context.Background(), simQuoteRequest("nep141:eth.omft.near", "nep141:btc.omft.near", "500000000000000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := simQuoteRequest("nep141:eth.omft.near", "nep141:btc.omft.near", "500000000000000000")
+	again.Deadline = "2026-03-02T00:00:00Z"
+	b, _ := requestQuote(context.Background(), again)
+	if a.Quote.DepositAddress != b.Quote.DepositAddress || a.CorrelationID != b.CorrelationID {
+		t.Errorf("quotes differ: %s/%s vs %s/%s", a.Quote.DepositAddress, a.CorrelationID, b.Quote.DepositAddress, b.CorrelationID)
+	}
+	if status, err := checkQuoteSignature(a); err != nil || status != quoteSigVerified {
+		t.Errorf("simulated quote signature: %q, %v", status, err)
+	}
+
+	if _, err := requestDryQuote(context.Background(), simQuoteRequest("nep141:nope.near", "nep141:btc.omft.near", "1")); err == nil {
+		t.Error("unknown asset should be rejected")
+	}
+}
+
+func TestSimulatorDepositAddresses(t *testing.T) {
+	withSimulator(t)
+	for _, tok := range simulatorTokens {
+		resp, err := requestQuote(context.Background(), simQuoteRequest(tok.DefuseAssetID, "nep141:eth.omft.near", "1000000"))
+		if err != nil {
+			t.Fatalf("%s: %v", tok.DefuseAssetID, err)
+		}
+		if err := validateAddress(tok.ChainName, resp.Quote.DepositAddress); err != nil {
+			t.Errorf("%s deposit address %s: %v", tok.ChainName, resp.Quote.DepositAddress, err)
+		}
+		if wantMemo := tok.ChainName == "xrp"; (resp.Quote.DepositMemo != "") != wantMemo {
+			t.Errorf("%s memo = %q", tok.ChainName, resp.Quote.DepositMemo)
+		}
+	}
+}
+
+func TestSimulatorStatusProgression(t *testing.T) {
+	sim, advance := withSimulator(t)
+
+	status := func(addr string) string {
+		t.Helper()
+		resp, err := fetchStatus(context.Background(), addr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Status
+	}
+	quote := func(amount string) string {
+		t.Helper()
+		resp, err := requestQuote(context.Background(), simQuoteRequest("nep141:eth.omft.near", "nep141:btc.omft.near", amount))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Quote.DepositAddress
+	}
+
+	ok, refund := quote("100000000000000000"), quote("170000000000000000")
+	want := []string{"PENDING_DEPOSIT", "KNOWN_DEPOSIT_TX", "PROCESSING", "SUCCESS", "SUCCESS"}
+	for i, w := range want {
+		if got := status(ok); got != w {
+			t.Errorf("step %d: %s, want %s", i, got, w)
+		}
+		if got, w := status(refund), strings.Replace(w, "SUCCESS", "REFUNDED", 1); got != w {
+			t.Errorf("refund step %d: %s, want %s", i, got, w)
+		}
+		advance(sim.step)
+	}
+
+	resp, _ := fetchStatus(context.Background(), ok, "")
+	if d := resp.SwapDetails; d == nil || d.AmountOut == "" || len(d.DestTxs) != 1 || len(resp.RawJSON) == 0 {
+		t.Errorf("SUCCESS should carry swap details: %+v", resp)
+	}
+	resp, _ = fetchStatus(context.Background(), refund, "")
+	if d := resp.SwapDetails; d == nil || d.RefundedAmount != "170000000000000000" {
+		t.Errorf("REFUNDED should carry the refunded amount: %+v", resp.SwapDetails)
+	}
+
+	if _, err := fetchStatus(context.Background(), "0xunknown", ""); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("unknown deposit address: %v", err)
+	}
+}
+
+func TestSimulatorAnyInputWithdrawals(t *testing.T) {
+	sim, advance := withSimulator(t)
+	req := simQuoteRequest("nep141:eth.omft.near", "nep141:btc.omft.near", "100000000000000000")
+	req.SwapType = "ANY_INPUT"
+	resp, err := requestQuote(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := fetchAnyInputWithdrawals(context.Background(), resp.Quote.DepositAddress)
+	if err != nil || len(w.Withdrawals) != 0 {
+		t.Fatalf("before SUCCESS: %+v, %v", w, err)
+	}
+	advance(3 * sim.step)
+	w, _ = fetchAnyInputWithdrawals(context.Background(), resp.Quote.DepositAddress)
+	if len(w.Withdrawals) != 1 || w.Withdrawals[0].AmountOut != resp.Quote.AmountOut {
+		t.Errorf("after SUCCESS: %+v", w)
+	}
+}
+
+func TestSimulatorPlaceOrder(t *testing.T) {
+	withSimulator(t)
+	from, _ := simToken("nep141:eth.omft.near")
+	to, _ := simToken("nep141:sol.omft.near")
+	in := &swapInput{FromTicker: "ETH", FromNet: "eth", ToTicker: "SOL", ToNet: "sol",
+		Recipient:  "So11111111111111111111111111111111111111112",
+		RefundAddr: "0x000000000000000000000000000000000000dEaD"}
+
+	order, token, serr := placeOrder(context.Background(), in, &from, &to, "EXACT_INPUT", "1000000000000000000", 100, "", "")
+	if serr != nil {
+		t.Fatalf("placeOrder: %+v", serr)
+	}
+	if order.QuoteSig != quoteSigVerified || order.TranscriptHash == "" || order.Transcript != "" {
+		t.Errorf("QuoteSig = %q, transcript hash %q, inline transcript %d bytes", order.QuoteSig, order.TranscriptHash, len(order.Transcript))
+	}
+	w := httptest.NewRecorder()
+	handleOrder(w, httptest.NewRequest("GET", "/order/"+token, nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), order.DepositAddr) {
+		t.Errorf("order page: %d, deposit address missing", w.Code)
+	}
+}
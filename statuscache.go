@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Order pages poll every few seconds, Telegram cards refresh on demand,
+// and a shared order link can be open in many tabs at once. fetchStatus and
+// fetchAnyInputWithdrawals therefore go through a small in-memory cache
+// keyed by deposit address (and memo):
+//
+//   - Results are reused for statusCacheTTL, or statusCacheTerminalTTL once
+//     the swap has reached a terminal status.
+//   - Concurrent misses for the same key share one upstream call. Each
+//     caller stops waiting when its own context ends; the shared call is
+//     cancelled only once every caller has gone.
+//   - Errors are never cached.
+//
+// Only what 1Click already returned for an address the caller supplied is
+// kept, and only briefly; nothing is written to disk.
+
+// Cache lifetimes; vars so tests can change them.
+var (
+	statusCacheTTL         = 5 * time.Second
+	statusCacheTerminalTTL = 10 * time.Minute
+)
+
+// statusCacheMaxEntries bounds memory. Expired entries are swept when the
+// limit is reached; if everything is still fresh the new result simply
+// isn't stored.
+const statusCacheMaxEntries = 20000
+
+var (
+	statusCache = newResultCache[*StatusResponse]("/v0/status", func(s *StatusResponse) time.Duration {
+		if _, terminal := orderStatusStep(s.Status); terminal {
+			return statusCacheTerminalTTL
+		}
+		return statusCacheTTL
+	})
+	// ANY_INPUT addresses accept new deposits indefinitely, so withdrawal
+	// lists are never final.
+	withdrawalsCache = newResultCache[*AnyInputWithdrawalsResponse]("/v0/any-input/withdrawals", func(*AnyInputWithdrawalsResponse) time.Duration {
+		return statusCacheTTL
+	})
+)
+
+type resultCache[T any] struct {
+	endpoint string // metrics label
+	ttl      func(T) time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry[T]
+}
+
+type cacheEntry[T any] struct {
+	done    chan struct{} // closed when val/err are set
+	val     T
+	err     error
+	expires time.Time
+
+	// While in flight: callers still waiting, and how to stop the fetch
+	// when none are left.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newResultCache[T any](endpoint string, ttl func(T) time.Duration) *resultCache[T] {
+	return &resultCache[T]{endpoint: endpoint, ttl: ttl, now: time.Now, entries: make(map[string]*cacheEntry[T])}
+}
+
+// get returns the cached value for key, or calls fetch — once, however
+// many callers are waiting.
+func (c *resultCache[T]) get(ctx context.Context, key string, fetch func(context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	switch {
+	case ok && !isDone(e.done):
+		statusCacheLookups.inc(c.endpoint, "coalesced")
+		e.waiters++
+	case ok && c.now().Before(e.expires):
+		c.mu.Unlock()
+		statusCacheLookups.inc(c.endpoint, "hit")
+		return e.val, e.err
+	default:
+		statusCacheLookups.inc(c.endpoint, "miss")
+		fillCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), upstreamCallBudget)
+		e = &cacheEntry[T]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		c.entries[key] = e
+		go c.fill(fillCtx, key, e, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.val, e.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		if e.waiters--; e.waiters == 0 && !isDone(e.done) {
+			e.cancel()
+			if c.entries[key] == e {
+				delete(c.entries, key)
+			}
+		}
+		c.mu.Unlock()
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (c *resultCache[T]) fill(ctx context.Context, key string, e *cacheEntry[T], fetch func(context.Context) (T, error)) {
+	defer e.cancel()
+	val, err := fetch(ctx)
+
+	c.mu.Lock()
+	e.val, e.err = val, err
+	if err == nil {
+		e.expires = c.now().Add(c.ttl(val))
+	}
+	if c.entries[key] == e && (err != nil || len(c.entries) > statusCacheMaxEntries && !c.sweepLocked()) {
+		delete(c.entries, key)
+	}
+	close(e.done)
+	c.mu.Unlock()
+}
+
+// reset drops every entry.
+func (c *resultCache[T]) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cacheEntry[T])
+}
+
+// sweepLocked drops expired entries and reports whether that made room.
+func (c *resultCache[T]) sweepLocked() bool {
+	now := c.now()
+	for k, e := range c.entries {
+		if isDone(e.done) && !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	return len(c.entries) <= statusCacheMaxEntries
+}
+
+func isDone(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withStatusCacheClock drives statusCache expiry from a fake clock.
+func withStatusCacheClock(t *testing.T) (advance func(time.Duration)) {
+	t.Helper()
+	clock := time.Now()
+	var mu sync.Mutex
+	statusCache.now = func() time.Time { mu.Lock(); defer mu.Unlock(); return clock }
+	t.Cleanup(func() { statusCache.now = time.Now })
+	return func(d time.Duration) { mu.Lock(); clock = clock.Add(d); mu.Unlock() }
+}
+
+func TestStatusCacheTTL(t *testing.T) {
+	var calls atomic.Int32
+	status := atomic.Value{}
+	status.Store("PROCESSING")
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprintf(w, `{"status":%q}`, status.Load())
+	})
+	advance := withStatusCacheClock(t)
+	ctx := context.Background()
+
+	fetchStatus(ctx, "0xttl", "")
+	fetchStatus(ctx, "0xttl", "")
+	if calls.Load() != 1 {
+		t.Fatalf("second lookup within the TTL reached upstream (%d calls)", calls.Load())
+	}
+	fetchStatus(ctx, "0xttl", "memo")
+	if calls.Load() != 2 {
+		t.Error("a different memo is a different key")
+	}
+
+	advance(statusCacheTTL)
+	status.Store("SUCCESS")
+	if s, _ := fetchStatus(ctx, "0xttl", ""); s.Status != "SUCCESS" || calls.Load() != 3 {
+		t.Fatalf("expired entry should be refetched: %q after %d calls", s.Status, calls.Load())
+	}
+
+	// Terminal statuses are kept longer.
+	advance(statusCacheTerminalTTL - time.Second)
+	fetchStatus(ctx, "0xttl", "")
+	if calls.Load() != 3 {
+		t.Error("terminal status should still be cached")
+	}
+	advance(time.Second)
+	fetchStatus(ctx, "0xttl", "")
+	if calls.Load() != 4 {
+		t.Error("terminal status should expire after statusCacheTerminalTTL")
+	}
+}
+
+func TestStatusCacheCoalesces(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+	})
+
+	// The first caller gives up early; the rest must still get the answer.
+	impatient, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		ctx := context.Background()
+		if i == 0 {
+			ctx = impatient
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := fetchStatus(ctx, "0xpopular", "")
+			if err == nil && s.Status != "PENDING_DEPOSIT" {
+				err = fmt.Errorf("status %q", s.Status)
+			}
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	cancelled := 0
+	for err := range errs {
+		switch {
+		case errors.Is(err, context.Canceled):
+			cancelled++
+		case err != nil:
+			t.Error(err)
+		}
+	}
+	if cancelled != 1 {
+		t.Errorf("%d callers saw cancellation, want only the impatient one", cancelled)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("upstream called %d times, want 1", calls.Load())
+	}
+}
+
+func TestStatusCacheSkipsErrors(t *testing.T) {
+	var calls atomic.Int32
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"status":"PENDING_DEPOSIT"}`)
+	})
+	if _, err := fetchStatus(context.Background(), "0xflaky", ""); err == nil {
+		t.Fatal("expected the upstream error")
+	}
+	if s, err := fetchStatus(context.Background(), "0xflaky", ""); err != nil || s.Status != "PENDING_DEPOSIT" {
+		t.Errorf("errors must not be cached: %v, %v", s, err)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The web form and the JSON API drive the same swap flow: validate input,
+// dry-quote, place a real quote, and seal the result into an order token.
+// The helpers below hold that flow; handlers only translate the outcome into
+// HTML (renderError) or JSON (writeAPIError).
+
+// swapInput is the user-supplied swap request before token resolution.
+type swapInput struct {
+	FromTicker string
+	FromNet    string
+	ToTicker   string
+	ToNet      string
+	Amount     string // send amount (FLEX_INPUT)
+	AmountOut  string // receive amount (EXACT_OUTPUT)
+	Recipient  string
+	RefundAddr string
+	Slippage   string // percentage string, e.g. "1"
+
+	CallbackURL    string // optional status webhook
+	CallbackSecret string
+}
+
+// swapError is a user-facing failure from the shared swap flow.
+type swapError struct {
+	Status  int    // HTTP status
+	Code    string // machine-readable code for the JSON API
+	Title   string // error page title
+	Message string
+}
+
+func (e *swapError) Error() string { return e.Message }
+
+// upstreamSwapError maps a failed 1Click call to a swapError. While the
+// circuit breaker is open the request never left the server, so say so.
+func upstreamSwapError(err error, title string) *swapError {
+	if errors.Is(err, errUpstreamOpen) {
+		return &swapError{
+			Status:  503,
+			Code:    "upstream_unavailable",
+			Title:   title,
+			Message: "NEAR Intents API is not responding, so requests are paused for a few seconds to let it recover. Please try again shortly.",
+		}
+	}
+	return &swapError{
+		Status:  502,
+		Code:    "upstream_unavailable",
+		Title:   title,
+		Message: "NEAR Intents API is temporarily unavailable. This usually resolves in a few minutes.",
+	}
+}
+
+// normalize upper-cases tickers and trims address whitespace.
+func (in *swapInput) normalize() {
+	in.FromTicker = strings.ToUpper(strings.TrimSpace(in.FromTicker))
+	in.ToTicker = strings.ToUpper(strings.TrimSpace(in.ToTicker))
+	in.FromNet = strings.TrimSpace(in.FromNet)
+	in.ToNet = strings.TrimSpace(in.ToNet)
+	in.Amount = strings.TrimSpace(in.Amount)
+	in.AmountOut = strings.TrimSpace(in.AmountOut)
+	in.Recipient = strings.TrimSpace(in.Recipient)
+	in.RefundAddr = strings.TrimSpace(in.RefundAddr)
+	in.CallbackURL = strings.TrimSpace(in.CallbackURL)
+}
+
+// validate returns a list of problems with the input (empty when valid).
+func (in *swapInput) validate() []string {
+	var errs []string
+	if in.Recipient == "" {
+		errs = append(errs, "Recipient address is required")
+	} else if err := validateAddress(in.ToNet, in.Recipient); err != nil {
+		errs = append(errs, "Recipient address is "+err.Error())
+	}
+	if in.RefundAddr == "" {
+		errs = append(errs, "Refund address is required")
+	} else if err := validateAddress(in.FromNet, in.RefundAddr); err != nil {
+		errs = append(errs, "Refund address is "+err.Error())
+	}
+	errs = append(errs, validateCallback(in.CallbackURL, in.CallbackSecret)...)
+	return errs
+}
+
+// resolveTokens looks up both sides of the swap in the token cache.
+func (in *swapInput) resolveTokens() (from, to *TokenInfo, serr *swapError) {
+	from = findToken(in.FromTicker, in.FromNet)
+	to = findToken(in.ToTicker, in.ToNet)
+	if from == nil || to == nil {
+		return nil, nil, &swapError{
+			Status:  400,
+			Code:    "unknown_token",
+			Title:   "Unknown Token",
+			Message: "Could not find the selected tokens. Try selecting them again.",
+		}
+	}
+	return from, to, nil
+}
+
+// swapType auto-detects the swap type from which amount field is filled.
+// Both filled: prefer send amount (FLEX_INPUT).
+func (in *swapInput) swapType() string {
+	if in.Amount == "" && in.AmountOut == "" {
+		return "ANY_INPUT"
+	}
+	if in.AmountOut != "" && in.Amount == "" {
+		return "EXACT_OUTPUT"
+	}
+	return "FLEX_INPUT"
+}
+
+// slippageBPS converts the slippage percentage, defaulting to 1%.
+func (in *swapInput) slippageBPS() int {
+	bps, err := slippageToBPS(in.Slippage)
+	if err != nil {
+		return 100
+	}
+	return bps
+}
+
+// atomicAmount converts the amount that drives the quote to atomic units.
+// ANY_INPUT quotes use a 1-unit reference amount.
+func (in *swapInput) atomicAmount(swapType string, from, to *TokenInfo) (string, *swapError) {
+	var atomic string
+	var err error
+	switch swapType {
+	case "ANY_INPUT":
+		atomic, err = humanToAtomic("1", from.Decimals)
+	case "EXACT_OUTPUT":
+		atomic, err = humanToAtomic(in.AmountOut, to.Decimals)
+	default:
+		atomic, err = humanToAtomic(in.Amount, from.Decimals)
+	}
+	if err != nil {
+		return "", &swapError{
+			Status:  400,
+			Code:    "invalid_amount",
+			Title:   "Invalid Amount",
+			Message: "Could not parse the amount: " + err.Error(),
+		}
+	}
+	return atomic, nil
+}
+
+// newQuoteRequest builds a 1Click quote request. AppFees is always empty —
+// this is the zero-markup guarantee.
+func newQuoteRequest(swapType string, slippageBPS int, from, to *TokenInfo, atomicAmount, refundAddr, recipient string) *QuoteRequest {
+	return &QuoteRequest{
+		SwapType:           swapType,
+		SlippageTolerance:  slippageBPS,
+		OriginAsset:        from.DefuseAssetID,
+		DepositType:        "ORIGIN_CHAIN",
+		DestinationAsset:   to.DefuseAssetID,
+		Amount:             atomicAmount,
+		RefundTo:           refundAddr,
+		RefundType:         "ORIGIN_CHAIN",
+		Recipient:          recipient,
+		RecipientType:      "DESTINATION_CHAIN",
+		Deadline:           buildDeadline(time.Hour),
+		QuoteWaitingTimeMs: 8000,
+		AppFees:            []struct{}{},
+	}
+}
+
+// preparedQuote is a dry quote with display values derived from cached prices.
+type preparedQuote struct {
+	SwapType     string
+	SlippageBPS  int
+	AtomicAmount string
+	AmountIn     string
+	AmountOut    string
+	AmountInUSD  string
+	AmountOutUSD string
+	SpreadUSD    string
+	SpreadPct    string
+	Rate         string
+}
+
+// prepareQuote requests a dry quote for a FLEX_INPUT or EXACT_OUTPUT swap.
+func prepareQuote(ctx context.Context, in *swapInput, from, to *TokenInfo) (*preparedQuote, *swapError) {
+	swapType := in.swapType()
+	bps := in.slippageBPS()
+
+	atomicAmount, serr := in.atomicAmount(swapType, from, to)
+	if serr != nil {
+		return nil, serr
+	}
+
+	quoteReq := newQuoteRequest(swapType, bps, from, to, atomicAmount, in.RefundAddr, in.Recipient)
+	dryResp, err := requestDryQuote(ctx, quoteReq)
+	if err != nil {
+		return nil, upstreamSwapError(err, "Quote Failed")
+	}
+
+	// For EXACT_OUTPUT, AmountIn is estimated and AmountOut is exact.
+	// For FLEX_INPUT, both are approximate.
+	humanIn := dryResp.Quote.AmountInFormatted
+	humanOut := dryResp.Quote.AmountOutFormatted
+	if humanIn == "" {
+		humanIn = in.Amount
+	}
+	if humanOut == "" {
+		humanOut = atomicToHuman(dryResp.Quote.AmountOut, to.Decimals)
+	}
+
+	if dryResp.Quote.AmountOut == "" || dryResp.Quote.AmountOut == "0" {
+		return nil, &swapError{
+			Status:  502,
+			Code:    "no_liquidity",
+			Title:   "Quote Unavailable",
+			Message: "No market makers are currently offering a rate for this pair/amount. Try a larger amount or a different pair.",
+		}
+	}
+
+	pq := &preparedQuote{
+		SwapType:     swapType,
+		SlippageBPS:  bps,
+		AtomicAmount: atomicAmount,
+		AmountIn:     humanIn,
+		AmountOut:    humanOut,
+	}
+
+	inFloat, _ := parseFloat(humanIn)
+	outFloat, _ := parseFloat(humanOut)
+
+	if from.Price > 0 && inFloat > 0 {
+		inUSD := inFloat * from.Price
+		pq.AmountInUSD = formatUSD(inUSD)
+
+		if to.Price > 0 && outFloat > 0 {
+			outUSD := outFloat * to.Price
+			pq.AmountOutUSD = formatUSD(outUSD)
+
+			spread := inUSD - outUSD
+			if spread < 0 {
+				spread = 0
+			}
+			pq.SpreadUSD = formatUSD(spread)
+			if inUSD > 0 {
+				pq.SpreadPct = fmt.Sprintf("%.2f%%", (spread/inUSD)*100)
+			}
+
+			pq.Rate = fmt.Sprintf("1 %s = %s %s", in.FromTicker, formatRate(outFloat/inFloat), in.ToTicker)
+		}
+	}
+	return pq, nil
+}
+
+// placeOrder requests a real quote and seals the result into an order token.
+// userAmountIn/userAmountOut are the amounts the user typed; for FLEX_INPUT
+// the API may return a different amountIn since it accepts a range.
+// The quote signature is checked before anything is sealed. If the input
+// carries a callback URL, a webhook watcher is started; when the registry
+// is already full the order is refused before any quote is requested.
+func placeOrder(ctx context.Context, in *swapInput, from, to *TokenInfo, swapType, atomicAmount string, slippageBPS int, userAmountIn, userAmountOut string) (*OrderData, string, *swapError) {
+	if in.CallbackURL != "" && webhooks.full() {
+		return nil, "", &swapError{
+			Status:  503,
+			Code:    "webhook_capacity",
+			Title:   "Webhooks Unavailable",
+			Message: "Too many status webhooks are active right now. No order was created. Try again later, or place the order without a callback URL.",
+		}
+	}
+	quoteReq := newQuoteRequest(swapType, slippageBPS, from, to, atomicAmount, in.RefundAddr, in.Recipient)
+	quoteResp, err := requestQuote(ctx, quoteReq)
+	if err != nil {
+		title := "Swap Failed"
+		if swapType == "ANY_INPUT" {
+			title = "Quick Swap Failed"
+		}
+		return nil, "", upstreamSwapError(err, title)
+	}
+
+	// Never hand out a deposit address from a quote that fails verification.
+	quoteSig, err := checkQuoteSignature(quoteResp)
+	if err != nil {
+		return nil, "", &swapError{
+			Status:  502,
+			Code:    "quote_unverified",
+			Title:   "Quote Not Verified",
+			Message: "The quote returned by NEAR Intents failed signature verification, so its deposit address can't be trusted. No order was created. Please try again.",
+		}
+	}
+
+	amountIn := quoteResp.Quote.AmountInFmt
+	amountOut := quoteResp.Quote.AmountOutFmt
+	switch swapType {
+	case "ANY_INPUT":
+		amountIn = "any"
+		amountOut = "market rate"
+	case "FLEX_INPUT":
+		if userAmountIn != "" {
+			amountIn = userAmountIn
+		}
+	case "EXACT_OUTPUT":
+		if userAmountOut != "" {
+			amountOut = userAmountOut
+		}
+	}
+
+	order := &OrderData{
+		DepositAddr: quoteResp.Quote.DepositAddress,
+		Memo:        quoteResp.Quote.DepositMemo,
+		FromTicker:  in.FromTicker,
+		FromNet:     in.FromNet,
+		ToTicker:    in.ToTicker,
+		ToNet:       in.ToNet,
+		AmountIn:    amountIn,
+		AmountOut:   amountOut,
+		Deadline:    quoteResp.Quote.Deadline,
+		CorrID:      quoteResp.CorrelationID,
+		RefundAddr:  in.RefundAddr,
+		RecvAddr:    in.Recipient,
+		SwapType:    swapType,
+
+		CallbackURL:    in.CallbackURL,
+		CallbackSecret: in.CallbackSecret,
+
+		QuoteSig: quoteSig,
+	}
+
+	order.TranscriptHash = keepTranscript(order, quoteResp)
+
+	token, err := encryptOrderData(order)
+	if err != nil {
+		return nil, "", &swapError{
+			Status:  500,
+			Code:    "internal_error",
+			Title:   "Internal Error",
+			Message: "Failed to create order token.",
+		}
+	}
+	webhooks.watch(order)
+	return order, token, nil
+}
+
+// orderStatusStep maps an API status to the 3-step stepper position
+// (0=pending, 1=processing, 2=complete) and whether it is terminal.
+func orderStatusStep(status string) (step int, terminal bool) {
+	switch status {
+	case "PROCESSING":
+		return 1, false
+	case "SUCCESS", "REFUNDED", "FAILED", "INCOMPLETE_DEPOSIT":
+		return 2, true
+	default:
+		return 0, false
+	}
+}
+
+// orderTimeRemaining formats the time left before an order deadline,
+// e.g. "1h 5m", "42m" or "Expired". Empty when the deadline is unknown.
+func orderTimeRemaining(deadline string) string {
+	if deadline == "" {
+		return ""
+	}
+	dl, err := time.Parse(time.RFC3339, deadline)
+	if err != nil {
+		return ""
+	}
+	remaining := time.Until(dl)
+	if remaining <= 0 {
+		return "Expired"
+	}
+	mins := int(remaining.Minutes())
+	if mins >= 60 {
+		return fmt.Sprintf("%dh %dm", mins/60, mins%60)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
+
+// loadOrderStatus fetches live status (and ANY_INPUT withdrawal history)
+// for an order. If the API is down, the status is reported as UNKNOWN so
+// callers can still show what the token contains.
+func loadOrderStatus(ctx context.Context, order *OrderData) (*StatusResponse, *AnyInputWithdrawalsResponse) {
+	status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
+	if err != nil {
+		status = &StatusResponse{Status: "UNKNOWN"}
+	}
+	var withdrawals *AnyInputWithdrawalsResponse
+	if order.SwapType == "ANY_INPUT" {
+		withdrawals, _ = fetchAnyInputWithdrawals(ctx, order.DepositAddr)
+	}
+	return status, withdrawals
+}
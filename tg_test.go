@@ -2,8 +2,12 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"image/png"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -294,7 +298,7 @@ func TestGenerateQRPNG(t *testing.T) {
 	}
 }
 
-func TestNetworkDisplayName(t *testing.T) {
+func TestChainName(t *testing.T) {
 	tests := []struct {
 		input string
 		want  string
@@ -306,9 +310,9 @@ func TestNetworkDisplayName(t *testing.T) {
 		{"unknown_chain", "unknown_chain"},
 	}
 	for _, tt := range tests {
-		got := networkDisplayName(tt.input)
+		got := chainName(tt.input)
 		if got != tt.want {
-			t.Errorf("networkDisplayName(%q) = %q, want %q", tt.input, got, tt.want)
+			t.Errorf("chainName(%q) = %q, want %q", tt.input, got, tt.want)
 		}
 	}
 }
@@ -990,3 +994,49 @@ func TestParseInlineQuery_StatusOnlyOneWord(t *testing.T) {
 		t.Errorf("lone 'status' kind = %q, want %q", p.kind, inlineKindSingle)
 	}
 }
+
+// tgCall is one Bot API request seen by withFakeTelegram.
+type tgCall struct {
+	Method  string
+	Payload map[string]interface{}
+}
+
+// tgRecorder collects Bot API calls.
+type tgRecorder struct {
+	mu    sync.Mutex
+	calls []tgCall
+}
+
+// texts returns the text of every message sent or edited, in order.
+func (r *tgRecorder) texts() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, c := range r.calls {
+		if text, ok := c.Payload["text"].(string); ok {
+			out = append(out, text)
+		}
+	}
+	return out
+}
+
+// withFakeTelegram points the Bot API client at a local server that
+// accepts every call and numbers sent messages from 100.
+func withFakeTelegram(t *testing.T) *tgRecorder {
+	t.Helper()
+	rec := &tgRecorder{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		rec.mu.Lock()
+		rec.calls = append(rec.calls, tgCall{Method: strings.TrimPrefix(r.URL.Path, "/"), Payload: payload})
+		n := len(rec.calls)
+		rec.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]interface{}{"message_id": 99 + n}})
+	}))
+	t.Cleanup(srv.Close)
+	saved := tgAPIBase
+	tgAPIBase = srv.URL
+	t.Cleanup(func() { tgAPIBase = saved })
+	return rec
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Telegram address book. It is opt-in: nothing is kept until a user taps
+// "Save" after typing a refund or receive address. Saved addresses are
+// offered, per network, whenever the swap card asks for an address, and
+// can be deleted from the same picker. Each chat's entries are sealed with
+// their own key, derived from ORDER_SECRET and the chat ID (see
+// sealAtRest); the file in addressBookPath (TG_ADDRESS_BOOK_PATH) holds
+// only those sealed blobs, itself sealed. Without ORDER_SECRET the key is
+// random per process, so the book is kept in memory only. /forget deletes
+// a chat's book completely.
+
+const (
+	maxSavedAddresses = 20
+	maxAddressLabel   = 24 // runes
+)
+
+var addressBookPath = cmp.Or(os.Getenv("TG_ADDRESS_BOOK_PATH"), "data/tg_addressbook.bin")
+
+// savedAddress is one address book entry.
+type savedAddress struct {
+	ID    int    `json:"id"`
+	Net   string `json:"n"`
+	Addr  string `json:"a"`
+	Label string `json:"l"`
+}
+
+// addressBookStore keeps each chat's entries sealed in memory too; they are
+// only opened while a chat uses them.
+type addressBookStore struct {
+	mu    sync.Mutex
+	chats map[int64][]byte
+}
+
+var addressBook = &addressBookStore{chats: make(map[int64][]byte)}
+
+// addressBookPurpose is the at-rest key purpose for one chat's entries.
+func addressBookPurpose(chatID int64) string {
+	return "tg-addressbook:" + strconv.FormatInt(chatID, 10)
+}
+
+// load restores the store from addressBookPath (see loadSealedFile).
+func (s *addressBookStore) load() {
+	chats := make(map[int64][]byte)
+	if !loadSealedFile(addressBookPath, "tg-addressbook", "address books", &chats) {
+		return
+	}
+	s.mu.Lock()
+	s.chats = chats
+	s.mu.Unlock()
+	log.Printf("Loaded %d Telegram address books", s.count())
+}
+
+// saveLocked writes the store (see saveSealedFile). The caller holds s.mu.
+func (s *addressBookStore) saveLocked() {
+	saveSealedFile(addressBookPath, "tg-addressbook", "address books", s.chats)
+}
+
+// entriesLocked opens a chat's entries. The caller holds s.mu.
+func (s *addressBookStore) entriesLocked(chatID int64) []savedAddress {
+	sealed, ok := s.chats[chatID]
+	if !ok {
+		return nil
+	}
+	data, err := openAtRest(addressBookPurpose(chatID), sealed)
+	if err != nil {
+		log.Printf("address book: chat entries ignored: %v", err)
+		return nil
+	}
+	var list []savedAddress
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("address book: chat entries ignored: %v", err)
+		return nil
+	}
+	return list
+}
+
+// putLocked seals a chat's entries and writes the store. The caller holds
+// s.mu.
+func (s *addressBookStore) putLocked(chatID int64, list []savedAddress) error {
+	if len(list) == 0 {
+		delete(s.chats, chatID)
+		s.saveLocked()
+		return nil
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealAtRest(addressBookPurpose(chatID), data)
+	if err != nil {
+		return err
+	}
+	s.chats[chatID] = sealed
+	s.saveLocked()
+	return nil
+}
+
+// list returns the chat's entries for one network, or all of them if net
+// is empty.
+func (s *addressBookStore) list(chatID int64, net string) []savedAddress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []savedAddress
+	for _, e := range s.entriesLocked(chatID) {
+		if net == "" || e.Net == net {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// find returns the entry for an address on a network, if saved.
+func (s *addressBookStore) find(chatID int64, net, addr string) *savedAddress {
+	for _, e := range s.list(chatID, net) {
+		if e.Addr == addr {
+			return &e
+		}
+	}
+	return nil
+}
+
+// save adds an address, or relabels it if it is already saved.
+func (s *addressBookStore) save(chatID int64, net, addr, label string) (savedAddress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.entriesLocked(chatID)
+	e := savedAddress{ID: 1, Net: net, Addr: addr, Label: label}
+	for i := range list {
+		if list[i].Net == net && list[i].Addr == addr {
+			list[i].Label = label
+			return list[i], s.putLocked(chatID, list)
+		}
+		if list[i].ID >= e.ID {
+			e.ID = list[i].ID + 1
+		}
+	}
+	if len(list) >= maxSavedAddresses {
+		return e, fmt.Errorf("your address book is full (%d addresses) — delete one first", maxSavedAddresses)
+	}
+	return e, s.putLocked(chatID, append(list, e))
+}
+
+// get returns one entry by ID.
+func (s *addressBookStore) get(chatID int64, id int) *savedAddress {
+	for _, e := range s.list(chatID, "") {
+		if e.ID == id {
+			return &e
+		}
+	}
+	return nil
+}
+
+// remove deletes one entry.
+func (s *addressBookStore) remove(chatID int64, id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.entriesLocked(chatID)
+	for i, e := range list {
+		if e.ID == id {
+			if err := s.putLocked(chatID, append(list[:i:i], list[i+1:]...)); err != nil {
+				log.Printf("address book remove error: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// forget deletes a chat's address book.
+func (s *addressBookStore) forget(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.chats[chatID]; ok {
+		delete(s.chats, chatID)
+		s.saveLocked()
+	}
+}
+
+// count returns the number of chats with an address book.
+func (s *addressBookStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.chats)
+}
+
+// cleanAddressLabel trims a typed label and checks it fits.
+func cleanAddressLabel(s string) (string, error) {
+	s = strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}), " ")
+	switch {
+	case s == "":
+		return "", fmt.Errorf("label is empty")
+	case utf8.RuneCountInString(s) > maxAddressLabel:
+		return "", fmt.Errorf("label is longer than %d characters", maxAddressLabel)
+	}
+	return s, nil
+}
+
+// ── Swap card integration ──
+
+// addressSide returns the network and the session field an address picker
+// for side ("from" for refund, "to" for receive) works on.
+func addressSide(sess *tgSession, side string) (net string, addr *string) {
+	if side == "from" {
+		return sess.FromNet, &sess.RefundAddr
+	}
+	return sess.ToNet, &sess.RecvAddr
+}
+
+// showAddressPicker replaces the swap card with the chat's saved addresses
+// for the side's network, if there are any. The ForceReply prompt stays
+// open so a new address can still be pasted.
+func showAddressPicker(chatID int64, sess *tgSession, side string) bool {
+	net, _ := addressSide(sess, side)
+	saved := addressBook.list(chatID, net)
+	if len(saved) == 0 || sess.CardMsgID == 0 {
+		return false
+	}
+	sess.PickSide = side
+	kind := "receive"
+	if side == "from" {
+		kind = "refund"
+	}
+	text := fmt.Sprintf("<b>Saved %s addresses</b> — %s\n\nTap one, or <u>paste a new address</u>.",
+		html.EscapeString(chainName(net)), kind)
+	var rows [][]TGInlineKeyboardButton
+	for _, e := range saved {
+		id := strconv.Itoa(e.ID)
+		rows = append(rows, []TGInlineKeyboardButton{
+			{Text: e.Label + " · " + truncAddr(e.Addr), CallbackData: "ab:u:" + id},
+			{Text: "🗑", CallbackData: "ab:d:" + id},
+		})
+	}
+	rows = append(rows, []TGInlineKeyboardButton{
+		{Text: "← Back", CallbackData: "bk"},
+	})
+	tgEditMessage(chatID, sess.CardMsgID, text, &TGInlineKeyboardMarkup{InlineKeyboard: rows})
+	return true
+}
+
+// handleTGAddressBook routes "ab:" callbacks: u:<id> uses a saved address,
+// d:<id> deletes one, s:<side> saves the side's current address and x
+// dismisses the save offer in msgID.
+func handleTGAddressBook(chatID int64, sess *tgSession, msgID int, arg string) {
+	action, param, _ := strings.Cut(arg, ":")
+	switch action {
+	case "u":
+		id, _ := strconv.Atoi(param)
+		e := addressBook.get(chatID, id)
+		net, addr := addressSide(sess, sess.PickSide)
+		if e == nil || sess.PickSide == "" || e.Net != net {
+			handleTGBackToCard(chatID, sess)
+			return
+		}
+		*addr = e.Addr
+		handleTGBackToCard(chatID, sess)
+	case "d":
+		id, _ := strconv.Atoi(param)
+		addressBook.remove(chatID, id)
+		if sess.PickSide == "" || !showAddressPicker(chatID, sess, sess.PickSide) {
+			updateSwapCard(chatID, sess)
+		}
+	case "s":
+		tgDeleteMessage(chatID, msgID)
+		net, addr := addressSide(sess, param)
+		if *addr == "" || sess.State != stateSwapCard {
+			return
+		}
+		sess.PickSide = param
+		sess.State = stateEnterAddrLabel
+		prompt := fmt.Sprintf("Name this %s address (e.g. Ledger, Exchange):", html.EscapeString(chainName(net)))
+		msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
+			ForceReply:            true,
+			Selective:             true,
+			InputFieldPlaceholder: "Label...",
+		})
+		if err == nil {
+			sess.PromptMsgID = msg.MessageID
+		}
+	default:
+		tgDeleteMessage(chatID, msgID)
+	}
+}
+
+// offerSaveAddress asks whether to keep a typed address, unless it is
+// already in the book.
+func offerSaveAddress(chatID int64, sess *tgSession, side string) {
+	net, addr := addressSide(sess, side)
+	if addressBook.find(chatID, net, *addr) != nil {
+		return
+	}
+	text := "💾 Save <code>" + html.EscapeString(truncAddr(*addr)) + "</code> to your address book for next time?"
+	markup := &TGInlineKeyboardMarkup{InlineKeyboard: [][]TGInlineKeyboardButton{{
+		{Text: "💾 Save", CallbackData: "ab:s:" + side, Style: "primary"},
+		{Text: "No thanks", CallbackData: "ab:x"},
+	}}}
+	if msg, err := tgSendMessage(chatID, text, markup); err == nil {
+		sess.trackMsg(msg.MessageID)
+	}
+}
+
+// handleTGAddrLabelInput saves the address named by the typed label.
+func handleTGAddrLabelInput(chatID int64, sess *tgSession, msg *TGMessage) {
+	label, err := cleanAddressLabel(msg.Text)
+	if err != nil {
+		tgSendMessage(chatID, "That "+html.EscapeString(err.Error())+". Please try again.", nil)
+		return
+	}
+	cleanupPromptReply(chatID, sess, msg.MessageID)
+	sess.State = stateSwapCard
+
+	net, addr := addressSide(sess, sess.PickSide)
+	sess.PickSide = ""
+	if _, err := addressBook.save(chatID, net, *addr, label); err != nil {
+		tgSendMessage(chatID, "❌ "+html.EscapeString(err.Error()), nil)
+		return
+	}
+	if m, err := tgSendMessage(chatID, "💾 Saved as <b>"+html.EscapeString(label)+"</b>.", nil); err == nil {
+		sess.trackMsg(m.MessageID)
+	}
+}
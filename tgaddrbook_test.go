@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withAddressBook gives the test an empty address book backed by a temp
+// file.
+func withAddressBook(t *testing.T) {
+	t.Helper()
+	withKeyring(t, testKey(5))
+	saved, savedPath := addressBook.chats, addressBookPath
+	addressBook.chats = make(map[int64][]byte)
+	addressBookPath = filepath.Join(t.TempDir(), "addressbook.bin")
+	t.Cleanup(func() { addressBook.chats, addressBookPath = saved, savedPath })
+}
+
+const bookAddr = "0x1111111111111111111111111111111111111111"
+
+func TestAddressBookStore(t *testing.T) {
+	withAddressBook(t)
+	if _, err := addressBook.save(1, "eth", bookAddr, "Ledger"); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := addressBook.save(1, "eth", bookAddr, "Cold"); e.ID != 1 || len(addressBook.list(1, "")) != 1 {
+		t.Errorf("saving an address again should relabel it, got %+v", e)
+	}
+	addressBook.save(1, "sol", "So11111111111111111111111111111111111111112", "Phantom")
+	addressBook.save(2, "eth", bookAddr, "Theirs")
+	if got := addressBook.list(1, "eth"); len(got) != 1 || got[0].Label != "Cold" {
+		t.Errorf("eth entries = %+v", got)
+	}
+	if e := addressBook.find(1, "sol", bookAddr); e != nil {
+		t.Error("entries are per network")
+	}
+
+	raw, err := os.ReadFile(addressBookPath)
+	if err != nil || strings.Contains(string(raw), "Cold") || strings.Contains(string(raw), bookAddr) {
+		t.Fatalf("store should exist and be encrypted: %v", err)
+	}
+	// Each chat's entries are sealed with that chat's own key.
+	if _, err := openAtRest(addressBookPurpose(2), addressBook.chats[1]); err == nil {
+		t.Error("another chat's key must not open the entries")
+	}
+
+	loaded := &addressBookStore{chats: make(map[int64][]byte)}
+	loaded.load()
+	if got := loaded.list(1, ""); len(got) != 2 || got[1].Label != "Phantom" {
+		t.Errorf("reloaded = %+v", got)
+	}
+
+	addressBook.remove(1, 1)
+	addressBook.forget(2)
+	if addressBook.count() != 1 || len(addressBook.list(1, "")) != 1 {
+		t.Errorf("after remove and forget: %d books", addressBook.count())
+	}
+	addressBook.remove(1, 2)
+	if addressBook.count() != 0 {
+		t.Error("an emptied book should be dropped")
+	}
+
+	for i := 0; i < maxSavedAddresses; i++ {
+		addressBook.save(3, "eth", bookAddr[:41]+string(rune('a'+i)), "x")
+	}
+	if _, err := addressBook.save(3, "eth", bookAddr, "one more"); err == nil {
+		t.Errorf("more than %d addresses should be refused", maxSavedAddresses)
+	}
+
+	// Without ORDER_SECRET the key is random: the book stays in memory only.
+	keyring.ephemeral = true
+	os.Remove(addressBookPath)
+	addressBook.save(4, "eth", bookAddr, "Ledger")
+	if _, err := os.Stat(addressBookPath); !os.IsNotExist(err) {
+		t.Errorf("a book sealed with an ephemeral key should not be written: %v", err)
+	}
+	if addressBook.find(4, "eth", bookAddr) == nil {
+		t.Error("the book should still be kept in memory")
+	}
+}
+
+func TestCleanAddressLabel(t *testing.T) {
+	if got, err := cleanAddressLabel("  My\tLedger \n"); err != nil || got != "My Ledger" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, bad := range []string{"", " \n ", strings.Repeat("a", maxAddressLabel+1)} {
+		if _, err := cleanAddressLabel(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestTGAddressBookFlow(t *testing.T) {
+	withAddressBook(t)
+	tg := withFakeTelegram(t)
+	const chatID = 9301
+	sess := &tgSession{State: stateSwapCard, CardMsgID: 50, FromTicker: "ETH", FromNet: "eth", ToTicker: "SOL", ToNet: "sol"}
+
+	// Nothing saved: the prompt is sent and the card is left alone.
+	handleTGPromptRefund(chatID, sess)
+	if n := len(tg.calls); n != 1 || tg.calls[0].Method != "sendMessage" {
+		t.Fatalf("expected only the prompt, got %+v", tg.calls)
+	}
+
+	// A typed address is offered for saving.
+	handleTGRefundInput(chatID, sess, &TGMessage{MessageID: 7, Text: bookAddr})
+	last := tg.calls[len(tg.calls)-1].Payload
+	markup, _ := json.Marshal(last["reply_markup"])
+	if !strings.Contains(last["text"].(string), "Save <code>0x111111...111111</code>") || !strings.Contains(string(markup), `"ab:s:from"`) {
+		t.Fatalf("save offer = %v %s", last["text"], markup)
+	}
+	if addressBook.count() != 0 {
+		t.Error("nothing should be stored before the user opts in")
+	}
+
+	handleTGAddressBook(chatID, sess, 120, "s:from")
+	if sess.State != stateEnterAddrLabel {
+		t.Fatalf("state = %d, want label prompt", sess.State)
+	}
+	handleTGAddrLabelInput(chatID, sess, &TGMessage{MessageID: 8, Text: strings.Repeat("x", 40)})
+	if sess.State != stateEnterAddrLabel {
+		t.Error("an overlong label should be asked for again")
+	}
+	handleTGAddrLabelInput(chatID, sess, &TGMessage{MessageID: 9, Text: "Ledger"})
+	if got := addressBook.list(chatID, "eth"); len(got) != 1 || got[0].Label != "Ledger" || sess.State != stateSwapCard {
+		t.Fatalf("saved = %+v, state %d", got, sess.State)
+	}
+
+	// Next time the picker lists it, and picking fills the card.
+	sess.RefundAddr = ""
+	n := len(tg.calls)
+	handleTGPromptRefund(chatID, sess)
+	picker := tg.calls[n].Payload
+	markup, _ = json.Marshal(picker["reply_markup"])
+	if tg.calls[n].Method != "editMessageText" || !strings.Contains(picker["text"].(string), "Saved Ethereum addresses") ||
+		!strings.Contains(string(markup), `"Ledger · 0x111111...111111"`) || !strings.Contains(string(markup), `"ab:d:1"`) {
+		t.Fatalf("picker = %v %s", picker["text"], markup)
+	}
+	handleTGAddressBook(chatID, sess, 50, "u:1")
+	if sess.RefundAddr != bookAddr || sess.State != stateSwapCard {
+		t.Errorf("picking should fill the refund address, got %q", sess.RefundAddr)
+	}
+
+	// Saved addresses are offered only on their network.
+	n = len(tg.calls)
+	handleTGPromptRecv(chatID, sess)
+	if tg.calls[n].Method != "sendMessage" {
+		t.Error("no receive addresses are saved for SOL")
+	}
+
+	// Deleting the last one goes back to the card.
+	handleTGBackToCard(chatID, sess)
+	handleTGPromptRefund(chatID, sess)
+	handleTGAddressBook(chatID, sess, 50, "d:1")
+	if addressBook.count() != 0 {
+		t.Error("delete should remove the entry")
+	}
+
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Price alerts. "/alert BTC > 100000" watches a token's USD price and
+// "/alert ETH/BTC < 0.03" watches the rate between two tokens; a trailing
+// "quote" on a pair alert makes it wait for a 1Click dry quote to confirm
+// the rate before firing. Alerts are one-shot: after each token cache
+// refresh the evaluator compares cached prices with every level, and a
+// crossed alert is removed and answered with a swap card prefilled for the
+// move. The store lives in alertStorePath (TG_ALERTS_PATH), sealed with a
+// key derived from ORDER_SECRET (see sealAtRest). Without ORDER_SECRET the
+// key is random per process, so alerts are kept in memory only.
+
+const (
+	maxAlertsPerChat = 10
+	// Size, in USD, of the dry quote that confirms a quote-checked alert.
+	alertQuoteUSD = 100
+	// Dry quotes never move funds, but 1Click still wants well-formed
+	// refund and recipient accounts.
+	alertQuoteAccount = "intents.near"
+)
+
+var alertStorePath = cmp.Or(os.Getenv("TG_ALERTS_PATH"), "data/tg_alerts.bin")
+
+// priceAlert is one alert. Base and Quote are resolved to a network when
+// the alert is created; an empty Quote means USD.
+type priceAlert struct {
+	ID         int       `json:"id"`
+	Base       string    `json:"b"`
+	BaseNet    string    `json:"bn"`
+	Quote      string    `json:"q,omitempty"`
+	QuoteNet   string    `json:"qn,omitempty"`
+	Above      bool      `json:"a"`
+	Level      float64   `json:"l"`
+	CheckQuote bool      `json:"dq,omitempty"`
+	Created    time.Time `json:"c"`
+}
+
+// pair is "BTC" for USD alerts and "ETH/BTC" otherwise.
+func (a *priceAlert) pair() string {
+	if a.Quote == "" {
+		return a.Base
+	}
+	return a.Base + "/" + a.Quote
+}
+
+func (a *priceAlert) op() string {
+	if a.Above {
+		return ">"
+	}
+	return "<"
+}
+
+// formatRate formats a price or rate in the alert's unit.
+func (a *priceAlert) formatRate(v float64) string {
+	if a.Quote == "" {
+		if v >= 1 {
+			return formatUSD(v)
+		}
+		return "$" + fmtEstimate(v)
+	}
+	return fmtEstimate(v) + " " + a.Quote
+}
+
+// describe renders the alert for /alerts, HTML-escaped.
+func (a *priceAlert) describe() string {
+	s := "<b>" + html.EscapeString(a.pair()) + "</b> " + html.EscapeString(a.op()) + " " + html.EscapeString(a.formatRate(a.Level))
+	if a.CheckQuote {
+		s += " <i>(quote-checked)</i>"
+	}
+	return s
+}
+
+// alertStore holds every chat's alerts, oldest first.
+type alertStore struct {
+	mu    sync.Mutex
+	chats map[int64][]priceAlert
+}
+
+var priceAlerts = &alertStore{chats: make(map[int64][]priceAlert)}
+
+// load restores the store from alertStorePath (see loadSealedFile).
+func (s *alertStore) load() {
+	chats := make(map[int64][]priceAlert)
+	if !loadSealedFile(alertStorePath, "tg-alerts", "price alerts", &chats) {
+		return
+	}
+	s.mu.Lock()
+	s.chats = chats
+	s.mu.Unlock()
+	log.Printf("Loaded %d price alerts", s.count())
+}
+
+// saveLocked writes the store (see saveSealedFile). The caller holds s.mu.
+func (s *alertStore) saveLocked() {
+	saveSealedFile(alertStorePath, "tg-alerts", "price alerts", s.chats)
+}
+
+// add stores a new alert for the chat and returns it with its ID.
+func (s *alertStore) add(chatID int64, a priceAlert) (priceAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.chats[chatID]
+	if len(list) >= maxAlertsPerChat {
+		return a, fmt.Errorf("you already have %d alerts — delete one with /alerts first", maxAlertsPerChat)
+	}
+	a.ID = 1
+	for _, x := range list {
+		if x.ID >= a.ID {
+			a.ID = x.ID + 1
+		}
+	}
+	s.chats[chatID] = append(list, a)
+	s.saveLocked()
+	return a, nil
+}
+
+// list returns a copy of the chat's alerts.
+func (s *alertStore) list(chatID int64) []priceAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]priceAlert(nil), s.chats[chatID]...)
+}
+
+// remove deletes one alert. It reports false if the alert was already gone,
+// which keeps a fired alert from firing twice.
+func (s *alertStore) remove(chatID int64, id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.chats[chatID]
+	for i, a := range list {
+		if a.ID != id {
+			continue
+		}
+		list = append(list[:i:i], list[i+1:]...)
+		if len(list) == 0 {
+			delete(s.chats, chatID)
+		} else {
+			s.chats[chatID] = list
+		}
+		s.saveLocked()
+		return true
+	}
+	return false
+}
+
+// clear deletes all of a chat's alerts.
+func (s *alertStore) clear(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.chats[chatID]; ok {
+		delete(s.chats, chatID)
+		s.saveLocked()
+	}
+}
+
+// count returns the number of alerts across all chats.
+func (s *alertStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, list := range s.chats {
+		n += len(list)
+	}
+	return n
+}
+
+// snapshot copies the store for the evaluator.
+func (s *alertStore) snapshot() map[int64][]priceAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[int64][]priceAlert, len(s.chats))
+	for id, list := range s.chats {
+		out[id] = append([]priceAlert(nil), list...)
+	}
+	return out
+}
+
+// ── Parsing ──
+
+// alertToken resolves a ticker (or a search alias like "bitcoin") to one
+// token: the asset on the ticker's native chain if there is one, otherwise
+// the one on the highest-priority chain.
+func alertToken(sym string) *TokenInfo {
+	ticker := strings.ToUpper(sym)
+	candidates := tokensByTicker(ticker)
+	if len(candidates) == 0 {
+		if alias, ok := tokenAliases[strings.ToLower(sym)]; ok {
+			candidates = tokensByTicker(alias)
+		}
+	}
+	var best *TokenInfo
+	bestRank := math.MaxInt
+	for i := range candidates {
+		t := &candidates[i]
+		rank := 1000
+		if c := lookupChain(t.ChainName); c != nil {
+			if c.NativeTicker == t.Ticker {
+				rank = 0
+			} else if c.Priority > 0 {
+				rank = c.Priority
+			}
+		}
+		if rank < bestRank {
+			best, bestRank = t, rank
+		}
+	}
+	return best
+}
+
+// parseAlertCommand parses the arguments of /alert:
+//
+//	BTC > 100000
+//	ETH/BTC < 0.03 quote
+func parseAlertCommand(args string) (priceAlert, error) {
+	var a priceAlert
+	args = strings.TrimSpace(args)
+	i := strings.IndexAny(args, "<>")
+	if i <= 0 {
+		return a, fmt.Errorf("use <code>/alert BTC &gt; 100000</code> or <code>/alert ETH/BTC &lt; 0.03</code>")
+	}
+	pair, rest := strings.TrimSpace(args[:i]), strings.Fields(args[i+1:])
+	a.Above = args[i] == '>'
+	if len(rest) > 0 && strings.EqualFold(rest[len(rest)-1], "quote") {
+		a.CheckQuote = true
+		rest = rest[:len(rest)-1]
+	}
+	if len(rest) != 1 {
+		return a, fmt.Errorf("give one price level, e.g. <code>/alert BTC &gt; 100000</code>")
+	}
+	level, err := strconv.ParseFloat(strings.NewReplacer(",", "", "_", "", "$", "").Replace(rest[0]), 64)
+	if err != nil || !(level > 0) || math.IsInf(level, 0) {
+		return a, fmt.Errorf("%s is not a price level", html.EscapeString(strconv.Quote(rest[0])))
+	}
+	a.Level = level
+
+	baseSym, quoteSym, isPair := strings.Cut(pair, "/")
+	base := alertToken(strings.TrimSpace(baseSym))
+	if base == nil {
+		return a, fmt.Errorf("unknown token %s", html.EscapeString(strconv.Quote(strings.TrimSpace(baseSym))))
+	}
+	a.Base, a.BaseNet = base.Ticker, base.ChainName
+	if isPair {
+		quote := alertToken(strings.TrimSpace(quoteSym))
+		if quote == nil {
+			return a, fmt.Errorf("unknown token %s", html.EscapeString(strconv.Quote(strings.TrimSpace(quoteSym))))
+		}
+		if quote.Ticker == base.Ticker {
+			return a, fmt.Errorf("pick two different tokens")
+		}
+		a.Quote, a.QuoteNet = quote.Ticker, quote.ChainName
+	} else if a.CheckQuote {
+		return a, fmt.Errorf("quote checks are for pair alerts like <code>ETH/BTC</code>")
+	}
+	return a, nil
+}
+
+// ── Evaluation ──
+
+// alertRate is the alert's current price (USD) or rate (quote per base)
+// from the token cache.
+func alertRate(a *priceAlert) (float64, bool) {
+	base := findToken(a.Base, a.BaseNet)
+	if base == nil || base.Price <= 0 {
+		return 0, false
+	}
+	if a.Quote == "" {
+		return base.Price, true
+	}
+	quote := findToken(a.Quote, a.QuoteNet)
+	if quote == nil || quote.Price <= 0 {
+		return 0, false
+	}
+	return base.Price / quote.Price, true
+}
+
+func (a *priceAlert) crossed(rate float64) bool {
+	if a.Above {
+		return rate >= a.Level
+	}
+	return rate <= a.Level
+}
+
+// quotedRate asks 1Click for a dry quote of about alertQuoteUSD worth of the
+// base token and returns the quote tokens received per base token.
+func quotedRate(a *priceAlert) (float64, error) {
+	from, to := findToken(a.Base, a.BaseNet), findToken(a.Quote, a.QuoteNet)
+	if from == nil || to == nil || from.Price <= 0 {
+		return 0, fmt.Errorf("token not priced")
+	}
+	amount := strconv.FormatFloat(alertQuoteUSD/from.Price, 'f', min(from.Decimals, 8), 64)
+	atomic, err := humanToAtomic(amount, from.Decimals)
+	if err != nil {
+		return 0, err
+	}
+	req := newQuoteRequest("FLEX_INPUT", 100, from, to, atomic, alertQuoteAccount, alertQuoteAccount)
+	req.Dry = true
+	req.RefundType, req.RecipientType = "INTENTS", "INTENTS"
+
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	resp, err := requestDryQuote(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	in, _ := parseFloat(atomicToHuman(resp.Quote.AmountIn, from.Decimals))
+	out, _ := parseFloat(atomicToHuman(resp.Quote.AmountOut, to.Decimals))
+	if in <= 0 || out <= 0 {
+		return 0, fmt.Errorf("no liquidity")
+	}
+	return out / in, nil
+}
+
+// alertEval keeps evaluations from overlapping when dry quotes are slow.
+var alertEval sync.Mutex
+
+// checkPriceAlerts evaluates every alert against the freshly refreshed
+// token cache. It runs after each refresh and returns at once when there
+// are no alerts.
+func checkPriceAlerts() {
+	if priceAlerts.count() == 0 || !alertEval.TryLock() {
+		return
+	}
+	go func() {
+		defer alertEval.Unlock()
+		evaluatePriceAlerts()
+	}()
+}
+
+// evaluatePriceAlerts fires every alert whose level has been crossed.
+func evaluatePriceAlerts() {
+	chats := priceAlerts.snapshot()
+	ids := make([]int64, 0, len(chats))
+	for id := range chats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, chatID := range ids {
+		for _, a := range chats[chatID] {
+			rate, ok := alertRate(&a)
+			if !ok || !a.crossed(rate) {
+				continue
+			}
+			if a.CheckQuote {
+				quoted, err := quotedRate(&a)
+				if err != nil {
+					log.Printf("alert quote check %s: %v", a.pair(), err)
+					continue
+				}
+				if !a.crossed(quoted) {
+					continue
+				}
+				rate = quoted
+			}
+			if priceAlerts.remove(chatID, a.ID) {
+				tgPriceAlertsFired.inc(map[bool]string{true: "quote", false: "price"}[a.CheckQuote])
+				deliverPriceAlert(chatID, a, rate)
+			}
+		}
+	}
+}
+
+// deliverPriceAlert tells the chat its alert fired and sends a swap card
+// prefilled for the move: sell the base token after a rise, buy it after a
+// fall. USD alerts trade against USDC. A chat in the middle of a quote or
+// order keeps its card and gets a button to open the new one instead.
+func deliverPriceAlert(chatID int64, a priceAlert, rate float64) {
+	verb := "fell below"
+	if a.Above {
+		verb = "rose above"
+	}
+	text := fmt.Sprintf("🔔 <b>%s</b> %s %s — now %s.", html.EscapeString(a.pair()), verb,
+		html.EscapeString(a.formatRate(a.Level)), html.EscapeString(a.formatRate(rate)))
+	if a.CheckQuote {
+		text += "\n<i>Confirmed by a 1Click quote.</i>"
+	}
+
+	other, otherNet := a.Quote, a.QuoteNet
+	if other == "" {
+		if usdc := alertToken("USDC"); usdc != nil {
+			other, otherNet = usdc.Ticker, usdc.ChainName
+		}
+	}
+	fromTicker, fromNet, toTicker, toNet := a.Base, a.BaseNet, other, otherNet
+	if !a.Above {
+		fromTicker, fromNet, toTicker, toNet = other, otherNet, a.Base, a.BaseNet
+	}
+	param := fromTicker + "-" + fromNet + "_" + toTicker + "-" + toNet
+
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if toTicker == "" || sess.State == stateQuoteConfirm || sess.State == stateOrderActive {
+		var markup *TGInlineKeyboardMarkup
+		if toTicker != "" {
+			markup = &TGInlineKeyboardMarkup{InlineKeyboard: [][]TGInlineKeyboardButton{
+				{{Text: "Swap " + fromTicker + " → " + toTicker, CallbackData: "ap:" + param}},
+			}}
+		}
+		tgSendMessage(chatID, text, markup)
+		return
+	}
+	tgSendMessage(chatID, text, nil)
+	sendPrefilledCard(chatID, sess, param)
+}
+
+// sendPrefilledCard replaces the chat's swap card with a fresh one prefilled
+// from a deep-link style parameter ("BTC-btc_USDC-eth"). Caller holds sess.mu.
+func sendPrefilledCard(chatID int64, sess *tgSession, param string) {
+	sess.releaseCard(chatID)
+	sess.reset()
+	parseSwapStartParam(sess, param)
+	sess.State = stateSwapCard
+
+	text, markup := renderSwapCard(sess)
+	msg, err := tgSendMessage(chatID, text, markup)
+	if err != nil {
+		log.Printf("tg alert card error: %v", err)
+		return
+	}
+	sess.CardMsgID = msg.MessageID
+	sess.trackMsg(msg.MessageID)
+}
+
+// ── Commands ──
+
+// handleTGAlert creates an alert from "/alert <pair> <op> <level> [quote]".
+// Levels the price has already crossed are refused, since they would fire
+// on the next refresh.
+func handleTGAlert(chatID int64, args string) {
+	if strings.TrimSpace(args) == "" {
+		tgSendMessage(chatID, "<b>🔔 Price alerts</b>\n\n"+
+			"<code>/alert BTC &gt; 100000</code> — BTC price in USD\n"+
+			"<code>/alert ETH/BTC &lt; 0.03</code> — ETH priced in BTC\n"+
+			"<code>/alert ETH/BTC &lt; 0.03 quote</code> — also confirm with a live quote\n\n"+
+			"Alerts fire once. /alerts to list or delete them.", nil)
+		return
+	}
+	a, err := parseAlertCommand(args)
+	if err != nil {
+		tgSendMessage(chatID, "❌ "+err.Error(), nil)
+		return
+	}
+	rate, ok := alertRate(&a)
+	if !ok {
+		tgSendMessage(chatID, "❌ No price for "+html.EscapeString(a.pair())+" right now. Try again later.", nil)
+		return
+	}
+	if a.crossed(rate) {
+		tgSendMessage(chatID, fmt.Sprintf("%s is already at %s. Pick a level it hasn't reached yet.",
+			html.EscapeString(a.pair()), html.EscapeString(a.formatRate(rate))), nil)
+		return
+	}
+	a.Created = time.Now().UTC()
+	a, err = priceAlerts.add(chatID, a)
+	if err != nil {
+		tgSendMessage(chatID, "❌ "+err.Error(), nil)
+		return
+	}
+	tgSendMessage(chatID, fmt.Sprintf("🔔 Alert #%d set: %s (now %s).\n<i>/alerts to list or delete.</i>",
+		a.ID, a.describe(), html.EscapeString(a.formatRate(rate))), nil)
+}
+
+// handleTGAlerts lists the chat's alerts with delete buttons.
+func handleTGAlerts(chatID int64) {
+	text, markup := renderAlertList(chatID)
+	tgSendMessage(chatID, text, markup)
+}
+
+func renderAlertList(chatID int64) (string, *TGInlineKeyboardMarkup) {
+	list := priceAlerts.list(chatID)
+	if len(list) == 0 {
+		return "You have no price alerts. Set one with <code>/alert BTC &gt; 100000</code>.", nil
+	}
+	var sb strings.Builder
+	sb.WriteString("<b>🔔 Your price alerts</b>\n")
+	var rows [][]TGInlineKeyboardButton
+	var row []TGInlineKeyboardButton
+	for _, a := range list {
+		fmt.Fprintf(&sb, "\n#%d  %s", a.ID, a.describe())
+		if rate, ok := alertRate(&a); ok {
+			sb.WriteString(" · now " + html.EscapeString(a.formatRate(rate)))
+		}
+		row = append(row, TGInlineKeyboardButton{Text: fmt.Sprintf("🗑 #%d", a.ID), CallbackData: fmt.Sprintf("ad:%d", a.ID)})
+		if len(row) == 4 {
+			rows, row = append(rows, row), nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, []TGInlineKeyboardButton{{Text: "Delete all", CallbackData: "ad:all", Style: "danger"}})
+	return sb.String(), &TGInlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handleTGAlertDelete handles the /alerts delete buttons ("ad:<id>" or
+// "ad:all") and redraws the list in place.
+func handleTGAlertDelete(chatID int64, msgID int, arg string) {
+	if arg == "all" {
+		priceAlerts.clear(chatID)
+	} else if id, err := strconv.Atoi(arg); err == nil {
+		priceAlerts.remove(chatID, id)
+	}
+	text, markup := renderAlertList(chatID)
+	tgEditMessage(chatID, msgID, text, markup)
+}
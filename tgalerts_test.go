@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withAlerts gives the test an empty alert store backed by a temp file and
+// the simulator's tokens in the cache.
+func withAlerts(t *testing.T) {
+	t.Helper()
+	withTokenSnapshot(t)
+	installTokens(append([]TokenInfo(nil), simulatorTokens...), time.Now(), false)
+	withKeyring(t, testKey(0x21))
+	saved, savedPath := priceAlerts.chats, alertStorePath
+	priceAlerts.chats = make(map[int64][]priceAlert)
+	alertStorePath = filepath.Join(t.TempDir(), "alerts.bin")
+	t.Cleanup(func() { priceAlerts.chats, alertStorePath = saved, savedPath })
+}
+
+// setTokenPrice reinstalls the simulator tokens with one ticker repriced.
+func setTokenPrice(ticker string, price float64) {
+	tokens := append([]TokenInfo(nil), simulatorTokens...)
+	for i := range tokens {
+		if tokens[i].Symbol == ticker {
+			tokens[i].Price = price
+		}
+	}
+	installTokens(tokens, time.Now(), false)
+}
+
+func TestParseAlertCommand(t *testing.T) {
+	withAlerts(t)
+	for _, tc := range []struct {
+		args string
+		want string // pair op level net/quoteNet check
+	}{
+		{"BTC > 100000", "BTC > 100000 btc/ false"},
+		{"eth < 2,000", "ETH < 2000 eth/ false"},
+		{"bitcoin>70000", "BTC > 70000 btc/ false"},
+		{"ETH/BTC < 0.03", "ETH/BTC < 0.03 eth/btc false"},
+		{"sol/eth > 0.07 QUOTE", "SOL/ETH > 0.07 sol/eth true"},
+		{"USDC > 1.01", "USDC > 1.01 eth/ false"},
+	} {
+		a, err := parseAlertCommand(tc.args)
+		if err != nil {
+			t.Errorf("%q: %v", tc.args, err)
+			continue
+		}
+		got := strings.Join([]string{a.pair(), a.op(), fmtEstimate(a.Level), a.BaseNet + "/" + a.QuoteNet, map[bool]string{true: "true", false: "false"}[a.CheckQuote]}, " ")
+		if got != tc.want {
+			t.Errorf("%q parsed as %q, want %q", tc.args, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"BTC 100000", "> 5", "BTC > ", "BTC > -1", "BTC > NaN", "BTC > 1 2", "NOPE > 1", "ETH/NOPE < 1", "ETH/ETH < 1", "BTC > 1 quote"} {
+		if _, err := parseAlertCommand(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestSealAtRest(t *testing.T) {
+	withKeyring(t, testKey(1))
+	packed, err := sealAtRest("tg-alerts", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := openAtRest("tg-alerts", packed); err != nil || string(got) != "hello" {
+		t.Fatalf("round trip = %q, %v", got, err)
+	}
+	if _, err := openAtRest("other", packed); err == nil {
+		t.Error("a different purpose must not open the data")
+	}
+	if _, err := decryptOrderData(base64.RawURLEncoding.EncodeToString(packed)); err == nil {
+		t.Error("at-rest data must not open as an order token")
+	}
+
+	withKeyring(t, testKey(2), testKey(1))
+	if got, err := openAtRest("tg-alerts", packed); err != nil || string(got) != "hello" {
+		t.Errorf("a previous key should still open the data: %q, %v", got, err)
+	}
+	withKeyring(t, testKey(2))
+	if _, err := openAtRest("tg-alerts", packed); err == nil {
+		t.Error("a retired key's data should no longer open")
+	}
+}
+
+func TestAlertStorePersistence(t *testing.T) {
+	withAlerts(t)
+	withKeyring(t, testKey(3))
+	a, _ := parseAlertCommand("BTC > 100000")
+	for i := 0; i < maxAlertsPerChat; i++ {
+		if _, err := priceAlerts.add(42, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := priceAlerts.add(42, a); err == nil {
+		t.Errorf("more than %d alerts per chat should be refused", maxAlertsPerChat)
+	}
+	priceAlerts.remove(42, 3)
+	priceAlerts.add(7, a)
+
+	raw, err := os.ReadFile(alertStorePath)
+	if err != nil || strings.Contains(string(raw), "BTC") {
+		t.Fatalf("store should exist and be encrypted: %v", err)
+	}
+
+	loaded := &alertStore{chats: make(map[int64][]priceAlert)}
+	loaded.load()
+	if loaded.count() != maxAlertsPerChat || len(loaded.list(42)) != maxAlertsPerChat-1 || loaded.list(7)[0].ID != 1 {
+		t.Errorf("reloaded store = %+v", loaded.chats)
+	}
+	if next, _ := loaded.add(42, a); next.ID != maxAlertsPerChat+1 {
+		t.Errorf("IDs should not be reused, got #%d", next.ID)
+	}
+
+	withKeyring(t, testKey(4))
+	other := &alertStore{chats: make(map[int64][]priceAlert)}
+	other.load()
+	if other.count() != 0 {
+		t.Error("a store sealed with an unknown key should load empty")
+	}
+
+	// Without ORDER_SECRET the key is random: alerts stay in memory only.
+	keyring.ephemeral = true
+	os.Remove(alertStorePath)
+	priceAlerts.add(7, a)
+	if _, err := os.Stat(alertStorePath); !os.IsNotExist(err) {
+		t.Errorf("alerts sealed with an ephemeral key should not be written: %v", err)
+	}
+	if len(priceAlerts.list(7)) != 2 {
+		t.Error("alerts should still be kept in memory")
+	}
+}
+
+func TestPriceAlertFires(t *testing.T) {
+	withAlerts(t)
+	tg := withFakeTelegram(t)
+	const chatID = 9001
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	handleTGAlert(chatID, "BTC > 50000")
+	handleTGAlert(chatID, "BTC > 65000")
+	handleTGAlert(chatID, "ETH < 2000")
+	if got := tg.texts(); !strings.Contains(got[0], "already at $60,000") || !strings.Contains(got[1], "Alert #1 set") {
+		t.Fatalf("replies = %q", got)
+	}
+
+	evaluatePriceAlerts()
+	if n := len(tg.texts()); n != 3 {
+		t.Fatalf("nothing should fire yet, got %d messages", n)
+	}
+
+	setTokenPrice("BTC", 66000)
+	evaluatePriceAlerts()
+	evaluatePriceAlerts() // one-shot
+	got := tg.texts()
+	if len(got) != 5 || !strings.Contains(got[3], "<b>BTC</b> rose above $65,000 — now $66,000") {
+		t.Fatalf("after BTC moved: %q", got)
+	}
+	if !strings.Contains(got[4], "<pre>") || tgSessions.get(chatID).FromTicker != "BTC" || tgSessions.get(chatID).ToTicker != "USDC" {
+		t.Errorf("expected a BTC → USDC swap card, session = %+v", tgSessions.get(chatID))
+	}
+	if list := priceAlerts.list(chatID); len(list) != 1 || list[0].Base != "ETH" {
+		t.Errorf("remaining alerts = %+v", list)
+	}
+
+	// A chat with an order open keeps its card and gets a button instead.
+	tgSessions.get(chatID).State = stateOrderActive
+	setTokenPrice("ETH", 1900)
+	evaluatePriceAlerts()
+	tg.mu.Lock()
+	last := tg.calls[len(tg.calls)-1]
+	tg.mu.Unlock()
+	markup, _ := json.Marshal(last.Payload["reply_markup"])
+	if !strings.Contains(last.Payload["text"].(string), "fell below") || !strings.Contains(string(markup), `"ap:USDC-eth_ETH-eth"`) {
+		t.Errorf("busy chat got %+v", last.Payload)
+	}
+	if tgSessions.get(chatID).State != stateOrderActive {
+		t.Error("the open order should be left alone")
+	}
+}
+
+func TestPriceAlertQuoteCheck(t *testing.T) {
+	withAlerts(t)
+	withSimulator(t)
+	tg := withFakeTelegram(t)
+	const chatID = 9002
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	// ETH/BTC is 0.041667 from cached prices; the simulator's spread
+	// quotes 0.041625.
+	for _, args := range []string{"ETH/BTC > 0.04165 quote", "ETH/BTC > 0.0416 quote"} {
+		a, err := parseAlertCommand(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		priceAlerts.add(chatID, a)
+	}
+	evaluatePriceAlerts()
+	if list := priceAlerts.list(chatID); len(list) != 1 || list[0].Level != 0.04165 {
+		t.Errorf("only the level the quote reaches should fire, left %+v", list)
+	}
+	if got := tg.texts(); len(got) < 1 || !strings.Contains(got[0], "Confirmed by a 1Click quote") {
+		t.Errorf("messages = %q", got)
+	}
+}
+
+func TestAlertListDelete(t *testing.T) {
+	withAlerts(t)
+	tg := withFakeTelegram(t)
+	const chatID = 9003
+	for _, args := range []string{"BTC > 70000", "SOL < 100", "ETH/BTC > 0.05"} {
+		handleTGAlert(chatID, args)
+	}
+	handleTGAlerts(chatID)
+	list := tg.texts()[3]
+	for _, want := range []string{"#1  <b>BTC</b> &gt; $70,000", "#2  <b>SOL</b> &lt; $100", "#3  <b>ETH/BTC</b> &gt; 0.05 BTC"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("list is missing %q:\n%s", want, list)
+		}
+	}
+
+	handleTGAlertDelete(chatID, 100, "2")
+	if got := priceAlerts.list(chatID); len(got) != 2 || got[1].ID != 3 {
+		t.Errorf("after deleting #2: %+v", got)
+	}
+	handleTGAlertDelete(chatID, 100, "all")
+	if priceAlerts.count() != 0 || !strings.Contains(tg.texts()[5], "no price alerts") {
+		t.Errorf("delete all left %d alerts", priceAlerts.count())
+	}
+}
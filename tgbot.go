@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -12,6 +13,8 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 )
 
 // Telegram bot configuration
@@ -22,10 +25,14 @@ var (
 	tgAPIBase       string
 	tgBotUsername   string
 	tgHTTPClient    = &http.Client{}
+	tgUsePolling    bool // TG_UPDATE_MODE=polling; see tgpoll.go
 )
 
-// initTelegramBot reads env vars and registers the webhook.
-// Returns true if the bot is enabled (TG_BOT_TOKEN is set).
+// tgAllowedUpdates are the update types the bot asks Telegram for.
+var tgAllowedUpdates = []string{"message", "callback_query", "inline_query"}
+
+// initTelegramBot reads env vars and registers the webhook, or removes it
+// in polling mode. Returns true if the bot is enabled (TG_BOT_TOKEN is set).
 func initTelegramBot() bool {
 	tgBotToken = os.Getenv("TG_BOT_TOKEN")
 	if tgBotToken == "" {
@@ -34,8 +41,10 @@ func initTelegramBot() bool {
 
 	tgAPIBase = "https://api.telegram.org/bot" + tgBotToken
 
+	tgUsePolling = parseTGUpdateMode(os.Getenv("TG_UPDATE_MODE"))
+
 	tgWebhookSecret = os.Getenv("TG_WEBHOOK_SECRET")
-	if tgWebhookSecret == "" {
+	if tgWebhookSecret == "" && !tgUsePolling {
 		b := make([]byte, 16)
 		rand.Read(b)
 		tgWebhookSecret = hex.EncodeToString(b)
@@ -47,10 +56,21 @@ func initTelegramBot() bool {
 		tgAppURL = "https://zero.uswap.net"
 	}
 
-	// Register webhook
-	appURL := tgAppURL + "/tg/webhook/" + tgWebhookSecret
-	if err := tgSetWebhook(appURL); err != nil {
-		log.Printf("WARNING: Failed to set Telegram webhook: %v", err)
+	if tgUsePolling {
+		// getUpdates is refused while a webhook is set
+		err := tgDeleteWebhook()
+		if err != nil {
+			log.Printf("WARNING: Failed to remove Telegram webhook: %v", err)
+		}
+		tgWebhook.set(err)
+	} else {
+		// Register webhook
+		appURL := tgAppURL + "/tg/webhook/" + tgWebhookSecret
+		err := tgSetWebhook(appURL)
+		if err != nil {
+			log.Printf("WARNING: Failed to set Telegram webhook: %v", err)
+		}
+		tgWebhook.set(err)
 	}
 
 	// Fetch bot info (needed for deep links)
@@ -181,12 +201,29 @@ type TGSentMessage struct {
 
 // tgRequest makes a JSON POST to the Telegram Bot API.
 func tgRequest(method string, payload interface{}) (json.RawMessage, error) {
+	return tgRequestContext(context.Background(), method, payload)
+}
+
+// tgRequestContext is tgRequest with a context, for calls that must stop
+// on shutdown (getUpdates).
+func tgRequestContext(ctx context.Context, method string, payload interface{}) (result json.RawMessage, err error) {
+	defer func() {
+		if err != nil {
+			tgAPIErrors.inc(method)
+		}
+	}()
+
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("tg marshal: %w", err)
 	}
 
-	resp, err := tgHTTPClient.Post(tgAPIBase+"/"+method, "application/json", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tgAPIBase+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("tg request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := tgHTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("tg request: %w", err)
 	}
@@ -271,7 +308,13 @@ func tgAnswerCallback(callbackID string, text string) {
 }
 
 // tgSendPhoto sends a photo (PNG bytes) with a caption and inline keyboard.
-func tgSendPhoto(chatID int64, pngData []byte, caption string, markup *TGInlineKeyboardMarkup) (*TGSentMessage, error) {
+func tgSendPhoto(chatID int64, pngData []byte, caption string, markup *TGInlineKeyboardMarkup) (sent *TGSentMessage, err error) {
+	defer func() {
+		if err != nil {
+			tgAPIErrors.inc("sendPhoto")
+		}
+	}()
+
 	var buf bytes.Buffer
 	w := multipart.NewWriter(&buf)
 
@@ -342,7 +385,7 @@ func tgGetMe() {
 func tgSetWebhook(url string) error {
 	payload := map[string]interface{}{
 		"url":             url,
-		"allowed_updates": []string{"message", "callback_query", "inline_query"},
+		"allowed_updates": tgAllowedUpdates,
 	}
 	_, err := tgRequest("setWebhook", payload)
 	if err != nil {
@@ -352,12 +395,50 @@ func tgSetWebhook(url string) error {
 	return nil
 }
 
+// tgDeleteWebhook removes any registered webhook so getUpdates can be
+// used. Pending updates are kept for the poller.
+func tgDeleteWebhook() error {
+	_, err := tgRequest("deleteWebhook", map[string]interface{}{"drop_pending_updates": false})
+	if err != nil {
+		return err
+	}
+	log.Printf("Telegram webhook removed; using long polling")
+	return nil
+}
+
+// tgWebhookStatus records the outcome of webhook registration for /readyz.
+// In polling mode it records the last getUpdates outcome instead.
+type tgWebhookStatus struct {
+	mu         sync.Mutex
+	enabled    bool
+	registered bool
+	lastErr    string
+	at         time.Time
+}
+
+var tgWebhook = &tgWebhookStatus{}
+
+func (s *tgWebhookStatus) set(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = true
+	s.registered = err == nil
+	s.lastErr = ""
+	if err != nil {
+		s.lastErr = err.Error()
+	}
+	s.at = time.Now()
+}
+
 // tgSetCommands registers the bot's command list.
 func tgSetCommands() {
 	commands := []map[string]string{
 		{"command": "start", "description": "Start a new swap"},
 		{"command": "verify", "description": "Verify deployment integrity"},
 		{"command": "status", "description": "Check order status"},
+		{"command": "orders", "description": "List your active orders"},
+		{"command": "alert", "description": "Set a price alert, e.g. /alert BTC > 100000"},
+		{"command": "alerts", "description": "List and delete price alerts"},
 	}
 	payload := map[string]interface{}{
 		"commands": commands,
@@ -30,24 +30,34 @@ func handleTelegramWebhook(w http.ResponseWriter, r *http.Request) {
 	// Always respond 200 to acknowledge the update
 	w.WriteHeader(http.StatusOK)
 
+	routeTGUpdate(&update)
+}
+
+// routeTGUpdate dispatches one update, from the webhook or the poller, to
+// its handler in the background.
+func routeTGUpdate(update *TGUpdate) {
 	// Track subscriber from any interaction
-	if chatID := extractChatID(&update); chatID != 0 {
+	if chatID := extractChatID(update); chatID != 0 {
 		go subscribers.track(chatID)
 	}
 
 	// Route to handler
-	if update.InlineQuery != nil {
-		go handleTGInlineQuery(update.InlineQuery)
-		return
-	}
-	if update.CallbackQuery != nil {
-		go handleTGCallback(update.CallbackQuery)
-		return
-	}
-	if update.Message != nil {
-		go handleTGMessage(update.Message)
+	var handle func()
+	switch {
+	case update.InlineQuery != nil:
+		handle = func() { handleTGInlineQuery(update.InlineQuery) }
+	case update.CallbackQuery != nil:
+		handle = func() { handleTGCallback(update.CallbackQuery) }
+	case update.Message != nil:
+		handle = func() { handleTGMessage(update.Message) }
+	default:
 		return
 	}
+	tgUpdates.Add(1)
+	go func() {
+		defer tgUpdates.Done()
+		handle()
+	}()
 }
 
 // handleTGMessage routes text messages and commands.
@@ -76,6 +86,16 @@ func handleTGMessage(msg *TGMessage) {
 			handleTGForget(chatID)
 		case "/subscribe":
 			handleTGSubscribe(chatID)
+		case "/alert":
+			args := ""
+			if len(cmd) > 1 {
+				args = cmd[1]
+			}
+			handleTGAlert(chatID, args)
+		case "/alerts":
+			handleTGAlerts(chatID)
+		case "/orders":
+			handleTGOrders(chatID)
 		case "/status":
 			if len(cmd) > 1 {
 				handleTGStatus(chatID, strings.TrimSpace(cmd[1]))
@@ -102,6 +122,8 @@ func handleTGMessage(msg *TGMessage) {
 		handleTGRefundInput(chatID, sess, msg)
 	case stateEnterRecv:
 		handleTGRecvInput(chatID, sess, msg)
+	case stateEnterAddrLabel:
+		handleTGAddrLabelInput(chatID, sess, msg)
 	case statePickToken:
 		// Token search by typing
 		handleTGTokenSearch(chatID, sess, msg)
@@ -179,13 +201,28 @@ func handleTGCallback(cb *TGCallbackQuery) {
 		handleTGBackToCard(chatID, sess)
 	case data == "rs":
 		tgAnswerCallback(cb.ID, "Refreshing...")
-		handleTGRefreshStatus(chatID, sess)
+		handleTGRefreshStatus(chatID, sess, cb.Message.MessageID)
 	case data == "dm":
 		tgAnswerCallback(cb.ID, "Messages deleted")
-		handleTGDeleteMessages(chatID, sess)
+		handleTGDeleteMessages(chatID, sess, cb.Message.MessageID)
 	case data == "ns":
 		tgAnswerCallback(cb.ID, "")
 		handleTGNewSwap(chatID, sess)
+	case strings.HasPrefix(data, "or:"):
+		tgAnswerCallback(cb.ID, "Refreshing...")
+		handleTGOrderRefresh(chatID, sess, cb.Message.MessageID, data[3:])
+	case strings.HasPrefix(data, "oc:"):
+		tgAnswerCallback(cb.ID, "Cleared")
+		handleTGOrderClear(chatID, sess, cb.Message.MessageID, data[3:])
+	case strings.HasPrefix(data, "ab:"):
+		tgAnswerCallback(cb.ID, "")
+		handleTGAddressBook(chatID, sess, cb.Message.MessageID, data[3:])
+	case strings.HasPrefix(data, "ad:"):
+		tgAnswerCallback(cb.ID, "Deleted")
+		handleTGAlertDelete(chatID, cb.Message.MessageID, data[3:])
+	case strings.HasPrefix(data, "ap:"):
+		tgAnswerCallback(cb.ID, "")
+		sendPrefilledCard(chatID, sess, data[3:])
 	default:
 		tgAnswerCallback(cb.ID, "")
 	}
@@ -198,10 +235,8 @@ func handleTGStart(chatID int64, startParam string) {
 	sess.mu.Lock()
 	defer sess.mu.Unlock()
 
-	// Delete old card if exists
-	if sess.CardMsgID != 0 {
-		tgDeleteMessage(chatID, sess.CardMsgID)
-	}
+	// Delete old card if exists (order cards stay; see /orders)
+	sess.releaseCard(chatID)
 
 	sess.reset()
 	if strings.HasPrefix(startParam, "swap_") {
@@ -230,9 +265,7 @@ func handleTGVerify(chatID int64) {
 
 // handleTGNewSwap starts a fresh swap while lock is already held.
 func handleTGNewSwap(chatID int64, sess *tgSession) {
-	if sess.CardMsgID != 0 {
-		tgDeleteMessage(chatID, sess.CardMsgID)
-	}
+	sess.releaseCard(chatID)
 
 	sess.reset()
 	sess.State = stateSwapCard
@@ -249,6 +282,7 @@ func handleTGNewSwap(chatID int64, sess *tgSession) {
 
 // handleTGStatus looks up an order by token, sends the unified order card,
 // and wires it into the session so Refresh/Clear/New Swap buttons work.
+// Unfinished orders get a watcher that keeps the card up to date.
 func handleTGStatus(chatID int64, token string) {
 	order, err := decryptOrderData(token)
 	if err != nil {
@@ -256,7 +290,9 @@ func handleTGStatus(chatID int64, token string) {
 		return
 	}
 
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
 	if err != nil {
 		tgSendMessage(chatID, "❌ Status check failed: "+err.Error(), nil)
 		return
@@ -269,9 +305,7 @@ func handleTGStatus(chatID int64, token string) {
 	defer sess.mu.Unlock()
 
 	// Replace any existing card
-	if sess.CardMsgID != 0 {
-		tgDeleteMessage(chatID, sess.CardMsgID)
-	}
+	sess.releaseCard(chatID)
 
 	msg, err := tgSendMessage(chatID, cardText, markup)
 	if err != nil {
@@ -281,13 +315,20 @@ func handleTGStatus(chatID int64, token string) {
 	sess.CardMsgID = msg.MessageID
 	sess.OrderToken = token
 	sess.State = stateOrderActive
+	if !isTerminalStatus(status.Status) {
+		sess.addOrder(token, msg.MessageID, order, status.Status)
+		tgWatchers.watch(chatID, order, token, status.Status)
+	}
 }
 
 // handleTGForget removes the user from subscribers and hashes their ID so
-// the opt-out persists without storing their actual ID.
+// the opt-out persists without storing their actual ID. Their price alerts
+// go too.
 func handleTGForget(chatID int64) {
 	subscribers.forget(chatID)
-	tgSendMessage(chatID, "Done — you've been forgotten. No updates will be sent.\n\nYou can still use the bot normally. /subscribe to re-subscribe.", nil)
+	priceAlerts.clear(chatID)
+	addressBook.forget(chatID)
+	tgSendMessage(chatID, "Done — you've been forgotten. No updates will be sent and your price alerts and saved addresses are deleted.\n\nYou can still use the bot normally. /subscribe to re-subscribe.", nil)
 }
 
 // handleTGSubscribe re-adds a previously forgotten user.
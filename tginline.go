@@ -89,14 +89,9 @@ func parseInlineQuery(raw string) parsedInlineQuery {
 // findAllTokenNetworks returns all tokens with an exact ticker match, one per chain,
 // in cache order (which reflects API ordering by liquidity/popularity).
 func findAllTokenNetworks(ticker string) []TokenInfo {
-	matches := searchTokens(ticker)
-	upper := strings.ToUpper(ticker)
 	seen := make(map[string]bool)
 	var result []TokenInfo
-	for _, t := range matches {
-		if strings.ToUpper(t.Ticker) != upper {
-			continue
-		}
+	for _, t := range tokensByTicker(ticker) {
 		key := strings.ToLower(t.ChainName)
 		if !seen[key] {
 			seen[key] = true
@@ -144,11 +139,11 @@ func buildEmptyResults() []interface{} {
 		fromLabel := tokenLabel(from.Ticker, from.ChainName)
 		toLabel := tokenLabel(to.Ticker, to.ChainName)
 		title := fmt.Sprintf("Swap %s → %s", fromLabel, toLabel)
-		desc := networkDisplayName(from.ChainName) + " → " + networkDisplayName(to.ChainName) + " · Zero fees"
+		desc := chainName(from.ChainName) + " → " + chainName(to.ChainName) + " · Zero fees"
 		if from.Price > 0 && to.Price > 0 {
 			desc = fmt.Sprintf("1 %s ≈ %s %s · %s → %s",
 				fromLabel, fmtEstimate(from.Price/to.Price), toLabel,
-				networkDisplayName(from.ChainName), networkDisplayName(to.ChainName))
+				chainName(from.ChainName), chainName(to.ChainName))
 		}
 		results = append(results, buildSwapArticle(
 			fmt.Sprintf("empty-%d", i),
@@ -200,11 +195,11 @@ func buildSingleTokenResults(query string) []interface{} {
 				fromLabel := tokenLabel(from.Ticker, from.ChainName)
 				toLabel := tokenLabel(to.Ticker, to.ChainName)
 				title := fmt.Sprintf("Swap %s → %s", fromLabel, toLabel)
-				desc := networkDisplayName(from.ChainName) + " → " + networkDisplayName(to.ChainName) + " · Zero fees"
+				desc := chainName(from.ChainName) + " → " + chainName(to.ChainName) + " · Zero fees"
 				if from.Price > 0 && to.Price > 0 {
 					desc = fmt.Sprintf("1 %s ≈ %s %s · %s → %s",
 						fromLabel, fmtEstimate(from.Price/to.Price), toLabel,
-						networkDisplayName(from.ChainName), networkDisplayName(to.ChainName))
+						chainName(from.ChainName), chainName(to.ChainName))
 				}
 				results = append(results, buildSwapArticle(
 					fmt.Sprintf("single-%d-%s-%d", i, strings.ToLower(targetTicker), k),
@@ -249,11 +244,11 @@ func buildPairResults(fromQuery, toQuery string) []interface{} {
 		fromLabel := tokenLabel(from.Ticker, from.ChainName)
 		toLabel := tokenLabel(toVar.Ticker, toVar.ChainName)
 		title := fmt.Sprintf("Swap %s → %s", fromLabel, toLabel)
-		desc := networkDisplayName(from.ChainName) + " → " + networkDisplayName(toVar.ChainName) + " · Zero fees"
+		desc := chainName(from.ChainName) + " → " + chainName(toVar.ChainName) + " · Zero fees"
 		if from.Price > 0 && toVar.Price > 0 {
 			desc = fmt.Sprintf("1 %s ≈ %s %s · %s → %s",
 				fromLabel, fmtEstimate(from.Price/toVar.Price), toLabel,
-				networkDisplayName(from.ChainName), networkDisplayName(toVar.ChainName))
+				chainName(from.ChainName), chainName(toVar.ChainName))
 		}
 		results = append(results, buildSwapArticle(
 			fmt.Sprintf("pair-fwd-%d", i),
@@ -271,11 +266,11 @@ func buildPairResults(fromQuery, toQuery string) []interface{} {
 		toLabel := tokenLabel(to.Ticker, to.ChainName)
 		fromLabel := tokenLabel(fromVar.Ticker, fromVar.ChainName)
 		title := fmt.Sprintf("Swap %s → %s", toLabel, fromLabel)
-		desc := networkDisplayName(to.ChainName) + " → " + networkDisplayName(fromVar.ChainName) + " · Zero fees"
+		desc := chainName(to.ChainName) + " → " + chainName(fromVar.ChainName) + " · Zero fees"
 		if to.Price > 0 && fromVar.Price > 0 {
 			desc = fmt.Sprintf("1 %s ≈ %s %s · %s → %s",
 				toLabel, fmtEstimate(to.Price/fromVar.Price), fromLabel,
-				networkDisplayName(to.ChainName), networkDisplayName(fromVar.ChainName))
+				chainName(to.ChainName), chainName(fromVar.ChainName))
 		}
 		results = append(results, buildSwapArticle(
 			fmt.Sprintf("pair-rev-%d", i),
@@ -313,11 +308,11 @@ func buildPairAmountResults(fromQuery, toQuery, amount string) []interface{} {
 		title := fmt.Sprintf("Swap %s %s → %s", amount, fromLabel, toLabel)
 
 		outAmt, outUSD := estimateOutputForTokens(from, to, amount)
-		desc := networkDisplayName(from.ChainName) + " → " + networkDisplayName(to.ChainName) + " · Tap to quote"
+		desc := chainName(from.ChainName) + " → " + chainName(to.ChainName) + " · Tap to quote"
 		if outAmt != "" {
 			desc = fmt.Sprintf("≈ %s %s (%s) · %s → %s",
 				outAmt, toLabel, outUSD,
-				networkDisplayName(from.ChainName), networkDisplayName(to.ChainName))
+				chainName(from.ChainName), chainName(to.ChainName))
 		}
 		results = append(results, buildSwapArticle(
 			fmt.Sprintf("amount-%d", i),
@@ -336,7 +331,9 @@ func buildStatusResults(token string) []interface{} {
 		return nil
 	}
 
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
 	if err != nil {
 		return nil
 	}
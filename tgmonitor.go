@@ -101,7 +101,7 @@ func postMonitorCard(groupID, threadID int64, resellerName string, tx ExplorerTx
 	// NEAR tx — hyperlinked
 	if len(tx.NearTxHashes) > 0 && tx.NearTxHashes[0] != "" {
 		hash := tx.NearTxHashes[0]
-		sb.WriteString("\nNEAR: <a href=\"https://nearblocks.io/txns/" + hash + "\">" + hash + "</a>")
+		sb.WriteString("\nNEAR: <a href=\"" + lookupChain("near").TxLink(hash) + "\">" + hash + "</a>")
 	}
 
 	payload := map[string]interface{}{
@@ -65,7 +65,9 @@ func handleTGGetQuote(chatID int64, sess *tgSession) {
 		AppFees:            []struct{}{},
 	}
 
-	dryResp, err := requestDryQuote(req)
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	dryResp, err := requestDryQuote(ctx, req)
 	if err != nil {
 		showErrorAndCard(chatID, sess, "Quote failed: "+err.Error())
 		return
@@ -164,11 +166,18 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 		AppFees:            []struct{}{},
 	}
 
-	quoteResp, err := requestQuote(req)
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	quoteResp, err := requestQuote(ctx, req)
 	if err != nil {
 		showErrorAndCard(chatID, sess, "Quick swap failed: "+err.Error())
 		return
 	}
+	quoteSig, err := checkQuoteSignature(quoteResp)
+	if err != nil {
+		showErrorAndCard(chatID, sess, "Quick swap failed: the quote failed signature verification, so its deposit address can't be trusted. Please try again.")
+		return
+	}
 
 	order := &OrderData{
 		DepositAddr: quoteResp.Quote.DepositAddress,
@@ -184,8 +193,11 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 		RefundAddr:  sess.RefundAddr,
 		RecvAddr:    sess.RecvAddr,
 		SwapType:    "ANY_INPUT",
+		QuoteSig:    quoteSig,
 	}
 
+	order.TranscriptHash = keepTranscript(order, quoteResp)
+
 	orderToken, err := encryptOrderData(order)
 	if err != nil {
 		log.Printf("tg encrypt any_input order error: %v", err)
@@ -198,7 +210,7 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 	depositCard := "<pre>" + renderAnyInputDepositCardMono(AnyInputCardData{
 		FromTicker: sess.FromTicker,
 		ToTicker:   sess.ToTicker,
-		Network:    networkDisplayName(sess.FromNet),
+		Network:    chainName(sess.FromNet),
 		RefundAddr: sess.RefundAddr,
 		RecvAddr:   sess.RecvAddr,
 	}) + "</pre>"
@@ -221,6 +233,8 @@ func handleTGAnyInputSwap(chatID int64, sess *tgSession, fromToken, toToken *Tok
 	if err := tgEditMessage(chatID, sess.CardMsgID, depositCard, markup); err != nil {
 		log.Printf("tg edit any_input deposit card error: %v", err)
 	}
+	sess.addOrder(orderToken, sess.CardMsgID, order, "PENDING_DEPOSIT")
+	tgWatchers.watch(chatID, order, orderToken, "PENDING_DEPOSIT")
 }
 
 // handleTGConfirmSwap places a real quote and shows the unified deposit/order card.
@@ -270,11 +284,18 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 		AppFees:            []struct{}{},
 	}
 
-	quoteResp, err := requestQuote(req)
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	quoteResp, err := requestQuote(ctx, req)
 	if err != nil {
 		showErrorAndCard(chatID, sess, "Order failed: "+err.Error())
 		return
 	}
+	quoteSig, err := checkQuoteSignature(quoteResp)
+	if err != nil {
+		showErrorAndCard(chatID, sess, "Order failed: the quote failed signature verification, so its deposit address can't be trusted. Please try again.")
+		return
+	}
 
 	// For FLEX_INPUT, use the user's original amount (the API may return a
 	// different amountIn since FLEX_INPUT accepts a range).
@@ -301,8 +322,11 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 		RefundAddr:  sess.RefundAddr,
 		RecvAddr:    sess.RecvAddr,
 		SwapType:    swapType,
+		QuoteSig:    quoteSig,
 	}
 
+	order.TranscriptHash = keepTranscript(order, quoteResp)
+
 	orderToken, err := encryptOrderData(order)
 	if err != nil {
 		log.Printf("tg encrypt order error: %v", err)
@@ -311,7 +335,7 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 	sess.OrderToken = orderToken
 	sess.State = stateOrderActive
 
-	netName := networkDisplayName(sess.FromNet)
+	netName := chainName(sess.FromNet)
 	timeLeft := deadlineString(quoteResp.Quote.Deadline)
 
 	// Build unified deposit/order card (step 0 of stepper)
@@ -346,6 +370,8 @@ func handleTGConfirmSwap(chatID int64, sess *tgSession) {
 	if err := tgEditMessage(chatID, sess.CardMsgID, depositCard, markup); err != nil {
 		log.Printf("tg edit deposit card error: %v", err)
 	}
+	sess.addOrder(orderToken, sess.CardMsgID, order, "PENDING_DEPOSIT")
+	tgWatchers.watch(chatID, order, orderToken, "PENDING_DEPOSIT")
 }
 
 // handleTGCancelQuote returns to the swap card by editing CardMsgID in place.
@@ -372,12 +398,12 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 			cardText = "<pre>" + renderAnyInputDepositCardMono(AnyInputCardData{
 				FromTicker: order.FromTicker,
 				ToTicker:   order.ToTicker,
-				Network:    networkDisplayName(order.FromNet),
+				Network:    chainName(order.FromNet),
 				RefundAddr: order.RefundAddr,
 				RecvAddr:   order.RecvAddr,
 			}) + "</pre>"
 		} else {
-			netName := networkDisplayName(order.FromNet)
+			netName := chainName(order.FromNet)
 			timeLeft := deadlineString(order.Deadline)
 			cardText = "<pre>" + renderDepositCardMono(DepositCardData{
 				FromTicker: order.FromTicker,
@@ -409,9 +435,9 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 
 	if status.SwapDetails != nil {
 		for _, tx := range status.SwapDetails.DestTxs {
-			if tx.ExplorerURL != "" {
+			if u := txExplorerURL(tx, order.ToNet); u != "" {
 				rows = append(rows, []TGInlineKeyboardButton{
-					{Text: "🔗 View TX", WebApp: &TGWebApp{URL: tx.ExplorerURL}},
+					{Text: "🔗 View TX", WebApp: &TGWebApp{URL: u}},
 				})
 				break
 			}
@@ -433,28 +459,44 @@ func buildOrderCard(order *OrderData, status *StatusResponse, orderToken string)
 	return cardText, &TGInlineKeyboardMarkup{InlineKeyboard: rows}
 }
 
-// handleTGRefreshStatus fetches and updates the order card in place.
-func handleTGRefreshStatus(chatID int64, sess *tgSession) {
-	if sess.OrderToken == "" {
+// handleTGRefreshStatus fetches and updates the order card msgID in place,
+// and restarts the order's watcher if it isn't running (e.g. after a
+// restart). msgID may be the card of any order the chat follows.
+func handleTGRefreshStatus(chatID int64, sess *tgSession, msgID int) {
+	token := sess.OrderToken
+	if ref := sess.orderByMsg(msgID); ref != nil {
+		token = ref.Token
+	} else {
+		msgID = sess.CardMsgID
+	}
+	if token == "" {
 		return
 	}
 
-	order, err := decryptOrderData(sess.OrderToken)
+	order, err := decryptOrderData(token)
 	if err != nil {
 		return
 	}
 
-	status, err := fetchStatus(order.DepositAddr, order.Memo)
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
 	if err != nil {
-		tgEditMessage(chatID, sess.CardMsgID, "❌ Status check failed: "+err.Error(), nil)
+		tgEditMessage(chatID, msgID, "❌ Status check failed: "+err.Error(), nil)
 		return
 	}
 
-	cardText, markup := buildOrderCard(order, status, sess.OrderToken)
+	cardText, markup := buildOrderCard(order, status, token)
 
-	if err := tgEditMessage(chatID, sess.CardMsgID, cardText, markup); err != nil {
+	if err := tgEditMessage(chatID, msgID, cardText, markup); err != nil {
 		log.Printf("tg refresh status edit error: %v", err)
 	}
+	if isTerminalStatus(status.Status) {
+		sess.dropOrder(token)
+		return
+	}
+	sess.addOrder(token, msgID, order, status.Status)
+	tgWatchers.watch(chatID, order, token, status.Status)
 }
 
 // isTerminalStatus returns true when the status indicates a finished swap.
@@ -481,8 +523,21 @@ func showErrorAndCard(chatID int64, sess *tgSession, errMsg string) {
 }
 
 // handleTGDeleteMessages deletes all tracked messages for the current swap.
-func handleTGDeleteMessages(chatID int64, sess *tgSession) {
+// Pressed on the card of an older order, it deletes just that card. Cards
+// of other orders the chat follows are left alone.
+func handleTGDeleteMessages(chatID int64, sess *tgSession, cardMsgID int) {
+	if cardMsgID != 0 && cardMsgID != sess.CardMsgID {
+		if ref := sess.orderByMsg(cardMsgID); ref != nil {
+			sess.dropOrder(ref.Token)
+		}
+		tgDeleteMessage(chatID, cardMsgID)
+		return
+	}
+	sess.dropOrder(sess.OrderToken)
 	for _, msgID := range sess.OrderMsgIDs {
+		if sess.orderByMsg(msgID) != nil {
+			continue
+		}
 		tgDeleteMessage(chatID, msgID)
 	}
 	if sess.DepositMsgID != 0 {
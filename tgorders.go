@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A chat can follow several orders at once. Each order placed in the bot
+// or opened with /status keeps its own card message and watcher (see
+// tgwatcher.go); /orders lists them with refresh, open and clear buttons.
+// Orders drop off the list when they reach a final status, when cleared,
+// or after tgOrderRetention.
+
+const maxTGOrders = 10
+
+// tgOrderRetention is how long an unfinished order stays listed
+// (TG_ORDER_RETENTION, a Go duration such as "48h"; default 24h).
+var tgOrderRetention = parseOrderRetention(os.Getenv("TG_ORDER_RETENTION"))
+
+func parseOrderRetention(s string) time.Duration {
+	if s == "" {
+		return 24 * time.Hour
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		log.Printf("WARNING: TG_ORDER_RETENTION %q is not a positive duration — using 24h", s)
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// tgOrderRef is one order a chat is following. MsgID is the message that
+// holds its order card.
+type tgOrderRef struct {
+	ID      int
+	Token   string
+	MsgID   int
+	Label   string // "0.5 ETH → SOL"
+	Status  string
+	Created time.Time
+}
+
+// addOrder starts following an order shown in card msgID, or moves an
+// already-followed order to that card. Past maxTGOrders the oldest order
+// is dropped. Caller holds sess.mu.
+func (sess *tgSession) addOrder(token string, msgID int, order *OrderData, status string) {
+	if ref := sess.orderByToken(token); ref != nil {
+		ref.MsgID, ref.Status = msgID, status
+		return
+	}
+	amount := order.AmountIn
+	if order.SwapType == "ANY_INPUT" {
+		amount = "any"
+	}
+	sess.nextOrderID++
+	sess.Orders = append(sess.Orders, tgOrderRef{
+		ID:      sess.nextOrderID,
+		Token:   token,
+		MsgID:   msgID,
+		Label:   amount + " " + order.FromTicker + " → " + order.ToTicker,
+		Status:  status,
+		Created: time.Now(),
+	})
+	if n := len(sess.Orders) - maxTGOrders; n > 0 {
+		for _, ref := range sess.Orders[:n] {
+			tgWatchers.stop(sess.chatID, ref.Token)
+		}
+		sess.Orders = append([]tgOrderRef(nil), sess.Orders[n:]...)
+	}
+}
+
+func (sess *tgSession) orderByToken(token string) *tgOrderRef {
+	for i := range sess.Orders {
+		if sess.Orders[i].Token == token {
+			return &sess.Orders[i]
+		}
+	}
+	return nil
+}
+
+func (sess *tgSession) orderByID(id int) *tgOrderRef {
+	for i := range sess.Orders {
+		if sess.Orders[i].ID == id {
+			return &sess.Orders[i]
+		}
+	}
+	return nil
+}
+
+// orderByMsg finds the order whose card is msgID.
+func (sess *tgSession) orderByMsg(msgID int) *tgOrderRef {
+	if msgID == 0 {
+		return nil
+	}
+	for i := range sess.Orders {
+		if sess.Orders[i].MsgID == msgID {
+			return &sess.Orders[i]
+		}
+	}
+	return nil
+}
+
+// dropOrder stops following an order and stops its watcher.
+func (sess *tgSession) dropOrder(token string) {
+	tgWatchers.stop(sess.chatID, token)
+	for i, ref := range sess.Orders {
+		if ref.Token == token {
+			sess.Orders = append(sess.Orders[:i:i], sess.Orders[i+1:]...)
+			return
+		}
+	}
+}
+
+// pruneOrders drops finished orders and ones older than tgOrderRetention,
+// stopping their watchers.
+func (sess *tgSession) pruneOrders(now time.Time) {
+	kept := sess.Orders[:0]
+	for _, ref := range sess.Orders {
+		if !isTerminalStatus(ref.Status) && now.Sub(ref.Created) < tgOrderRetention {
+			kept = append(kept, ref)
+		} else {
+			tgWatchers.stop(sess.chatID, ref.Token)
+		}
+	}
+	sess.Orders = kept
+}
+
+// releaseCard deletes the chat's current card before a new one is sent,
+// unless it is the card of an order still being followed.
+func (sess *tgSession) releaseCard(chatID int64) {
+	if sess.CardMsgID != 0 && sess.orderByMsg(sess.CardMsgID) == nil {
+		tgDeleteMessage(chatID, sess.CardMsgID)
+	}
+}
+
+// ── /orders ──
+
+// handleTGOrders lists the chat's orders.
+func handleTGOrders(chatID int64) {
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.pruneOrders(time.Now())
+	text, markup := renderOrderList(sess)
+	tgSendMessage(chatID, text, markup)
+}
+
+// renderOrderList draws one row per order, each with its own buttons.
+func renderOrderList(sess *tgSession) (string, *TGInlineKeyboardMarkup) {
+	if len(sess.Orders) == 0 {
+		return "No active orders. Use /start to begin a swap, or /status &lt;token&gt; to follow an existing order.", nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<b>📋 Active orders (%d)</b>\n", len(sess.Orders))
+	var rows [][]TGInlineKeyboardButton
+	for _, ref := range sess.Orders {
+		fmt.Fprintf(&sb, "\n<b>#%d</b>  %s\n      %s · %s", ref.ID, html.EscapeString(ref.Label),
+			html.EscapeString(statusDisplayName(ref.Status)), orderAge(time.Since(ref.Created)))
+		id := strconv.Itoa(ref.ID)
+		rows = append(rows, []TGInlineKeyboardButton{
+			{Text: "🔄 #" + id, CallbackData: "or:" + id},
+			{Text: "📱 Open #" + id, WebApp: &TGWebApp{URL: tgAppURL + "/order/" + ref.Token}},
+			{Text: "🗑 #" + id, CallbackData: "oc:" + id, Style: "danger"},
+		})
+	}
+	return sb.String(), &TGInlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// orderAge formats how long ago an order was created: "5m ago", "3h ago".
+func orderAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh ago", int(d.Hours()))
+}
+
+// handleTGOrderRefresh handles "or:<id>": fetch the order's status, redraw
+// its card and the list.
+func handleTGOrderRefresh(chatID int64, sess *tgSession, listMsgID int, arg string) {
+	id, _ := strconv.Atoi(arg)
+	ref := sess.orderByID(id)
+	if ref == nil {
+		redrawOrderList(chatID, sess, listMsgID)
+		return
+	}
+	order, err := decryptOrderData(ref.Token)
+	if err != nil {
+		sess.dropOrder(ref.Token)
+		redrawOrderList(chatID, sess, listMsgID)
+		return
+	}
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
+	if err != nil {
+		log.Printf("tg /orders refresh error: %v", err)
+		return
+	}
+	ref.Status = status.Status
+	if ref.MsgID != 0 {
+		text, markup := buildOrderCard(order, status, ref.Token)
+		tgEditMessage(chatID, ref.MsgID, text, markup)
+	}
+	tgWatchers.watch(chatID, order, ref.Token, status.Status)
+	redrawOrderList(chatID, sess, listMsgID)
+}
+
+// handleTGOrderClear handles "oc:<id>": stop following the order and remove
+// its card. The order itself is unaffected and still opens from its link.
+func handleTGOrderClear(chatID int64, sess *tgSession, listMsgID int, arg string) {
+	id, _ := strconv.Atoi(arg)
+	if ref := sess.orderByID(id); ref != nil {
+		if ref.MsgID != 0 {
+			tgDeleteMessage(chatID, ref.MsgID)
+			if ref.MsgID == sess.CardMsgID {
+				sess.CardMsgID = 0
+			}
+		}
+		if ref.Token == sess.OrderToken {
+			sess.OrderToken = ""
+			sess.State = stateIdle
+		}
+		sess.dropOrder(ref.Token)
+	}
+	redrawOrderList(chatID, sess, listMsgID)
+}
+
+func redrawOrderList(chatID int64, sess *tgSession, listMsgID int) {
+	sess.pruneOrders(time.Now())
+	text, markup := renderOrderList(sess)
+	tgEditMessage(chatID, listMsgID, text, markup)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTGSessionOrders(t *testing.T) {
+	sess := &tgSession{}
+	order := watcherOrder(time.Now().Add(time.Hour))
+	for i := 0; i < maxTGOrders+2; i++ {
+		sess.addOrder(string(rune('a'+i)), 50+i, order, "PENDING_DEPOSIT")
+	}
+	if len(sess.Orders) != maxTGOrders || sess.Orders[0].Token != "c" || sess.Orders[0].ID != 3 {
+		t.Fatalf("oldest orders should be dropped past the cap: %+v", sess.Orders[0])
+	}
+	sess.addOrder("c", 99, order, "PROCESSING")
+	if ref := sess.orderByMsg(99); ref == nil || ref.ID != 3 || len(sess.Orders) != maxTGOrders {
+		t.Errorf("re-adding should move the order to its new card, got %+v", ref)
+	}
+	if sess.Orders[0].Label != "0.5 ETH → SOL" {
+		t.Errorf("label = %q", sess.Orders[0].Label)
+	}
+
+	sess.reset()
+	if len(sess.Orders) != maxTGOrders {
+		t.Error("reset should keep followed orders")
+	}
+	sess.Orders[1].Status = "SUCCESS"
+	sess.Orders[2].Created = time.Now().Add(-tgOrderRetention - time.Minute)
+	sess.pruneOrders(time.Now())
+	if len(sess.Orders) != maxTGOrders-2 || sess.orderByToken("d") != nil || sess.orderByToken("e") != nil {
+		t.Errorf("finished and expired orders should be pruned, left %d", len(sess.Orders))
+	}
+}
+
+func TestTGSessionCleanupKeepsOrders(t *testing.T) {
+	store := &tgSessionStore{sessions: make(map[int64]*tgSession)}
+	store.get(1)
+	following := store.get(2)
+	following.addOrder("tok", 50, watcherOrder(time.Now().Add(time.Hour)), "PROCESSING")
+
+	store.cleanup(time.Now().Add(3 * time.Hour))
+	if _, ok := store.sessions[1]; ok {
+		t.Error("idle session should be removed")
+	}
+	if _, ok := store.sessions[2]; !ok {
+		t.Error("a session following an order should be kept")
+	}
+
+	store.cleanup(time.Now().Add(tgOrderRetention + time.Hour))
+	if len(store.sessions) != 0 {
+		t.Error("once its orders expire the session should go too")
+	}
+}
+
+func TestParseOrderRetention(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": 24 * time.Hour, "48h": 48 * time.Hour, "90m": 90 * time.Minute, "soon": 24 * time.Hour, "-1h": 24 * time.Hour} {
+		if got := parseOrderRetention(in); got != want {
+			t.Errorf("parseOrderRetention(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestTGOrdersCommand(t *testing.T) {
+	withTGWatchers(t, "PROCESSING", "SUCCESS")
+	tg := withFakeTelegram(t)
+	const chatID = 9201
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	handleTGOrders(chatID)
+	if got := tg.texts(); !strings.Contains(got[0], "No active orders") {
+		t.Fatalf("empty list = %q", got)
+	}
+
+	first := watcherOrder(time.Now().Add(time.Hour))
+	second := watcherOrder(time.Now().Add(time.Hour))
+	second.DepositAddr, second.FromTicker = "0xsecond", "BTC"
+	tok1, _ := encryptOrderData(first)
+	tok2, _ := encryptOrderData(second)
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	sess.addOrder(tok1, 50, first, "PENDING_DEPOSIT")
+	sess.addOrder(tok2, 60, second, "PENDING_DEPOSIT")
+	sess.OrderToken, sess.CardMsgID, sess.State = tok2, 60, stateOrderActive
+	sess.mu.Unlock()
+
+	handleTGOrders(chatID)
+	tg.mu.Lock()
+	list := tg.calls[len(tg.calls)-1].Payload
+	tg.mu.Unlock()
+	markup, _ := json.Marshal(list["reply_markup"])
+	for _, want := range []string{"Active orders (2)", "#1</b>  0.5 ETH → SOL", "#2</b>  0.5 BTC → SOL", "Awaiting Deposit"} {
+		if !strings.Contains(list["text"].(string), want) {
+			t.Errorf("list is missing %q:\n%s", want, list["text"])
+		}
+	}
+	for _, want := range []string{`"or:1"`, `"oc:2"`, "/order/" + tok1} {
+		if !strings.Contains(string(markup), want) {
+			t.Errorf("buttons are missing %s: %s", want, markup)
+		}
+	}
+
+	// Refresh #1: its card is redrawn, then the list.
+	sess.mu.Lock()
+	handleTGOrderRefresh(chatID, sess, 101, "1")
+	if ref := sess.orderByID(1); ref == nil || ref.Status != "PROCESSING" {
+		t.Errorf("refreshed order = %+v", ref)
+	}
+	tg.mu.Lock()
+	n := len(tg.calls)
+	card, redraw := tg.calls[n-2].Payload, tg.calls[n-1].Payload
+	tg.mu.Unlock()
+	if card["message_id"].(float64) != 50 || redraw["message_id"].(float64) != 101 || !strings.Contains(redraw["text"].(string), "Processing") {
+		t.Errorf("refresh edited %v then %v", card["message_id"], redraw["message_id"])
+	}
+
+	// Clear #2, the current card.
+	handleTGOrderClear(chatID, sess, 101, "2")
+	if sess.orderByID(2) != nil || sess.CardMsgID != 0 || sess.OrderToken != "" || sess.State != stateIdle {
+		t.Errorf("after clearing #2: %+v", sess)
+	}
+	sess.mu.Unlock()
+	tg.mu.Lock()
+	deleted := false
+	for _, c := range tg.calls {
+		if c.Method == "deleteMessage" && c.Payload["message_id"].(float64) == 60 {
+			deleted = true
+		}
+	}
+	tg.mu.Unlock()
+	if !deleted {
+		t.Error("clearing should delete the order's card")
+	}
+
+	// #1's watcher sees it complete and drops it.
+	waitForTGWatchers(t)
+	if got := tgSessions.get(chatID).Orders; len(got) != 0 {
+		t.Errorf("orders left = %+v", got)
+	}
+}
+
+func TestTGReleaseCard(t *testing.T) {
+	tg := withFakeTelegram(t)
+	sess := &tgSession{CardMsgID: 50}
+	sess.addOrder("tok", 50, watcherOrder(time.Now()), "PROCESSING")
+	sess.releaseCard(1)
+	if len(tg.calls) != 0 {
+		t.Error("a followed order's card should be kept")
+	}
+	sess.CardMsgID = 77
+	sess.releaseCard(1)
+	if len(tg.calls) != 1 || tg.calls[0].Method != "deleteMessage" {
+		t.Errorf("a plain card should be deleted, got %+v", tg.calls)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Long-polling transport. With TG_UPDATE_MODE=polling the bot pulls
+// updates with getUpdates instead of registering a webhook, so it runs
+// without a public HTTPS URL (Tor-only deployments, local development).
+// Updates go through the same routeTGUpdate as webhook deliveries. The
+// next offset is written to tgPollOffsetPath (TG_OFFSET_PATH) after every
+// batch, so a restart neither replays nor skips updates Telegram still
+// holds (it keeps them for 24 hours).
+
+const (
+	// tgPollTimeout is the long-poll wait Telegram holds a request open.
+	tgPollTimeout = 50 * time.Second
+	// tgShutdownWait bounds how long shutdown waits for in-flight updates.
+	tgShutdownWait = 10 * time.Second
+)
+
+var (
+	tgPollOffsetPath = cmp.Or(os.Getenv("TG_OFFSET_PATH"), "data/tg_offset")
+	// tgPollRetryDelays is the wait after consecutive getUpdates failures.
+	tgPollRetryDelays = []time.Duration{time.Second, 5 * time.Second, 15 * time.Second, 30 * time.Second}
+)
+
+// parseTGUpdateMode reports whether TG_UPDATE_MODE selects long polling.
+// Anything other than "polling", "webhook" or empty falls back to the
+// webhook with a warning.
+func parseTGUpdateMode(s string) bool {
+	switch mode := strings.ToLower(strings.TrimSpace(s)); mode {
+	case "polling":
+		return true
+	case "", "webhook":
+		return false
+	default:
+		log.Printf("WARNING: TG_UPDATE_MODE %q is not \"webhook\" or \"polling\" — using webhook", mode)
+		return false
+	}
+}
+
+// tgUpdates counts updates being handled, so shutdown can let them finish.
+var tgUpdates sync.WaitGroup
+
+// tgGetUpdates long-polls for updates starting at offset. Cancelling ctx
+// aborts the request.
+func tgGetUpdates(ctx context.Context, offset int) ([]TGUpdate, error) {
+	payload := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(tgPollTimeout.Seconds()),
+		"allowed_updates": tgAllowedUpdates,
+	}
+	result, err := tgRequestContext(ctx, "getUpdates", payload)
+	if err != nil {
+		return nil, err
+	}
+	var updates []TGUpdate
+	if err := json.Unmarshal(result, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// loadTGOffset reads the saved offset; 0 lets Telegram start from the
+// oldest unconfirmed update.
+func loadTGOffset() int {
+	data, err := os.ReadFile(tgPollOffsetPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("tg poll offset read error: %v", err)
+		}
+		return 0
+	}
+	offset, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		log.Printf("tg poll offset ignored: %v", err)
+		return 0
+	}
+	return offset
+}
+
+func saveTGOffset(offset int) {
+	if err := writeFileAtomic(tgPollOffsetPath, []byte(strconv.Itoa(offset)+"\n")); err != nil {
+		log.Printf("tg poll offset write error: %v", err)
+	}
+}
+
+// startTGPoller polls for updates until ctx is cancelled. The returned
+// channel closes once the loop has stopped and the offset is saved.
+func startTGPoller(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runTGPoller(ctx)
+	}()
+	return done
+}
+
+func runTGPoller(ctx context.Context) {
+	offset := loadTGOffset()
+	log.Printf("Telegram long polling started (offset %d)", offset)
+	failures := 0
+	for ctx.Err() == nil {
+		updates, err := tgGetUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			tgWebhook.set(err)
+			delay := tgPollRetryDelays[min(failures, len(tgPollRetryDelays)-1)]
+			failures++
+			log.Printf("tg getUpdates error (retrying in %s): %v", delay, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if failures > 0 {
+			tgWebhook.set(nil)
+			failures = 0
+		}
+		for i := range updates {
+			if updates[i].UpdateID >= offset {
+				offset = updates[i].UpdateID + 1
+			}
+			routeTGUpdate(&updates[i])
+		}
+		if len(updates) > 0 {
+			saveTGOffset(offset)
+		}
+	}
+	log.Printf("Telegram long polling stopped (offset %d)", offset)
+}
+
+// waitTGUpdates waits, up to tgShutdownWait, for in-flight updates.
+func waitTGUpdates() {
+	done := make(chan struct{})
+	go func() {
+		tgUpdates.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(tgShutdownWait):
+		log.Printf("WARNING: Telegram updates still running at shutdown")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTGUpdateMode(t *testing.T) {
+	for in, want := range map[string]bool{"": false, "webhook": false, "polling": true, " Polling ": true, "poll": false} {
+		if got := parseTGUpdateMode(in); got != want {
+			t.Errorf("parseTGUpdateMode(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+// fakeTGPoll serves getUpdates from a queue of batches, then holds the
+// long poll open until the client gives up. Other methods succeed.
+type fakeTGPoll struct {
+	mu      sync.Mutex
+	batches [][]TGUpdate
+	offsets []int
+	texts   []string
+}
+
+func (f *fakeTGPoll) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&payload)
+	if !strings.HasSuffix(r.URL.Path, "/getUpdates") {
+		f.mu.Lock()
+		if text, ok := payload["text"].(string); ok {
+			f.texts = append(f.texts, text)
+		}
+		f.mu.Unlock()
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1}}`)
+		return
+	}
+	f.mu.Lock()
+	f.offsets = append(f.offsets, int(payload["offset"].(float64)))
+	var batch []TGUpdate
+	if len(f.batches) > 0 {
+		batch, f.batches = f.batches[0], f.batches[1:]
+	}
+	f.mu.Unlock()
+	if batch == nil {
+		<-r.Context().Done()
+		return
+	}
+	result, _ := json.Marshal(batch)
+	fmt.Fprintf(w, `{"ok":true,"result":%s}`, result)
+}
+
+func (f *fakeTGPoll) snapshot() (offsets []int, texts []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int(nil), f.offsets...), append([]string(nil), f.texts...)
+}
+
+func TestTGPoller(t *testing.T) {
+	const chatID = 9401
+	fake := &fakeTGPoll{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	saved, savedPath := tgAPIBase, tgPollOffsetPath
+	tgAPIBase, tgPollOffsetPath = srv.URL, filepath.Join(t.TempDir(), "offset")
+	t.Cleanup(func() { tgAPIBase, tgPollOffsetPath = saved, savedPath })
+	subscribers.mu.Lock()
+	subscribers.ids[chatID] = true // keep the test out of data/subscribers.txt
+	subscribers.mu.Unlock()
+	t.Cleanup(func() {
+		subscribers.mu.Lock()
+		delete(subscribers.ids, chatID)
+		subscribers.mu.Unlock()
+		tgSessions.mu.Lock()
+		delete(tgSessions.sessions, chatID)
+		tgSessions.mu.Unlock()
+	})
+
+	msg := func(id int) TGUpdate {
+		return TGUpdate{UpdateID: id, Message: &TGMessage{MessageID: id, Chat: TGChat{ID: chatID, Type: "private"}, Text: "/orders"}}
+	}
+	fake.batches = [][]TGUpdate{{msg(41), msg(42)}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := startTGPoller(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		offsets, texts := fake.snapshot()
+		if len(offsets) >= 2 && len(texts) >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("poller stalled: offsets %v, texts %q", offsets, texts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("poller should stop promptly when cancelled")
+	}
+	waitTGUpdates()
+
+	offsets, texts := fake.snapshot()
+	if offsets[0] != 0 || offsets[1] != 43 {
+		t.Errorf("offsets = %v, want [0 43 ...]", offsets)
+	}
+	if !strings.Contains(texts[0], "No active orders") {
+		t.Errorf("updates should be routed like webhook ones, got %q", texts)
+	}
+	if raw, _ := os.ReadFile(tgPollOffsetPath); strings.TrimSpace(string(raw)) != "43" {
+		t.Errorf("saved offset = %q", raw)
+	}
+
+	// A restart picks up where it left off.
+	ctx, cancel = context.WithCancel(context.Background())
+	done = startTGPoller(ctx)
+	for deadline = time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if offsets, _ = fake.snapshot(); len(offsets) > 2 || time.Now().After(deadline) {
+			break
+		}
+	}
+	cancel()
+	<-done
+	if len(offsets) < 3 || offsets[2] != 43 {
+		t.Errorf("restart polled from %v", offsets)
+	}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // generateQRPNG renders a QR code as a PNG with a dark frame.
-// Outer image: 220x220 dark background (#0c0c0c).
+// Outer image: 220x220 dark background (#0c0c0c), larger for big versions.
 // Inner QR area: 180x180 white box centered, with 1px green (#34ed7a) border.
 // QR modules: black on white (standard, scannable).
 func generateQRPNG(data string) ([]byte, error) {
@@ -22,12 +22,14 @@ func generateQRPNG(data string) ([]byte, error) {
 		return buf.Bytes(), nil
 	}
 
-	const (
-		imgSize  = 220
-		qrBoxW   = 180
-		qrBoxH   = 180
-		borderPx = 1
-	)
+	const borderPx = 1
+	imgSize, qrBoxW, qrBoxH := 220, 180, 180
+
+	// Large versions get a bigger canvas so every module is at least 2px.
+	if need := (len(modules) + 8) * 2; need > qrBoxW {
+		qrBoxW, qrBoxH = need, need
+		imgSize = need + 40
+	}
 
 	// Center the QR white box
 	qrX := (imgSize - qrBoxW) / 2
@@ -170,8 +170,8 @@ func renderSwapCardMono(sess *tgSession) string {
 	sb.WriteString(cardMid() + "\n")
 
 	// SEND / RECEIVE token rows
-	fromNet := networkDisplayName(sess.FromNet)
-	toNet := networkDisplayName(sess.ToNet)
+	fromNet := chainName(sess.FromNet)
+	toNet := chainName(sess.ToNet)
 
 	fromTicker := safeRunes(sess.FromTicker, 8)
 	toTicker := safeRunes(sess.ToTicker, 8)
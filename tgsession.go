@@ -18,11 +18,13 @@ const (
 	stateQuoteConfirm  = 8
 	stateOrderActive   = 9
 	stateEnterAmountOut = 10
+	stateEnterAddrLabel = 11
 )
 
 // tgSession holds the swap state for a single Telegram chat.
 type tgSession struct {
-	mu sync.Mutex
+	mu     sync.Mutex
+	chatID int64
 
 	State     int
 	CardMsgID int // the persistent swap card message ID
@@ -52,6 +54,11 @@ type tgSession struct {
 	DepositMsgID int
 	OrderMsgIDs  []int // all message IDs related to this swap
 
+	// Orders the chat is following (see /orders), oldest first. Kept
+	// across reset so starting a new swap doesn't orphan them.
+	Orders      []tgOrderRef
+	nextOrderID int
+
 	// Quote cache
 	DryQuote *DryQuoteResponse
 }
@@ -74,6 +81,7 @@ func (s *tgSessionStore) get(chatID int64) *tgSession {
 	sess, ok := s.sessions[chatID]
 	if !ok {
 		sess = &tgSession{
+			chatID:    chatID,
 			Slippage:  "1",
 			LastTouch: time.Now(),
 		}
@@ -83,7 +91,7 @@ func (s *tgSessionStore) get(chatID int64) *tgSession {
 	return sess
 }
 
-// reset clears a session back to defaults (keeps chat mapping).
+// reset clears a session back to defaults (keeps chat mapping and Orders).
 func (sess *tgSession) reset() {
 	sess.State = stateIdle
 	sess.CardMsgID = 0
@@ -132,19 +140,28 @@ func (sess *tgSession) swapType() string {
 	return "ANY_INPUT"
 }
 
-// startCleanup starts a goroutine that removes stale sessions.
+// startCleanup starts a goroutine that removes stale sessions. Sessions
+// still following orders are kept until the orders are pruned.
 func (s *tgSessionStore) startCleanup() {
 	go func() {
 		for {
 			time.Sleep(10 * time.Minute)
-			s.mu.Lock()
-			now := time.Now()
-			for id, sess := range s.sessions {
-				if now.Sub(sess.LastTouch) > 2*time.Hour {
-					delete(s.sessions, id)
-				}
-			}
-			s.mu.Unlock()
+			s.cleanup(time.Now())
 		}
 	}()
 }
+
+func (s *tgSessionStore) cleanup(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if !sess.mu.TryLock() {
+			continue // in use
+		}
+		sess.pruneOrders(now)
+		if now.Sub(sess.LastTouch) > 2*time.Hour && len(sess.Orders) == 0 {
+			delete(s.sessions, id)
+		}
+		sess.mu.Unlock()
+	}
+}
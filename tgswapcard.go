@@ -302,13 +302,7 @@ func handleTGTokenSelected(chatID int64, sess *tgSession, ticker string) {
 	}
 
 	// Find all networks for this ticker
-	results := searchTokens(ticker)
-	var networks []TokenInfo
-	for _, t := range results {
-		if strings.EqualFold(t.Ticker, ticker) {
-			networks = append(networks, t)
-		}
-	}
+	networks := tokensByTicker(ticker)
 
 	if len(networks) == 0 {
 		handleTGBackToCard(chatID, sess)
@@ -341,7 +335,7 @@ func handleTGTokenSelected(chatID int64, sess *tgSession, ticker string) {
 	for i := 0; i < len(unique); i += 3 {
 		var row []TGInlineKeyboardButton
 		for j := i; j < i+3 && j < len(unique); j++ {
-			label := networkDisplayName(unique[j].ChainName)
+			label := chainName(unique[j].ChainName)
 			row = append(row, TGInlineKeyboardButton{
 				Text:         label,
 				CallbackData: "tn:" + ticker + ":" + unique[j].ChainName,
@@ -493,6 +487,7 @@ func handleTGAmountOutInput(chatID int64, sess *tgSession, msg *TGMessage) {
 
 func handleTGPromptRefund(chatID int64, sess *tgSession) {
 	sess.State = stateEnterRefund
+	showAddressPicker(chatID, sess, "from")
 	prompt := fmt.Sprintf("Enter your %s refund address:", sess.FromTicker)
 	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
 		ForceReply:            true,
@@ -506,20 +501,23 @@ func handleTGPromptRefund(chatID int64, sess *tgSession) {
 
 func handleTGRefundInput(chatID int64, sess *tgSession, msg *TGMessage) {
 	addr := strings.TrimSpace(msg.Text)
-	if len(addr) < 10 {
-		tgSendMessage(chatID, "Address seems too short. Please try again.", nil)
+	if err := validateAddress(sess.FromNet, addr); err != nil {
+		tgSendMessage(chatID, "That's "+err.Error()+". Please try again.", nil)
 		return
 	}
 
 	sess.RefundAddr = addr
 	sess.State = stateSwapCard
+	sess.PickSide = ""
 
 	cleanupPromptReply(chatID, sess, msg.MessageID)
 	updateSwapCard(chatID, sess)
+	offerSaveAddress(chatID, sess, "from")
 }
 
 func handleTGPromptRecv(chatID int64, sess *tgSession) {
 	sess.State = stateEnterRecv
+	showAddressPicker(chatID, sess, "to")
 	prompt := fmt.Sprintf("Enter your %s receive address:", sess.ToTicker)
 	msg, err := tgSendMessage(chatID, prompt, &TGForceReply{
 		ForceReply:            true,
@@ -533,16 +531,18 @@ func handleTGPromptRecv(chatID int64, sess *tgSession) {
 
 func handleTGRecvInput(chatID int64, sess *tgSession, msg *TGMessage) {
 	addr := strings.TrimSpace(msg.Text)
-	if len(addr) < 10 {
-		tgSendMessage(chatID, "Address seems too short. Please try again.", nil)
+	if err := validateAddress(sess.ToNet, addr); err != nil {
+		tgSendMessage(chatID, "That's "+err.Error()+". Please try again.", nil)
 		return
 	}
 
 	sess.RecvAddr = addr
 	sess.State = stateSwapCard
+	sess.PickSide = ""
 
 	cleanupPromptReply(chatID, sess, msg.MessageID)
 	updateSwapCard(chatID, sess)
+	offerSaveAddress(chatID, sess, "to")
 }
 
 // --- Slippage ---
@@ -587,19 +587,3 @@ func truncAddr(addr string) string {
 	}
 	return addr[:8] + "..." + addr[len(addr)-6:]
 }
-
-// networkDisplayName maps chain codes to display names.
-func networkDisplayName(chain string) string {
-	names := map[string]string{
-		"eth": "Ethereum", "btc": "Bitcoin", "sol": "Solana", "base": "Base",
-		"arb": "Arbitrum", "ton": "TON", "tron": "TRON", "bsc": "BNB Chain",
-		"pol": "Polygon", "op": "Optimism", "avax": "Avalanche", "near": "NEAR",
-		"sui": "Sui", "apt": "Aptos", "aptos": "Aptos", "doge": "Dogecoin",
-		"ltc": "Litecoin", "xrp": "XRP", "bch": "Bitcoin Cash",
-		"xlm": "Stellar", "stellar": "Stellar", "zec": "Zcash",
-	}
-	if name, ok := names[strings.ToLower(chain)]; ok {
-		return name
-	}
-	return chain
-}
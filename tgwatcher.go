@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Telegram order watchers. Every order a chat follows (see tgorders.go)
+// gets a goroutine that polls its status, backing off while nothing
+// changes. On each transition it redraws the order's card in place with
+// buildOrderCard and sends a short notification. A watcher stops at a
+// terminal status, at the quote deadline if no deposit has arrived by then,
+// or as soon as the chat stops following the order (dropOrder and
+// pruneOrders stop it). Like webhook watchers they live in memory only:
+// after a restart, /status or "Refresh Status" starts a new one.
+
+var (
+	// tgWatchDelays is the wait before each poll. It steps up while the
+	// status stays the same and starts over after a transition.
+	tgWatchDelays = []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second, 2 * time.Minute}
+)
+
+const (
+	// tgWatchMaxWatch bounds a watcher once the deposit is in; refunds can
+	// land well after the quote deadline.
+	tgWatchMaxWatch = 24 * time.Hour
+	// tgWatchMaxWatchers caps concurrent watchers process-wide.
+	tgWatchMaxWatchers = 500
+)
+
+// tgWatcherRegistry tracks running watchers so each order has at most one
+// per chat. Each maps to a channel that stop closes; running also counts
+// stopped watchers that have not returned yet.
+type tgWatcherRegistry struct {
+	mu      sync.Mutex
+	active  map[string]chan struct{}
+	running int
+}
+
+var tgWatchers = &tgWatcherRegistry{active: make(map[string]chan struct{})}
+
+func tgWatcherKey(chatID int64, token string) string {
+	return fmt.Sprintf("%d|%s", chatID, token)
+}
+
+// watch starts a watcher for an order chatID follows, unless one is
+// already running. status is the status its card currently shows. A
+// watcher that cannot start because the registry is full is logged and
+// counted.
+func (wr *tgWatcherRegistry) watch(chatID int64, order *OrderData, token string, status string) {
+	if isTerminalStatus(status) {
+		return
+	}
+	key := tgWatcherKey(chatID, token)
+
+	wr.mu.Lock()
+	if _, ok := wr.active[key]; ok {
+		wr.mu.Unlock()
+		return
+	}
+	if wr.running >= tgWatchMaxWatchers {
+		wr.mu.Unlock()
+		tgWatchersDropped.inc()
+		log.Printf("WARNING: Telegram order watcher not started: %d watchers already running", tgWatchMaxWatchers)
+		return
+	}
+	done := make(chan struct{})
+	wr.active[key] = done
+	wr.running++
+	wr.mu.Unlock()
+
+	go func() {
+		defer func() {
+			wr.mu.Lock()
+			wr.running--
+			if wr.active[key] == done {
+				delete(wr.active, key)
+			}
+			wr.mu.Unlock()
+		}()
+		runTGOrderWatcher(chatID, order, token, status, done)
+	}()
+}
+
+// stop ends the watcher for an order chatID no longer follows, if any.
+func (wr *tgWatcherRegistry) stop(chatID int64, token string) {
+	key := tgWatcherKey(chatID, token)
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if done, ok := wr.active[key]; ok {
+		delete(wr.active, key)
+		close(done)
+	}
+}
+
+func (wr *tgWatcherRegistry) count() int {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return wr.running
+}
+
+// runTGOrderWatcher polls until the order reaches a terminal status, the
+// deadline passes with no deposit, the chat stops following it (done is
+// closed), or tgWatchMaxWatch elapses.
+func runTGOrderWatcher(chatID int64, order *OrderData, token string, last string, done <-chan struct{}) {
+	stop := time.Now().Add(tgWatchMaxWatch)
+	deadline, err := time.Parse(time.RFC3339, order.Deadline)
+	if err != nil {
+		deadline = time.Now().Add(time.Hour)
+	}
+
+	step := 0
+	for time.Now().Before(stop) {
+		select {
+		case <-done:
+			return
+		case <-time.After(tgWatchDelays[min(step, len(tgWatchDelays)-1)]):
+		}
+
+		ctx, cancel := upstreamContext()
+		status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
+		cancel()
+		switch {
+		case err != nil:
+			step++
+		case status.Status == last:
+			step++
+		default:
+			step = 0
+			last = status.Status
+			if !tgOrderTransition(chatID, order, token, status) || isTerminalStatus(last) {
+				return
+			}
+		}
+
+		if (last == "" || last == "PENDING_DEPOSIT") && time.Now().After(deadline) {
+			return
+		}
+	}
+}
+
+// tgOrderTransition redraws the order's card and sends a notification for
+// the new status. Finished orders drop off the chat's list. It reports
+// false, doing nothing, if the chat no longer follows the order.
+func tgOrderTransition(chatID int64, order *OrderData, token string, status *StatusResponse) bool {
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	ref := sess.orderByToken(token)
+	if ref == nil {
+		return false
+	}
+	ref.Status = status.Status
+	if ref.MsgID != 0 {
+		text, markup := buildOrderCard(order, status, token)
+		if err := tgEditMessage(chatID, ref.MsgID, text, markup); err != nil {
+			log.Printf("tg watcher edit error: %v", err)
+		}
+	}
+	if isTerminalStatus(status.Status) {
+		sess.dropOrder(token)
+	}
+
+	note := orderStatusNotice(order, status)
+	if note == "" {
+		return true
+	}
+	msg, err := tgSendMessage(chatID, note, nil)
+	if err != nil {
+		log.Printf("tg watcher notify error: %v", err)
+		return true
+	}
+	if sess.OrderToken == token {
+		sess.trackMsg(msg.MessageID) // removed by "Clear" along with the card
+	}
+	return true
+}
+
+// orderStatusNotice is the notification for a status, or "" for statuses
+// that need none.
+func orderStatusNotice(order *OrderData, status *StatusResponse) string {
+	pair := html.EscapeString(order.FromTicker + " → " + order.ToTicker)
+	switch strings.ToUpper(status.Status) {
+	case "KNOWN_DEPOSIT_TX":
+		return "📥 Deposit detected for your " + pair + " swap. Waiting for confirmations."
+	case "PROCESSING":
+		return "⚙️ Deposit confirmed — your " + pair + " swap is processing."
+	case "SUCCESS":
+		out := order.AmountOut
+		if status.SwapDetails != nil && status.SwapDetails.AmountOutFmt != "" {
+			out = status.SwapDetails.AmountOutFmt
+		}
+		return "✅ Swap complete: <b>" + html.EscapeString(out+" "+order.ToTicker) + "</b> sent to " +
+			"<code>" + html.EscapeString(truncAddr(order.RecvAddr)) + "</code>."
+	case "REFUNDED":
+		return "↩️ Your " + pair + " swap was refunded to <code>" + html.EscapeString(truncAddr(order.RefundAddr)) + "</code>."
+	case "INCOMPLETE_DEPOSIT":
+		return "⚠️ Incomplete deposit for your " + pair + " swap. It will be refunded."
+	case "FAILED":
+		return "❌ Your " + pair + " swap failed. Open the order for details."
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withTGWatchers makes watchers poll every millisecond against a fake
+// upstream that serves statuses in order, repeating the last one.
+func withTGWatchers(t *testing.T, statuses ...string) {
+	t.Helper()
+	var mu sync.Mutex
+	polls := 0
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		s := statuses[min(polls, len(statuses)-1)]
+		polls++
+		mu.Unlock()
+		fmt.Fprintf(w, `{"status":%q,"swapDetails":{"amountOutFormatted":"12.5"}}`, s)
+	})
+	withoutStatusCache(t)
+	saved := tgWatchDelays
+	tgWatchDelays = []time.Duration{time.Millisecond}
+	t.Cleanup(func() { tgWatchDelays = saved })
+}
+
+func waitForTGWatchers(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for tgWatchers.count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := tgWatchers.count(); n > 0 {
+		t.Fatalf("%d watchers still running", n)
+	}
+}
+
+func watcherOrder(deadline time.Time) *OrderData {
+	return &OrderData{DepositAddr: "0xwatch", FromTicker: "ETH", FromNet: "eth", ToTicker: "SOL", ToNet: "sol",
+		AmountIn: "0.5", AmountOut: "12", RecvAddr: "So11111111111111111111111111111111111111112",
+		RefundAddr: "0x1111111111111111111111111111111111111111", Deadline: deadline.UTC().Format(time.RFC3339)}
+}
+
+func TestTGOrderWatcher(t *testing.T) {
+	withTGWatchers(t, "PENDING_DEPOSIT", "KNOWN_DEPOSIT_TX", "KNOWN_DEPOSIT_TX", "PROCESSING", "SUCCESS")
+	tg := withFakeTelegram(t)
+	const chatID = 9101
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	order := watcherOrder(time.Now().Add(time.Hour))
+	token, _ := encryptOrderData(order)
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	sess.OrderToken, sess.CardMsgID, sess.State = token, 50, stateOrderActive
+	sess.addOrder(token, 50, order, "PENDING_DEPOSIT")
+	sess.mu.Unlock()
+
+	tgWatchers.watch(chatID, order, token, "PENDING_DEPOSIT")
+	tgWatchers.watch(chatID, order, token, "PENDING_DEPOSIT")
+	if n := tgWatchers.count(); n != 1 {
+		t.Errorf("an order should have one watcher, got %d", n)
+	}
+	waitForTGWatchers(t)
+
+	var edits, notices []string
+	tg.mu.Lock()
+	for _, c := range tg.calls {
+		switch c.Method {
+		case "editMessageText":
+			if c.Payload["message_id"].(float64) != 50 {
+				t.Errorf("edited message %v", c.Payload["message_id"])
+			}
+			edits = append(edits, c.Payload["text"].(string))
+		case "sendMessage":
+			notices = append(notices, c.Payload["text"].(string))
+		}
+	}
+	tg.mu.Unlock()
+
+	if len(edits) != 3 || !strings.Contains(edits[2], "COMPLETE ✓") {
+		t.Errorf("card edits = %d, last:\n%s", len(edits), edits[len(edits)-1])
+	}
+	for i, want := range []string{"Deposit detected", "is processing", "Swap complete: <b>12.5 SOL</b>"} {
+		if i >= len(notices) || !strings.Contains(notices[i], want) {
+			t.Errorf("notice %d should mention %q, got %q", i, want, notices)
+		}
+	}
+	if len(notices) != 3 {
+		t.Errorf("one notice per transition, got %d", len(notices))
+	}
+	if got := tgSessions.get(chatID).OrderMsgIDs; len(got) != 3 {
+		t.Errorf("notices should be tracked for Clear, got %v", got)
+	}
+	if got := tgSessions.get(chatID).Orders; len(got) != 0 {
+		t.Errorf("a completed order should drop off the list, got %+v", got)
+	}
+}
+
+func TestTGOrderWatcherStops(t *testing.T) {
+	withTGWatchers(t, "PENDING_DEPOSIT")
+	tg := withFakeTelegram(t)
+	const chatID = 9102
+
+	// No deposit by the deadline: stop quietly.
+	order := watcherOrder(time.Now().Add(-time.Minute))
+	tgWatchers.watch(chatID, order, "tok", "PENDING_DEPOSIT")
+	waitForTGWatchers(t)
+	if len(tg.texts()) != 0 {
+		t.Errorf("expired order sent %q", tg.texts())
+	}
+
+	// Finished orders aren't watched at all.
+	tgWatchers.watch(chatID, order, "tok", "REFUNDED")
+	if tgWatchers.count() != 0 {
+		t.Error("terminal orders should not be watched")
+	}
+}
+
+func TestTGOrderWatcherOtherCards(t *testing.T) {
+	withTGWatchers(t, "REFUNDED")
+	tg := withFakeTelegram(t)
+	const chatID = 9103
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	// The chat has since moved on to a new swap card; the order's own card
+	// is still updated, but its notice isn't tied to the new card.
+	order := watcherOrder(time.Now().Add(time.Hour))
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	sess.CardMsgID, sess.State = 77, stateSwapCard
+	sess.addOrder("old-token", 50, order, "PROCESSING")
+	sess.mu.Unlock()
+
+	tgWatchers.watch(chatID, order, "old-token", "PROCESSING")
+	waitForTGWatchers(t)
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	if len(tg.calls) != 2 || tg.calls[0].Payload["message_id"].(float64) != 50 ||
+		!strings.Contains(tg.calls[1].Payload["text"].(string), "refunded to <code>0x111111...111111</code>") {
+		t.Errorf("expected a card edit and a refund notice, got %+v", tg.calls)
+	}
+	if len(sess.OrderMsgIDs) != 0 {
+		t.Errorf("notice should not be tracked with the new card: %v", sess.OrderMsgIDs)
+	}
+}
+
+func TestTGOrderWatcherUnfollowed(t *testing.T) {
+	withTGWatchers(t, "PROCESSING")
+	tg := withFakeTelegram(t)
+	const chatID = 9104
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	// Cleared from /orders: the watcher stops at the next change, silently.
+	tgWatchers.watch(chatID, watcherOrder(time.Now().Add(time.Hour)), "cleared", "PENDING_DEPOSIT")
+	waitForTGWatchers(t)
+	if n := len(tg.calls); n != 0 {
+		t.Errorf("an unfollowed order sent %d calls", n)
+	}
+}
+
+func TestTGOrderWatcherDropped(t *testing.T) {
+	withTGWatchers(t, "PENDING_DEPOSIT")
+	withFakeTelegram(t)
+	const chatID = 9105
+	t.Cleanup(func() { tgSessions.mu.Lock(); delete(tgSessions.sessions, chatID); tgSessions.mu.Unlock() })
+
+	// Clearing or pruning an order stops its watcher right away instead of
+	// leaving it to poll until the deadline.
+	order := watcherOrder(time.Now().Add(time.Hour))
+	sess := tgSessions.get(chatID)
+	sess.mu.Lock()
+	sess.addOrder("cleared", 50, order, "PENDING_DEPOSIT")
+	sess.addOrder("expired", 51, order, "PENDING_DEPOSIT")
+	sess.mu.Unlock()
+	tgWatchers.watch(chatID, order, "cleared", "PENDING_DEPOSIT")
+	tgWatchers.watch(chatID, order, "expired", "PENDING_DEPOSIT")
+
+	sess.mu.Lock()
+	sess.dropOrder("cleared")
+	sess.pruneOrders(time.Now().Add(tgOrderRetention))
+	sess.mu.Unlock()
+	waitForTGWatchers(t)
+
+	// Past the cap, watchers are refused and counted.
+	tgWatchers.mu.Lock()
+	tgWatchers.running += tgWatchMaxWatchers
+	tgWatchers.mu.Unlock()
+	t.Cleanup(func() { tgWatchers.mu.Lock(); tgWatchers.running -= tgWatchMaxWatchers; tgWatchers.mu.Unlock() })
+	before := tgWatchersDropped.get()
+	tgWatchers.watch(chatID, order, "late", "PENDING_DEPOSIT")
+	if got := tgWatchersDropped.get() - before; got != 1 || tgWatchers.count() != tgWatchMaxWatchers {
+		t.Errorf("dropped %v watchers, %d running", got, tgWatchers.count())
+	}
+}
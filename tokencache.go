@@ -22,31 +22,50 @@ type tokenCache struct {
 	byAssetID map[string]*TokenInfo
 	networks  []NetworkGroup
 	updatedAt time.Time
+
+	lastErr   error // most recent refresh failure (nil after a success)
+	lastErrAt time.Time
+
+	// fromSnapshot is set while the tokens come from the on-disk snapshot
+	// rather than a live refresh (see tokensnapshot.go).
+	fromSnapshot bool
 }
 
 var cache = &tokenCache{}
 
 // refreshTokenCache fetches and caches the token list from NEAR Intents.
 func refreshTokenCache() error {
-	tokens, err := fetchTokens()
+	ctx, cancel := upstreamContext()
+	defer cancel()
+	tokens, err := fetchTokens(ctx)
 	if err != nil {
+		tokenCacheRefreshes.inc("failure")
+		cache.mu.Lock()
+		cache.lastErr = err
+		cache.lastErrAt = time.Now()
+		cache.mu.Unlock()
 		return err
 	}
 
+	now := time.Now()
+	networks := installTokens(tokens, now, false)
+	tokenCacheRefreshes.inc("success")
+	queueListingAnnouncements(recordListingChanges(tokens, now))
+	recordPrices(tokens, now)
+	checkPriceAlerts()
+	saveTokenSnapshot(tokens)
+	savePriceHistory()
+
+	log.Printf("Token cache refreshed: %d tokens across %d networks", len(tokens), len(networks))
+	return nil
+}
+
+// installTokens normalizes tokens, rebuilds the lookup indexes and swaps
+// them into the cache. It returns the network groups it built.
+func installTokens(tokens []TokenInfo, updatedAt time.Time, fromSnapshot bool) []NetworkGroup {
 	byAssetID := make(map[string]*TokenInfo, len(tokens))
 	networkMap := make(map[string][]TokenInfo)
-
-	// Map API blockchain codes to display names
-	chainDisplayName := map[string]string{
-		"eth": "Ethereum", "btc": "Bitcoin", "sol": "Solana", "base": "Base",
-		"arb": "Arbitrum", "ton": "TON", "tron": "TRON", "bsc": "BNB Chain",
-		"pol": "Polygon", "op": "Optimism", "avax": "Avalanche", "near": "NEAR",
-		"sui": "Sui", "apt": "Aptos", "aptos": "Aptos", "doge": "Dogecoin", "ltc": "Litecoin",
-		"xrp": "XRP", "bch": "Bitcoin Cash", "xlm": "Stellar", "stellar": "Stellar", "zec": "Zcash",
-		"cardano": "Cardano", "starknet": "StarkNet", "gnosis": "Gnosis",
-		"bera": "Berachain", "monad": "Monad", "plasma": "Plasma",
-		"xlayer": "X Layer", "aleo": "Aleo", "adi": "ADI",
-	}
+	networkOrder := make(map[string]int)
 
 	for i := range tokens {
 		t := &tokens[i]
@@ -59,9 +78,9 @@ func refreshTokenCache() error {
 		byAssetID[t.DefuseAssetID] = t
 
 		// Map blockchain code to display name
-		netName := t.ChainName
-		if displayName, ok := chainDisplayName[strings.ToLower(netName)]; ok {
-			netName = displayName
+		netName := chainName(t.ChainName)
+		if c := lookupChain(t.ChainName); c != nil && c.Priority > 0 {
+			networkOrder[c.Name] = c.Priority
 		}
 		if netName == "" {
 			netName = "Other"
@@ -69,12 +88,7 @@ func refreshTokenCache() error {
 		networkMap[netName] = append(networkMap[netName], *t)
 	}
 
-	// Sort networks: popular first, then alphabetical
-	networkOrder := map[string]int{
-		"Ethereum": 1, "Bitcoin": 2, "Solana": 3, "Base": 4,
-		"Arbitrum": 5, "TON": 6, "TRON": 7, "BNB Chain": 8,
-		"Polygon": 9, "Optimism": 10, "Avalanche": 11, "NEAR": 12,
-	}
+	// Sort networks: by registry priority, then alphabetical
 
 	var networks []NetworkGroup
 	for name, toks := range networkMap {
@@ -103,11 +117,13 @@ func refreshTokenCache() error {
 	cache.tokens = tokens
 	cache.byAssetID = byAssetID
 	cache.networks = networks
-	cache.updatedAt = time.Now()
+	cache.updatedAt = updatedAt
+	cache.fromSnapshot = fromSnapshot
+	if !fromSnapshot {
+		cache.lastErr = nil
+	}
 	cache.mu.Unlock()
-
-	log.Printf("Token cache refreshed: %d tokens across %d networks", len(tokens), len(networks))
-	return nil
+	return networks
 }
 
 // getTokens returns the cached token list, refreshing if stale.
@@ -196,21 +212,22 @@ func findTokenByAssetID(assetID string) *TokenInfo {
 	return nil
 }
 
-// searchTokens returns tokens matching a query string (ticker, name, or network).
+// searchTokens returns tokens matching a query string, best match first
+// (see tokensearch.go).
 func searchTokens(query string) []TokenInfo {
 	cache.mu.RLock()
 	defer cache.mu.RUnlock()
+	return rankTokens(cache.tokens, query)
+}
 
-	if query == "" {
-		return cache.tokens
-	}
-
-	q := strings.ToLower(query)
+// tokensByTicker returns every token with exactly this ticker, in cache
+// order.
+func tokensByTicker(ticker string) []TokenInfo {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
 	var results []TokenInfo
 	for _, t := range cache.tokens {
-		if strings.Contains(strings.ToLower(t.Ticker), q) ||
-			strings.Contains(strings.ToLower(t.Name), q) ||
-			strings.Contains(strings.ToLower(t.ChainName), q) {
+		if strings.EqualFold(t.Ticker, ticker) {
 			results = append(results, t)
 		}
 	}
@@ -219,6 +236,10 @@ func searchTokens(query string) []TokenInfo {
 
 // startCacheRefresher starts a background goroutine to keep the cache fresh.
 func startCacheRefresher() {
+	// Serve the last good list if 1Click is down at boot.
+	loadTokenSnapshot()
+	loadPriceHistory(time.Now())
+
 	// Initial load
 	if err := refreshTokenCache(); err != nil {
 		log.Printf("Initial token cache load failed (will retry): %v", err)
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Token search shared by the swap modal, /currencies, /api/v1/tokens, the
+// Telegram picker and inline queries. Each token gets the score of its best
+// match, in tiers:
+//
+//	contract address or asset ID > exact ticker > alias ("tether") >
+//	exact name > ticker prefix > name prefix > ticker substring >
+//	name substring > network
+//
+// Typo-tolerant matches ("etherium", "usdtt") are only used when nothing
+// matches outright, so "usdt" never pulls in USDC. Within a tier, tokens
+// earlier in the 1Click list (which comes roughly in liquidity order) and
+// pricier tokens rank first; the boost never crosses a tier.
+
+const (
+	scoreContract     = 1000
+	scoreTicker       = 900
+	scoreAlias        = 850
+	scoreName         = 800
+	scoreTickerPrefix = 700
+	scoreNamePrefix   = 600
+	scoreTickerSubstr = 500
+	scoreNameSubstr   = 400
+	scoreNetwork      = 300
+	scoreNetworkSub   = 200
+	scoreFuzzy        = 250 // minus scoreFuzzyEdit per edit
+	scoreFuzzyEdit    = 50
+
+	maxSearchBoost = 49 // < the smallest gap between tiers
+)
+
+// tokenAliases maps common names people search for to tickers. The 1Click
+// list carries symbols but rarely names, so this is what makes "tether"
+// or "wrapped bitcoin" work.
+var tokenAliases = map[string]string{
+	"bitcoin": "BTC", "xbt": "BTC", "wrapped bitcoin": "WBTC", "wrapped btc": "WBTC",
+	"ethereum": "ETH", "ether": "ETH", "wrapped ether": "WETH", "wrapped eth": "WETH", "wrapped ethereum": "WETH",
+	"tether": "USDT", "tether usd": "USDT", "usd coin": "USDC", "circle": "USDC", "dai stablecoin": "DAI",
+	"solana": "SOL", "toncoin": "TON", "the open network": "TON", "tron": "TRX",
+	"binance coin": "BNB", "bnb chain": "BNB", "near protocol": "NEAR", "wrapped near": "WNEAR",
+	"polygon": "POL", "matic": "POL", "avalanche": "AVAX", "arbitrum": "ARB", "optimism": "OP",
+	"dogecoin": "DOGE", "litecoin": "LTC", "ripple": "XRP", "bitcoin cash": "BCH",
+	"stellar": "XLM", "lumens": "XLM", "zcash": "ZEC", "cardano": "ADA", "aptos": "APT",
+	"chainlink": "LINK", "uniswap": "UNI", "berachain": "BERA", "shiba inu": "SHIB", "pepe coin": "PEPE",
+}
+
+// tickerAliases is tokenAliases inverted, for the fuzzy pass.
+var tickerAliases = func() map[string][]string {
+	m := make(map[string][]string)
+	for alias, ticker := range tokenAliases {
+		m[ticker] = append(m[ticker], alias)
+	}
+	return m
+}()
+
+// rankTokens returns the tokens matching query, best first. An empty
+// query returns tokens unchanged.
+func rankTokens(tokens []TokenInfo, query string) []TokenInfo {
+	q := normalizeSearchQuery(query)
+	if q == "" {
+		return tokens
+	}
+
+	type hit struct {
+		i     int
+		score float64
+	}
+	var strict, fuzzy []hit
+	for i := range tokens {
+		score, typo := matchToken(&tokens[i], q)
+		if score == 0 {
+			continue
+		}
+		h := hit{i, float64(score) + searchBoost(i, len(tokens), tokens[i].Price)}
+		if typo {
+			fuzzy = append(fuzzy, h)
+		} else {
+			strict = append(strict, h)
+		}
+	}
+	hits := strict
+	if len(hits) == 0 {
+		hits = fuzzy
+	}
+	sort.SliceStable(hits, func(a, b int) bool { return hits[a].score > hits[b].score })
+
+	results := make([]TokenInfo, len(hits))
+	for k, h := range hits {
+		results[k] = tokens[h.i]
+	}
+	return results
+}
+
+// matchToken scores t against a normalized query. typo reports a match
+// that needed edits; score 0 means no match.
+func matchToken(t *TokenInfo, q string) (score int, typo bool) {
+	ticker := strings.ToLower(t.Ticker)
+	name := strings.ToLower(t.Name)
+	chain := strings.ToLower(t.ChainName)
+	var network string
+	if c := lookupChain(chain); c != nil {
+		network = strings.ToLower(c.Name)
+	}
+
+	best := func(s int) {
+		if s > score {
+			score = s
+		}
+	}
+	if len(q) >= 6 && (strings.EqualFold(t.ContractAddress, q) || strings.EqualFold(t.DefuseAssetID, q) ||
+		len(q) >= 10 && strings.Contains(strings.ToLower(t.DefuseAssetID), q)) {
+		best(scoreContract)
+	}
+	switch {
+	case ticker == q:
+		best(scoreTicker)
+	case strings.HasPrefix(ticker, q):
+		best(scoreTickerPrefix)
+	case strings.Contains(ticker, q):
+		best(scoreTickerSubstr)
+	}
+	if target, ok := tokenAliases[q]; ok && strings.EqualFold(target, t.Ticker) {
+		best(scoreAlias)
+	}
+	if name != "" {
+		switch {
+		case name == q:
+			best(scoreName)
+		case strings.HasPrefix(name, q) || strings.Contains(name, " "+q):
+			best(scoreNamePrefix)
+		case strings.Contains(name, q):
+			best(scoreNameSubstr)
+		}
+	}
+	switch {
+	case q == chain || q == network:
+		best(scoreNetwork)
+	case strings.Contains(chain, q) || strings.Contains(network, q):
+		best(scoreNetworkSub)
+	}
+	if score > 0 {
+		return score, false
+	}
+
+	// Nothing matched outright; allow a typo or two.
+	limit := maxSearchEdits(q)
+	if limit == 0 {
+		return 0, false
+	}
+	candidates := append([]string{ticker, name}, tickerAliases[strings.ToUpper(ticker)]...)
+	edits := limit + 1
+	for _, c := range candidates {
+		if c != "" {
+			edits = min(edits, editDistance(q, c, limit))
+		}
+	}
+	if edits > limit {
+		return 0, false
+	}
+	return scoreFuzzy - scoreFuzzyEdit*edits, true
+}
+
+// searchBoost ranks within a tier: up to 30 points for list position and
+// up to 19 for price.
+func searchBoost(i, n int, price float64) float64 {
+	boost := 30 * float64(n-i) / float64(n)
+	if price > 0 {
+		boost += math.Min(19, 4*math.Log10(1+price))
+	}
+	return math.Min(boost, maxSearchBoost)
+}
+
+// maxSearchEdits is how many typos a query of this length tolerates.
+// Short queries get none: "eth" is one edit from too many tickers.
+func maxSearchEdits(q string) int {
+	switch {
+	case len(q) < 4:
+		return 0
+	case len(q) < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func normalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// editDistance is the optimal-string-alignment distance between a and b
+// (insertions, deletions, substitutions and adjacent swaps). It returns
+// limit+1 as soon as the distance is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j], cur[j-1])+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return min(prev[len(b)], limit+1)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// searchFixture is the simulator token list with tickers filled in, plus a
+// few tokens that look alike.
+func searchFixture() []TokenInfo {
+	tokens := append([]TokenInfo(nil), simulatorTokens...)
+	tokens = append(tokens,
+		TokenInfo{DefuseAssetID: "nep141:eth-wbtc.omft.near", Symbol: "WBTC", Name: "Wrapped Bitcoin", ChainName: "eth", Price: 60000},
+		TokenInfo{DefuseAssetID: "nep141:usdt0.near", Symbol: "USDT0", ChainName: "near", Price: 1},
+		TokenInfo{DefuseAssetID: "nep141:eth-weth.omft.near", Symbol: "WETH", ChainName: "eth", Price: 2500},
+	)
+	for i := range tokens {
+		tokens[i].Ticker = strings.ToUpper(tokens[i].Symbol)
+	}
+	return tokens
+}
+
+func rankedLabels(tokens []TokenInfo, query string, n int) []string {
+	var labels []string
+	for _, t := range rankTokens(tokens, query) {
+		labels = append(labels, t.Ticker+"/"+t.ChainName)
+		if len(labels) == n {
+			break
+		}
+	}
+	return labels
+}
+
+func TestRankTokens(t *testing.T) {
+	tokens := searchFixture()
+	for _, tc := range []struct {
+		query string
+		want  string // leading results, comma-separated
+	}{
+		{"usdt", "USDT/eth,USDT/tron,USDT0/near"},
+		{"USDT", "USDT/eth,USDT/tron,USDT0/near"},
+		{"usdtt", "USDT/eth,USDT/tron"},
+		{"etherium", "ETH/eth,ETH/base"},
+		{"tether", "USDT/eth,USDT/tron"},
+		{"wrapped bitcoin", "WBTC/eth"},
+		{"bitcoin", "BTC/btc,WBTC/eth"},
+		{"0xdac17f958d2ee523a2206206994597c13d831ec7", "USDT/eth"},
+		{"EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", "USDC/sol"},
+		{"  Solana ", "SOL/sol,USDC/sol"},
+		{"eth", "ETH/eth,ETH/base,WETH/eth"},
+	} {
+		got := rankedLabels(tokens, tc.query, strings.Count(tc.want, ",")+1)
+		if strings.Join(got, ",") != tc.want {
+			t.Errorf("rankTokens(%q) = %v, want %s", tc.query, got, tc.want)
+		}
+	}
+
+	// Exact matches keep near-misses out.
+	for _, tk := range rankTokens(tokens, "usdt") {
+		if tk.Ticker == "USDC" {
+			t.Error(`"usdt" should not match USDC`)
+		}
+	}
+	if got := rankTokens(tokens, "xyzzy"); len(got) != 0 {
+		t.Errorf("nonsense query matched %v", rankedLabels(got, "", 0))
+	}
+	if got := rankTokens(tokens, ""); len(got) != len(tokens) {
+		t.Error("empty query should return everything")
+	}
+}
+
+func TestFilterNetworksRanksGroups(t *testing.T) {
+	withTokenSnapshot(t) // restores the cache afterwards
+	networks := installTokens(searchFixture(), time.Now(), false)
+	got := filterNetworks(networks, "tether")
+	if len(got) != 2 || got[0].Tokens[0].Ticker != "USDT" || got[1].Tokens[0].Ticker != "USDT" {
+		t.Fatalf("filterNetworks(tether) = %+v", got)
+	}
+	if got := filterNetworks(networks, "usdc"); got[0].Name != "Ethereum" || len(got) != 3 {
+		t.Errorf("filterNetworks(usdc) groups = %+v", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"usdt", "usdt", 2, 0},
+		{"usdtt", "usdt", 2, 1},
+		{"etherium", "ethereum", 2, 1},
+		{"uscd", "usdc", 2, 1}, // adjacent swap
+		{"bitcoin", "btc", 2, 3},
+		{"solana", "sui", 1, 2},
+	} {
+		if got := editDistance(tc.a, tc.b, tc.limit); got != tc.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tc.a, tc.b, tc.limit, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// With TOKEN_SNAPSHOT_PATH set, every successful token refresh writes the
+// list (prices and decimals included) to that file, and startup loads it
+// before the first live refresh. If 1Click is down at boot the site still
+// has tokens to show; pages say the list is a snapshot until a live refresh
+// replaces it. The file holds only public token metadata.
+
+var tokenSnapshotPath = os.Getenv("TOKEN_SNAPSHOT_PATH")
+
+type tokenSnapshot struct {
+	SavedAt time.Time   `json:"savedAt"`
+	Tokens  []TokenInfo `json:"tokens"`
+
+	// Changes is the listing log (see listings.go), so "New" badges and
+	// the feed survive restarts.
+	Changes []listingChange `json:"changes,omitempty"`
+}
+
+// saveTokenSnapshot writes tokens to the snapshot file, replacing it
+// atomically so a crash mid-write never leaves a truncated list.
+func saveTokenSnapshot(tokens []TokenInfo) {
+	if tokenSnapshotPath == "" || len(tokens) == 0 {
+		return
+	}
+	data, err := json.Marshal(tokenSnapshot{SavedAt: time.Now().UTC(), Tokens: tokens, Changes: recentListingChanges(0)})
+	if err != nil {
+		log.Printf("token snapshot encode error: %v", err)
+		return
+	}
+	if err := writeFileAtomic(tokenSnapshotPath, data); err != nil {
+		log.Printf("token snapshot write error: %v", err)
+	}
+}
+
+// writeFileAtomic writes data to a temp file next to path and renames it
+// into place, creating the directory if needed.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	os.MkdirAll(dir, 0755)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// loadTokenSnapshot fills the token cache from the snapshot file, marked
+// as such. Reports whether anything was loaded.
+func loadTokenSnapshot() bool {
+	if tokenSnapshotPath == "" {
+		return false
+	}
+	data, err := os.ReadFile(tokenSnapshotPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("token snapshot read error: %v", err)
+		}
+		return false
+	}
+	var snap tokenSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil || len(snap.Tokens) == 0 {
+		log.Printf("token snapshot ignored: unreadable or empty")
+		return false
+	}
+	installTokens(snap.Tokens, snap.SavedAt, true)
+	seedListings(snap.Tokens, snap.Changes)
+	log.Printf("Loaded %d tokens from snapshot saved %s", len(snap.Tokens), snap.SavedAt.Format(time.RFC3339))
+	return true
+}
+
+// tokenCacheSnapshotTime returns when the cached tokens were saved, or the
+// zero time when they came from a live refresh.
+func tokenCacheSnapshotTime() time.Time {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	if !cache.fromSnapshot {
+		return time.Time{}
+	}
+	return cache.updatedAt
+}
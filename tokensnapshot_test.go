@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withTokenSnapshot points the snapshot at a temp file and restores the
+// whole token cache afterwards.
+func withTokenSnapshot(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data", "tokens.json")
+	savedPath := tokenSnapshotPath
+	tokenSnapshotPath = path
+
+	cache.mu.Lock()
+	tokens, byAssetID, networks := cache.tokens, cache.byAssetID, cache.networks
+	updatedAt, fromSnapshot, lastErr := cache.updatedAt, cache.fromSnapshot, cache.lastErr
+	cache.mu.Unlock()
+	t.Cleanup(func() {
+		tokenSnapshotPath = savedPath
+		cache.mu.Lock()
+		cache.tokens, cache.byAssetID, cache.networks = tokens, byAssetID, networks
+		cache.updatedAt, cache.fromSnapshot, cache.lastErr = updatedAt, fromSnapshot, lastErr
+		cache.mu.Unlock()
+	})
+	return path
+}
+
+func pageShowsSnapshotBanner(t *testing.T) bool {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handleHowItWorks(w, httptest.NewRequest("GET", "/how-it-works", nil))
+	return strings.Contains(w.Body.String(), "from a snapshot saved")
+}
+
+func TestTokenSnapshotColdStart(t *testing.T) {
+	path := withTokenSnapshot(t)
+	up, price := true, "2500.5"
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `[{"assetId":"nep141:eth.omft.near","symbol":"eth","blockchain":"eth","decimals":18,"price":%s}]`, price)
+	})
+
+	// A live refresh writes the snapshot.
+	if err := refreshTokenCache(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("snapshot not written: %v", err)
+	}
+	var snap tokenSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil || len(snap.Tokens) != 1 {
+		t.Fatalf("snapshot content: %v %s", err, data)
+	}
+	if tok := snap.Tokens[0]; tok.Decimals != 18 || tok.Price != 2500.5 || tok.Ticker != "ETH" {
+		t.Errorf("snapshot should keep prices and decimals: %+v", tok)
+	}
+	if pageShowsSnapshotBanner(t) {
+		t.Error("live tokens must not show the snapshot banner")
+	}
+
+	// Simulated restart with upstream down: the snapshot fills the cache.
+	cache.mu.Lock()
+	cache.tokens, cache.byAssetID, cache.networks = nil, nil, nil
+	cache.mu.Unlock()
+	up = false
+	if !loadTokenSnapshot() {
+		t.Fatal("snapshot did not load")
+	}
+	if err := refreshTokenCache(); err == nil {
+		t.Fatal("refresh should fail while upstream is down")
+	}
+	if tok := findToken("ETH", "eth"); tok == nil || tok.Price != 2500.5 {
+		t.Fatalf("findToken from snapshot: %+v", tok)
+	}
+	if at := tokenCacheSnapshotTime(); !at.Equal(snap.SavedAt) {
+		t.Errorf("snapshot time = %v, want %v", at, snap.SavedAt)
+	}
+	if !pageShowsSnapshotBanner(t) {
+		t.Error("pages should say the token list is a snapshot")
+	}
+	w := httptest.NewRecorder()
+	handleAPITokens(w, httptest.NewRequest("GET", "/api/v1/tokens", nil))
+	if !strings.Contains(w.Body.String(), `"snapshotSavedAt"`) {
+		t.Errorf("API should flag snapshot data: %s", w.Body.String())
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("a failed refresh must not remove the snapshot")
+	}
+
+	// Upstream is back: the live list replaces the snapshot.
+	up, price = true, "2600"
+	if err := refreshTokenCache(); err != nil {
+		t.Fatal(err)
+	}
+	if tok := findToken("ETH", "eth"); tok == nil || tok.Price != 2600 {
+		t.Errorf("live price should replace the snapshot: %+v", tok)
+	}
+	if !tokenCacheSnapshotTime().IsZero() || pageShowsSnapshotBanner(t) {
+		t.Error("a live refresh should clear the snapshot flag")
+	}
+}
+
+func TestTokenSnapshotIgnoresBadFiles(t *testing.T) {
+	path := withTokenSnapshot(t)
+	if loadTokenSnapshot() {
+		t.Error("missing file should not load")
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	for _, content := range []string{"{not json", `{"savedAt":"2026-03-01T00:00:00Z","tokens":[]}`} {
+		os.WriteFile(path, []byte(content), 0644)
+		if loadTokenSnapshot() {
+			t.Errorf("%q should not load", content)
+		}
+	}
+
+	tokenSnapshotPath = ""
+	saveTokenSnapshot([]TokenInfo{{DefuseAssetID: "x", Ticker: "X"}})
+	if loadTokenSnapshot() {
+		t.Error("an empty TOKEN_SNAPSHOT_PATH disables the snapshot")
+	}
+}
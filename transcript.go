@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Quote transcripts let users audit an order without trusting the UI: the
+// exact QuoteRequest we POSTed to /v0/quote (showing "appFees": []) and the
+// exact upstream response with its signature, plus timestamps.
+//
+// The order token carries only the SHA-256 of the transcript document
+// (OrderData.TranscriptHash), which keeps links short. The document itself
+// is held in memory for transcriptHoldTTL, so /order/{token}/transcript can
+// serve it right after the order is placed; users keep that download, and
+// POSTing it back to the same URL checks it against the hash in the token.
+// Nothing is written to disk. Tokens issued before the hash carried the
+// whole transcript DEFLATE-compressed (OrderData.Transcript); those are
+// still served from the token.
+
+// transcriptHoldTTL is how long a transcript stays downloadable; a var so
+// tests can change it.
+var transcriptHoldTTL = time.Hour
+
+// transcriptHoldMaxEntries bounds memory. Expired entries are swept when
+// the limit is reached; if everything is still fresh the new transcript
+// isn't held (the hash still goes in the token).
+const transcriptHoldMaxEntries = 5000
+
+// quoteTranscript is the request/response pair. Request and Response hold
+// the bytes sent and received, unmodified.
+type quoteTranscript struct {
+	RequestedAt time.Time
+	ReceivedAt  time.Time
+	Request     []byte
+	Response    []byte
+}
+
+type heldTranscript struct {
+	doc     []byte
+	expires time.Time
+}
+
+// transcriptHold keeps recent transcript documents keyed by their hash.
+type transcriptHold struct {
+	mu      sync.Mutex
+	entries map[string]heldTranscript
+}
+
+var heldTranscripts = &transcriptHold{entries: make(map[string]heldTranscript)}
+
+func (h *transcriptHold) put(hash string, doc []byte) {
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) >= transcriptHoldMaxEntries {
+		for k, e := range h.entries {
+			if now.After(e.expires) {
+				delete(h.entries, k)
+			}
+		}
+		if len(h.entries) >= transcriptHoldMaxEntries {
+			return
+		}
+	}
+	h.entries[hash] = heldTranscript{doc: doc, expires: now.Add(transcriptHoldTTL)}
+}
+
+// get returns a held document, or nil once it has expired.
+func (h *transcriptHold) get(hash string) []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.entries[hash]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expires) {
+		delete(h.entries, hash)
+		return nil
+	}
+	return e.doc
+}
+
+// keepTranscript builds the transcript document for a freshly placed
+// order, holds it for download and returns its hex SHA-256 for the token.
+// Returns "" if there is nothing to record.
+func keepTranscript(order *OrderData, resp *QuoteResponse) string {
+	if resp.RequestJSON == nil || resp.RawJSON == nil {
+		return ""
+	}
+	doc := transcriptDocument(order, &quoteTranscript{
+		RequestedAt: resp.RequestedAt.UTC(),
+		ReceivedAt:  resp.ReceivedAt.UTC(),
+		Request:     resp.RequestJSON,
+		Response:    resp.RawJSON,
+	})
+	hash := transcriptHash(doc)
+	heldTranscripts.put(hash, doc)
+	return hash
+}
+
+func transcriptHash(doc []byte) string {
+	sum := sha256.Sum256(doc)
+	return hex.EncodeToString(sum[:])
+}
+
+// openTranscript decodes the transcript carried by older tokens.
+func openTranscript(sealed string) (*quoteTranscript, error) {
+	zr := flate.NewReader(strings.NewReader(sealed))
+	defer zr.Close()
+	doc, err := io.ReadAll(io.LimitReader(zr, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("decompress transcript: %w", err)
+	}
+
+	var fields [3]uint64
+	for i := range fields {
+		v, n := binary.Uvarint(doc)
+		if n <= 0 {
+			return nil, fmt.Errorf("bad transcript header")
+		}
+		fields[i], doc = v, doc[n:]
+	}
+	if fields[2] > uint64(len(doc)) {
+		return nil, fmt.Errorf("transcript truncated")
+	}
+	return &quoteTranscript{
+		RequestedAt: time.UnixMilli(int64(fields[0])).UTC(),
+		ReceivedAt:  time.UnixMilli(int64(fields[1])).UTC(),
+		Request:     doc[:fields[2]],
+		Response:    doc[fields[2]:],
+	}, nil
+}
+
+// transcriptDocument renders the downloadable JSON. The request and
+// response are spliced in byte-for-byte rather than re-encoded, so they can
+// be diffed against what the API returns.
+func transcriptDocument(order *OrderData, tr *quoteTranscript) []byte {
+	header, _ := json.Marshal(struct {
+		CorrelationID  string `json:"correlationId"`
+		Endpoint       string `json:"endpoint"`
+		RequestedAt    string `json:"requestedAt"`
+		ReceivedAt     string `json:"receivedAt"`
+		QuoteSignature string `json:"quoteSignature,omitempty"`
+	}{
+		CorrelationID:  order.CorrID,
+		Endpoint:       "POST " + nearIntentsBaseURL + "/v0/quote",
+		RequestedAt:    tr.RequestedAt.Format(time.RFC3339Nano),
+		ReceivedAt:     tr.ReceivedAt.Format(time.RFC3339Nano),
+		QuoteSignature: order.QuoteSig,
+	})
+
+	var out bytes.Buffer
+	out.Write(header[:len(header)-1]) // drop the closing brace
+	out.WriteString(`,"request":`)
+	out.Write(tr.Request)
+	out.WriteString(`,"response":`)
+	out.Write(tr.Response)
+	out.WriteString("}\n")
+	return out.Bytes()
+}
+
+// orderTranscript returns the order's transcript document, from the token
+// for older orders or from the in-memory hold. ok is false when the order
+// has a transcript that is no longer held.
+func orderTranscript(order *OrderData) (doc []byte, ok bool, err error) {
+	if order.Transcript != "" {
+		tr, err := openTranscript(order.Transcript)
+		if err != nil {
+			return nil, false, err
+		}
+		return transcriptDocument(order, tr), true, nil
+	}
+	doc = heldTranscripts.get(order.TranscriptHash)
+	return doc, doc != nil, nil
+}
+
+// handleOrderTranscript serves the quote transcript as a JSON download, or,
+// for POST, checks an uploaded copy against the order.
+func handleOrderTranscript(w http.ResponseWriter, r *http.Request, order *OrderData) {
+	if order.Transcript == "" && order.TranscriptHash == "" {
+		writeAPIError(w, http.StatusNotFound, "no_transcript", "This order was created before quote transcripts were recorded.")
+		return
+	}
+	if r.Method == http.MethodPost {
+		checkOrderTranscript(w, r, order)
+		return
+	}
+	doc, ok, err := orderTranscript(order)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal_error", "The transcript in this order link could not be read.")
+		return
+	}
+	if !ok {
+		writeAPIError(w, http.StatusGone, "transcript_expired", "The server only holds a quote transcript for a short time after the order is placed. POST your saved copy to this URL to check it against the order.")
+		return
+	}
+
+	name := "zero-quote-transcript.json"
+	if id := strings.Map(func(r rune) rune {
+		if r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, order.CorrID); id != "" {
+		name = "zero-quote-" + id + ".json"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(doc)
+}
+
+// checkOrderTranscript compares an uploaded transcript, sent as the raw
+// body or as the "transcript" file of a multipart form, with the hash in
+// the order token.
+func checkOrderTranscript(w http.ResponseWriter, r *http.Request, order *OrderData) {
+	want := order.TranscriptHash
+	if want == "" {
+		doc, _, err := orderTranscript(order)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal_error", "The transcript in this order link could not be read.")
+			return
+		}
+		want = transcriptHash(doc)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := r.FormFile("transcript")
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_transcript", "Attach the transcript file as \"transcript\".")
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_transcript", "The transcript could not be read (1 MB limit).")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Match bool `json:"match"`
+	}{hex.EncodeToString(h.Sum(nil)) == want})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Resilience for 1Click calls (see nearRequest):
+//
+//   - Every call carries the caller's context, so a request the user gave
+//     up on stops waiting on upstream. Calls without a deadline get
+//     upstreamCallBudget; each attempt gets upstreamAttemptTimeout.
+//   - Errors, 5xx and 429 are retried with exponential backoff and full
+//     jitter. A Retry-After header raises the delay; one beyond
+//     upstreamRetryAfterMax (or past the caller's deadline) is not waited
+//     out.
+//   - A circuit breaker opens after upstreamBreakerThreshold consecutive
+//     failed calls. While open, calls fail fast with errUpstreamOpen and
+//     pages show a degraded-mode banner. After upstreamBreakerCooldown a
+//     single probe call is let through; success closes the circuit.
+
+const (
+	upstreamMaxAttempts      = 3
+	upstreamAttemptTimeout   = 30 * time.Second // quotes may wait 24s for solvers
+	upstreamCallBudget       = 60 * time.Second
+	upstreamRetryAfterMax    = 10 * time.Second
+	upstreamBreakerThreshold = 5
+	upstreamBreakerCooldown  = 30 * time.Second
+)
+
+// Backoff bounds; vars so tests can shrink them.
+var (
+	upstreamBackoffBase = 500 * time.Millisecond
+	upstreamBackoffMax  = 5 * time.Second
+)
+
+// errUpstreamOpen is returned without contacting 1Click while the circuit
+// breaker is open.
+var errUpstreamOpen = errors.New("1Click API is unavailable (circuit open); try again shortly")
+
+// upstreamBreaker guards every 1Click call.
+var upstreamBreaker = newCircuitBreaker(upstreamBreakerThreshold, upstreamBreakerCooldown)
+
+// backoffDelay returns the wait before retry number attempt (1-based):
+// uniformly random in [0, min(max, base·2^(attempt-1))].
+func backoffDelay(attempt int) time.Duration {
+	ceiling := upstreamBackoffMax
+	if shift := attempt - 1; shift < 30 && upstreamBackoffBase<<shift < ceiling {
+		ceiling = upstreamBackoffBase << shift
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// parseRetryAfter reads a Retry-After header in either delta-seconds or
+// HTTP-date form. ok is false when the header is absent or malformed.
+func parseRetryAfter(h http.Header, now time.Time) (d time.Duration, ok bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0), true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// upstreamContext bounds work that has no request to inherit a deadline
+// from: Telegram updates, background watchers, cache refreshes.
+func upstreamContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), upstreamCallBudget)
+}
+
+// upstreamDegraded reports whether pages should show the degraded banner.
+func upstreamDegraded() bool {
+	return upstreamBreaker.state() != breakerClosed
+}
+
+// --- Circuit breaker ---
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int       // consecutive failed calls
+	openUntil time.Time // zero unless tripped
+	probing   bool      // half-open probe in flight
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+func (b *circuitBreaker) state() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+func (b *circuitBreaker) stateLocked() breakerState {
+	switch {
+	case b.failures < b.threshold:
+		return breakerClosed
+	case b.now().Before(b.openUntil):
+		return breakerOpen
+	}
+	return breakerHalfOpen
+}
+
+// allow reports whether a call may proceed. In the half-open state only
+// one probe call is admitted at a time. Every admitted call must be
+// followed by exactly one of success, failure or abandon.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateLocked() {
+	case breakerOpen:
+		return errUpstreamOpen
+	case breakerHalfOpen:
+		if b.probing {
+			return errUpstreamOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// success records that upstream answered (any status other than 5xx/429).
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+}
+
+// failure records a call that exhausted its retries.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+// abandon releases a call that ended without telling us anything about
+// upstream, such as one cancelled by its caller.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
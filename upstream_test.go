@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	savedBase, savedMax := upstreamBackoffBase, upstreamBackoffMax
+	upstreamBackoffBase, upstreamBackoffMax = 100*time.Millisecond, time.Second
+	defer func() { upstreamBackoffBase, upstreamBackoffMax = savedBase, savedMax }()
+
+	for attempt, ceiling := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 60: time.Second} {
+		for i := 0; i < 50; i++ {
+			if d := backoffDelay(attempt); d < 0 || d > ceiling {
+				t.Fatalf("backoffDelay(%d) = %v, want within [0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for v, want := range map[string]time.Duration{
+		"3":                             3 * time.Second,
+		"0":                             0,
+		"-5":                            0,
+		"Sun, 01 Mar 2026 12:00:07 GMT": 7 * time.Second,
+		"Sun, 01 Mar 2026 11:00:00 GMT": 0,
+	} {
+		d, ok := parseRetryAfter(http.Header{"Retry-After": {v}}, now)
+		if !ok || d != want {
+			t.Errorf("Retry-After %q = %v, %v; want %v", v, d, ok, want)
+		}
+	}
+	for _, v := range []string{"", "soon"} {
+		if _, ok := parseRetryAfter(http.Header{"Retry-After": {v}}, now); ok {
+			t.Errorf("Retry-After %q should not parse", v)
+		}
+	}
+}
+
+func TestNearRequestRetries(t *testing.T) {
+	var calls atomic.Int32
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			fmt.Fprint(w, `{"status":"PROCESSING"}`)
+		}
+	})
+	status, err := fetchStatus(context.Background(), "0xretry", "")
+	if err != nil || status.Status != "PROCESSING" {
+		t.Fatalf("got %+v, %v after %d calls", status, err, calls.Load())
+	}
+	if calls.Load() != 3 {
+		t.Errorf("upstream called %d times, want 3", calls.Load())
+	}
+}
+
+func TestNearRequestDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, `{"message":"amount is too low"}`, http.StatusBadRequest)
+	})
+	_, err := fetchStatus(context.Background(), "0xbad", "")
+	if err == nil || !strings.Contains(err.Error(), "API error 400") {
+		t.Fatalf("err = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("4xx was retried: %d calls", calls.Load())
+	}
+	if upstreamBreaker.state() != breakerClosed {
+		t.Error("a 4xx means upstream is up; the breaker must stay closed")
+	}
+}
+
+func TestNearRequestLongRetryAfterGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	start := time.Now()
+	if _, err := fetchStatus(context.Background(), "0xbusy", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls.Load() != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Retry-After beyond the cap should fail at once: %d calls in %v", calls.Load(), time.Since(start))
+	}
+}
+
+func TestNearRequestHonorsCancellation(t *testing.T) {
+	release := make(chan struct{})
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := intents.Status(ctx, "0xslow", "") // direct: statusCache detaches its fetch
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("cancelled call took %v", time.Since(start))
+	}
+	if upstreamBreaker.state() != breakerClosed {
+		t.Error("a caller giving up says nothing about upstream; the breaker must stay closed")
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	healthy := atomic.Bool{}
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"status":"SUCCESS"}`)
+	})
+	clock := time.Now()
+	upstreamBreaker.now = func() time.Time { return clock }
+
+	for i := 0; i < upstreamBreakerThreshold; i++ {
+		if _, err := fetchStatus(context.Background(), "0xdown", ""); err == nil {
+			t.Fatal("expected failure")
+		}
+	}
+	if upstreamBreaker.state() != breakerOpen || !upstreamDegraded() {
+		t.Fatalf("breaker should be open after %d failed calls", upstreamBreakerThreshold)
+	}
+
+	// Open: fail fast without touching upstream.
+	before := calls.Load()
+	if _, err := fetchStatus(context.Background(), "0xdown", ""); !errors.Is(err, errUpstreamOpen) {
+		t.Errorf("err = %v, want errUpstreamOpen", err)
+	}
+	if calls.Load() != before {
+		t.Error("open breaker must not call upstream")
+	}
+
+	// The degraded banner shows on pages and the API says why.
+	w := httptest.NewRecorder()
+	handleHowItWorks(w, httptest.NewRequest("GET", "/how-it-works", nil))
+	if !strings.Contains(w.Body.String(), `class="degraded-banner"`) {
+		t.Error("pages should show the degraded banner while the breaker is open")
+	}
+	if serr := upstreamSwapError(errUpstreamOpen, "Quote Failed"); serr.Status != 503 {
+		t.Errorf("open-circuit swap error status = %d", serr.Status)
+	}
+
+	// Half-open after the cooldown: one probe; success closes it.
+	clock = clock.Add(upstreamBreakerCooldown)
+	if upstreamBreaker.state() != breakerHalfOpen {
+		t.Fatalf("state = %v, want half-open", upstreamBreaker.state())
+	}
+	healthy.Store(true)
+	if _, err := fetchStatus(context.Background(), "0xdown", ""); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if upstreamBreaker.state() != breakerClosed || upstreamDegraded() {
+		t.Error("a successful probe should close the breaker")
+	}
+	w = httptest.NewRecorder()
+	handleHowItWorks(w, httptest.NewRequest("GET", "/how-it-works", nil))
+	if strings.Contains(w.Body.String(), `class="degraded-banner"`) {
+		t.Error("banner should be gone once upstream recovers")
+	}
+}
+
+func TestCircuitBreakerHalfOpenAdmitsOneProbe(t *testing.T) {
+	clock := time.Now()
+	b := newCircuitBreaker(1, time.Minute)
+	b.now = func() time.Time { return clock }
+	b.allow()
+	b.failure()
+	if err := b.allow(); err == nil {
+		t.Fatal("open breaker admitted a call")
+	}
+	clock = clock.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("half-open should admit a probe: %v", err)
+	}
+	if err := b.allow(); err == nil {
+		t.Error("only one probe at a time")
+	}
+	b.failure()
+	if b.state() != breakerOpen {
+		t.Errorf("failed probe should reopen, state %v", b.state())
+	}
+}
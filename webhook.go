@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Per-order status webhooks. The callback URL and secret live only inside the
+// encrypted order token; watchers are in-memory goroutines, so nothing is
+// written to disk and every watcher is lost on restart. One resumes only
+// when the order is opened again (web page, SSE stream or /api/v1/order), so
+// an order nobody reopens gets no further events. A resumed watcher starts
+// from the status it finds then: it never repeats a status, and a change
+// made while nothing was watching is not sent. Each event is retried until
+// the receiver accepts it, so receivers should still dedupe on
+// (depositAddress, status). At webhookMaxWatchers
+// new orders with a callback URL are refused rather than accepted unwatched.
+
+var (
+	// webhookPollInterval is how often a watcher polls 1Click for status.
+	webhookPollInterval = 15 * time.Second
+	// webhookRetryDelays is the backoff between delivery attempts.
+	webhookRetryDelays = []time.Duration{5 * time.Second, 15 * time.Second, 45 * time.Second, 2 * time.Minute, 5 * time.Minute}
+)
+
+const (
+	// webhookMaxWatch bounds how long a single watcher runs. Refunds can land
+	// well after the quote deadline, so this is deliberately generous.
+	webhookMaxWatch = 24 * time.Hour
+	// webhookMaxWatchers caps concurrent watchers process-wide.
+	webhookMaxWatchers = 500
+	// webhookQueueSize bounds events waiting for delivery per watcher;
+	// an order has far fewer transitions than this.
+	webhookQueueSize = 16
+)
+
+// webhookClient refuses to connect to loopback, private and link-local
+// addresses (checked after DNS resolution) and never follows redirects.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+		MaxIdleConns:    10,
+		IdleConnTimeout: 30 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+var errWebhookAddress = errors.New("webhook: destination address not allowed")
+
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil || !isPublicAddr(ap.Addr()) {
+		return errWebhookAddress
+	}
+	return nil
+}
+
+// nonPublicPrefixes are global unicast ranges that still reach internal or
+// translated destinations: carrier-grade NAT and the NAT64 prefixes, which
+// embed an arbitrary IPv4 address.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// isPublicAddr reports whether addr is a globally routable unicast address.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateCallback checks an optional callback URL/secret pair.
+// Returns nil when both are empty.
+func validateCallback(rawURL, secret string) []string {
+	if rawURL == "" {
+		if secret != "" {
+			return []string{"Callback secret was given without a callback URL"}
+		}
+		return nil
+	}
+
+	var errs []string
+	u, err := url.Parse(rawURL)
+	switch {
+	case err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "":
+		errs = append(errs, "Callback URL must be an http:// or https:// URL")
+	case len(rawURL) > 512:
+		errs = append(errs, "Callback URL is too long (max 512 characters)")
+	case u.User != nil:
+		errs = append(errs, "Callback URL must not contain credentials")
+	default:
+		if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !isPublicAddr(ip) {
+			errs = append(errs, "Callback URL must point to a public address")
+		}
+	}
+
+	if len(secret) < 16 || len(secret) > 128 {
+		errs = append(errs, "Callback secret must be 16–128 characters")
+	}
+	return errs
+}
+
+// webhookEvent is the JSON body POSTed on every status transition.
+type webhookEvent struct {
+	Event          string       `json:"event"` // always "order.status"
+	DepositAddress string       `json:"depositAddress"`
+	DepositMemo    string       `json:"depositMemo,omitempty"`
+	CorrelationID  string       `json:"correlationId,omitempty"`
+	Status         string       `json:"status"`
+	PreviousStatus string       `json:"previousStatus,omitempty"`
+	IsTerminal     bool         `json:"isTerminal"`
+	SwapDetails    *SwapDetails `json:"swapDetails,omitempty"`
+	Timestamp      string       `json:"timestamp"`
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>".
+// Receivers recompute it with their secret and compare in constant time.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRegistry tracks running watchers so each order has at most one.
+type webhookRegistry struct {
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+var webhooks = &webhookRegistry{active: make(map[string]bool)}
+
+// watch starts a watcher for a new order unless one is already running.
+// Every status it sees, including the first, is delivered. It is a no-op
+// for orders without a callback URL. A watcher that cannot start because
+// the registry is full is logged and counted.
+func (wr *webhookRegistry) watch(order *OrderData) {
+	wr.start(order, false)
+}
+
+// resume restarts the watcher for an existing order, e.g. when its page is
+// opened after a restart. The status it finds first is taken as already
+// delivered, and an order that has already finished is not watched.
+func (wr *webhookRegistry) resume(order *OrderData) {
+	wr.start(order, true)
+}
+
+func (wr *webhookRegistry) start(order *OrderData, resumed bool) {
+	if order.CallbackURL == "" || order.CallbackSecret == "" {
+		return
+	}
+	key := order.DepositAddr + "|" + order.Memo
+
+	wr.mu.Lock()
+	if wr.active[key] {
+		wr.mu.Unlock()
+		return
+	}
+	if len(wr.active) >= webhookMaxWatchers {
+		wr.mu.Unlock()
+		webhookWatchersDropped.inc()
+		log.Printf("WARNING: webhook watcher not started: %d watchers already running", webhookMaxWatchers)
+		return
+	}
+	wr.active[key] = true
+	wr.mu.Unlock()
+
+	go func() {
+		defer func() {
+			wr.mu.Lock()
+			delete(wr.active, key)
+			wr.mu.Unlock()
+		}()
+		runWebhookWatcher(order, resumed)
+	}()
+}
+
+// full reports whether a new watcher would be refused.
+func (wr *webhookRegistry) full() bool {
+	return wr.count() >= webhookMaxWatchers
+}
+
+func (wr *webhookRegistry) count() int {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return len(wr.active)
+}
+
+// runWebhookWatcher polls the order's status and queues one event per
+// transition until the status is terminal or webhookMaxWatch elapses. A
+// resumed watcher only seeds its last status from the first poll. Events
+// are delivered in order on a separate goroutine, so a slow receiver
+// never holds up polling; the watcher returns once the queue is drained.
+func runWebhookWatcher(order *OrderData, resumed bool) {
+	queue := make(chan webhookEvent, webhookQueueSize)
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		for ev := range queue {
+			if err := deliverWebhook(order.CallbackURL, order.CallbackSecret, &ev); err != nil {
+				log.Printf("webhook: %s → %s: %v", truncAddr(order.DepositAddr), ev.Status, err)
+			}
+		}
+	}()
+	defer func() {
+		close(queue)
+		<-delivered
+	}()
+
+	stop := time.Now().Add(webhookMaxWatch)
+	last := ""
+	for time.Now().Before(stop) {
+		ctx, cancel := upstreamContext()
+		status, err := fetchStatus(ctx, order.DepositAddr, order.Memo)
+		cancel()
+		if err == nil && resumed {
+			resumed = false
+			last = status.Status
+			if _, terminal := orderStatusStep(last); terminal {
+				return
+			}
+		} else if err == nil && status.Status != last {
+			_, terminal := orderStatusStep(status.Status)
+			queue <- webhookEvent{
+				Event:          "order.status",
+				DepositAddress: order.DepositAddr,
+				DepositMemo:    order.Memo,
+				CorrelationID:  order.CorrID,
+				Status:         status.Status,
+				PreviousStatus: last,
+				IsTerminal:     terminal,
+				SwapDetails:    status.SwapDetails,
+				Timestamp:      time.Now().UTC().Format(time.RFC3339),
+			}
+			last = status.Status
+			if terminal {
+				return
+			}
+		}
+		time.Sleep(webhookPollInterval)
+	}
+}
+
+// deliverWebhook POSTs a signed event, retrying with backoff until the
+// receiver answers 2xx or the retry schedule is exhausted.
+func deliverWebhook(callbackURL, secret string, ev *webhookEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= len(webhookRetryDelays); attempt++ {
+		if attempt > 0 {
+			time.Sleep(webhookRetryDelays[attempt-1])
+		}
+
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "uSwapZero-Webhook/1")
+		req.Header.Set("X-Zero-Timestamp", ts)
+		req.Header.Set("X-Zero-Signature", "sha256="+signWebhook(secret, ts, body))
+		req.Header.Set("X-Zero-Attempt", strconv.Itoa(attempt+1))
+
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("receiver returned HTTP %d", resp.StatusCode)
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", len(webhookRetryDelays)+1, lastErr)
+}
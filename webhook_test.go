@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateCallback(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	tests := []struct {
+		url, secret string
+		wantErrs    int
+	}{
+		{"", "", 0},
+		{"https://example.com/hook", secret, 0},
+		{"http://example.com:8080/hook?x=1", secret, 0},
+		{"", secret, 1},
+		{"ftp://example.com/hook", secret, 1},
+		{"example.com/hook", secret, 1},
+		{"https://user:pw@example.com/hook", secret, 1},
+		{"https://127.0.0.1/hook", secret, 1},
+		{"https://10.1.2.3/hook", secret, 1},
+		{"https://[::1]/hook", secret, 1},
+		{"https://example.com/hook", "short", 1},
+		{"https://example.com/hook", "", 1},
+		{"https://example.com/" + strings.Repeat("a", 600), secret, 1},
+	}
+	for _, tt := range tests {
+		errs := validateCallback(tt.url, tt.secret)
+		if len(errs) != tt.wantErrs {
+			t.Errorf("validateCallback(%q, %d-char secret) = %v, want %d errors", tt.url, len(tt.secret), errs, tt.wantErrs)
+		}
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"1.1.1.1":          true,
+		"2606:4700::1111":  true,
+		"::ffff:8.8.8.8":   true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.0.0.1":         false,
+		"172.16.0.1":       false,
+		"192.168.1.10":     false,
+		"169.254.169.254":  false, // cloud metadata
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::ffff:127.0.0.1": false,
+		"100.64.0.1":       false, // carrier-grade NAT
+		"100.127.255.254":  false,
+		"100.128.0.1":      true,
+		"64:ff9b::a00:1":   false, // NAT64 of 10.0.0.1
+		"64:ff9b:1::1":     false,
+	} {
+		if got := isPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+// withWebhookReceiver lets the webhook client reach a loopback test server
+// and shortens the retry/poll schedule.
+func withWebhookReceiver(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	savedClient, savedDelays, savedPoll := webhookClient, webhookRetryDelays, webhookPollInterval
+	webhookClient = srv.Client()
+	webhookRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	webhookPollInterval = time.Millisecond
+	t.Cleanup(func() {
+		webhookClient, webhookRetryDelays, webhookPollInterval = savedClient, savedDelays, savedPoll
+		srv.Close()
+	})
+	return srv.URL
+}
+
+func TestDeliverWebhookSignsAndRetries(t *testing.T) {
+	secret := "0123456789abcdef"
+	attempts := 0
+	url := withWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		want := "sha256=" + signWebhook(secret, r.Header.Get("X-Zero-Timestamp"), body)
+		if r.Header.Get("X-Zero-Signature") != want {
+			t.Errorf("attempt %d: bad signature %q", attempts, r.Header.Get("X-Zero-Signature"))
+		}
+		if attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := deliverWebhook(url, secret, &webhookEvent{Event: "order.status", Status: "SUCCESS"})
+	if err != nil {
+		t.Fatalf("deliverWebhook: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2 (one retry after 503)", attempts)
+	}
+}
+
+func TestDeliverWebhookGivesUp(t *testing.T) {
+	attempts := 0
+	url := withWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	err := deliverWebhook(url, "0123456789abcdef", &webhookEvent{Status: "FAILED"})
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries")
+	}
+	if attempts != len(webhookRetryDelays)+1 {
+		t.Errorf("attempts = %d, want %d", attempts, len(webhookRetryDelays)+1)
+	}
+}
+
+func TestWebhookWatcherDeliversTransitions(t *testing.T) {
+	statuses := []string{"PENDING_DEPOSIT", "PENDING_DEPOSIT", "PROCESSING", "PROCESSING", "SUCCESS"}
+	var mu sync.Mutex
+	polls := 0
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		s := statuses[min(polls, len(statuses)-1)]
+		polls++
+		mu.Unlock()
+		fmt.Fprintf(w, `{"status":%q}`, s)
+	})
+	withoutStatusCache(t)
+
+	var got []webhookEvent
+	url := withWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		var ev webhookEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		got = append(got, ev)
+		mu.Unlock()
+	})
+
+	order := &OrderData{DepositAddr: "0xwebhook", CorrID: "corr-1", CallbackURL: url, CallbackSecret: "0123456789abcdef"}
+	webhooks.watch(order)
+	webhooks.watch(order) // second call must not start a duplicate watcher
+
+	deadline := time.Now().Add(5 * time.Second)
+	for webhooks.count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"PENDING_DEPOSIT", "PROCESSING", "SUCCESS"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, ev := range got {
+		if ev.Status != want[i] {
+			t.Errorf("event %d: status %q, want %q", i, ev.Status, want[i])
+		}
+		if ev.CorrelationID != "corr-1" {
+			t.Errorf("event %d: correlationId %q", i, ev.CorrelationID)
+		}
+	}
+	if got[2].PreviousStatus != "PROCESSING" || !got[2].IsTerminal {
+		t.Errorf("final event: %+v", got[2])
+	}
+}
+
+func TestOrderTokenCarriesCallback(t *testing.T) {
+	in := &OrderData{DepositAddr: "0xabc", CallbackURL: "https://example.com/h", CallbackSecret: "0123456789abcdef"}
+	token, err := encryptOrderData(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := decryptOrderData(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.CallbackURL != in.CallbackURL || out.CallbackSecret != in.CallbackSecret {
+		t.Errorf("callback lost in round trip: %+v", out)
+	}
+	if o := newAPIOrder(out); o.CallbackURL != in.CallbackURL {
+		t.Error("API order should expose the callback URL")
+	}
+	data, _ := json.Marshal(newAPIOrder(out))
+	if strings.Contains(string(data), in.CallbackSecret) {
+		t.Error("API order must not expose the callback secret")
+	}
+}
+
+func TestAPISwapRejectsBadCallback(t *testing.T) {
+	body := `{"from":"ETH","fromNet":"eth","to":"USDT","toNet":"eth","amount":"1","recipient":"0x000000000000000000000000000000000000dEaD","refundAddr":"0x000000000000000000000000000000000000dEaD","callbackUrl":"http://127.0.0.1/x","callbackSecret":"short"}`
+	req := httptest.NewRequest("POST", "/api/v1/swap", strings.NewReader(body))
+	req.RemoteAddr = "203.0.113.77:1234"
+	w := httptest.NewRecorder()
+	handleAPISwap(w, req)
+
+	if w.Code != 400 {
+		t.Fatalf("got %d, want 400", w.Code)
+	}
+	if e := decodeAPIError(t, w); len(e.Details) != 2 {
+		t.Errorf("expected URL and secret errors, got %v", e.Details)
+	}
+}
+
+func TestWebhookCapacity(t *testing.T) {
+	webhooks.mu.Lock()
+	for i := 0; i < webhookMaxWatchers; i++ {
+		webhooks.active[fmt.Sprintf("0xfull%d|", i)] = true
+	}
+	webhooks.mu.Unlock()
+	t.Cleanup(func() {
+		webhooks.mu.Lock()
+		for i := 0; i < webhookMaxWatchers; i++ {
+			delete(webhooks.active, fmt.Sprintf("0xfull%d|", i))
+		}
+		webhooks.mu.Unlock()
+	})
+
+	in := &swapInput{CallbackURL: "https://example.com/h", CallbackSecret: "0123456789abcdef"}
+	_, _, serr := placeOrder(context.Background(), in, nil, nil, "EXACT_INPUT", "1", 100, "", "")
+	if serr == nil || serr.Code != "webhook_capacity" || serr.Status != 503 {
+		t.Fatalf("placeOrder at capacity = %+v, want webhook_capacity", serr)
+	}
+
+	before := webhookWatchersDropped.get()
+	webhooks.watch(&OrderData{DepositAddr: "0xlate", CallbackURL: in.CallbackURL, CallbackSecret: in.CallbackSecret})
+	if got := webhookWatchersDropped.get() - before; got != 1 {
+		t.Errorf("dropped watchers counted %v, want 1", got)
+	}
+	if webhooks.count() != webhookMaxWatchers {
+		t.Errorf("watcher started past the cap: %d running", webhooks.count())
+	}
+}
+
+func TestWebhookResumeSkipsDeliveredStatus(t *testing.T) {
+	statuses := []string{"PROCESSING", "PROCESSING", "SUCCESS"}
+	var mu sync.Mutex
+	polls := 0
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		s := statuses[min(polls, len(statuses)-1)]
+		polls++
+		mu.Unlock()
+		fmt.Fprintf(w, `{"status":%q}`, s)
+	})
+	withoutStatusCache(t)
+
+	var got []webhookEvent
+	url := withWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		var ev webhookEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		got = append(got, ev)
+		mu.Unlock()
+	})
+	waitIdle := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for webhooks.count() > 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// Reopened mid-swap: only the later transition is sent.
+	order := &OrderData{DepositAddr: "0xresume", CallbackURL: url, CallbackSecret: "0123456789abcdef"}
+	webhooks.resume(order)
+	waitIdle()
+	mu.Lock()
+	if len(got) != 1 || got[0].Status != "SUCCESS" || got[0].PreviousStatus != "PROCESSING" {
+		t.Errorf("resumed watcher sent %+v, want only PROCESSING → SUCCESS", got)
+	}
+	got = nil
+	mu.Unlock()
+
+	// Reopened after it finished: nothing is sent again.
+	webhooks.resume(order)
+	waitIdle()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 0 {
+		t.Errorf("finished order sent %+v again", got)
+	}
+}
+
+func TestWebhookSlowReceiverKeepsPolling(t *testing.T) {
+	statuses := []string{"PENDING_DEPOSIT", "KNOWN_DEPOSIT_TX", "PROCESSING", "SUCCESS"}
+	var mu sync.Mutex
+	polls := 0
+	withFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		s := statuses[min(polls, len(statuses)-1)]
+		polls++
+		mu.Unlock()
+		fmt.Fprintf(w, `{"status":%q}`, s)
+	})
+	withoutStatusCache(t)
+
+	// The receiver fails its first attempts, so the first event is retried
+	// while the order moves through every other status.
+	var got []string
+	attempts, pollsAtFirst := 0, 0
+	url := withWebhookReceiver(t, func(w http.ResponseWriter, r *http.Request) {
+		var ev webhookEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if got == nil {
+			pollsAtFirst = polls
+		}
+		got = append(got, ev.Status)
+	})
+	webhookRetryDelays = []time.Duration{20 * time.Millisecond, 20 * time.Millisecond}
+
+	webhooks.watch(&OrderData{DepositAddr: "0xslowhook", CallbackURL: url, CallbackSecret: "0123456789abcdef"})
+	deadline := time.Now().Add(5 * time.Second)
+	for webhooks.count() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(got, ",") != strings.Join(statuses, ",") {
+		t.Errorf("delivered %v, want every status in order %v", got, statuses)
+	}
+	if pollsAtFirst < len(statuses) {
+		t.Errorf("polling waited for delivery: %d polls before the first event landed", pollsAtFirst)
+	}
+}
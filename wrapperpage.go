@@ -113,7 +113,7 @@ func handleWrapperLogs(w http.ResponseWriter, r *http.Request) {
 		var nearHash, nearURL string
 		if len(tx.NearTxHashes) > 0 {
 			nearHash = tx.NearTxHashes[0]
-			nearURL = "https://nearblocks.io/txns/" + nearHash
+			nearURL = lookupChain("near").TxLink(nearHash)
 		}
 		var sender string
 		if len(tx.Senders) > 0 {